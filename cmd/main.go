@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
 
@@ -73,18 +75,46 @@ func main() {
 	ledgerRepo := repository.NewLedgerRepository(dbPool)
 	rewardRequestRepo := repository.NewRewardRequestRepository(dbPool)
 	portfolioRepo := repository.NewPortfolioRepository(dbPool)
+	campaignRepo := repository.NewCampaignRepository(dbPool)
+	vestingRepo := repository.NewVestingRepository(dbPool)
+	clawbackRepo := repository.NewClawbackRepository(dbPool)
+	scheduledRewardRepo := repository.NewScheduledRewardRepository(dbPool)
+	rewardQuoteRepo := repository.NewRewardQuoteRepository(dbPool)
+	basketRepo := repository.NewBasketRepository(dbPool)
+	transferRepo := repository.NewTransferRepository(dbPool)
+	redemptionRepo := repository.NewRedemptionRepository(dbPool)
+	eventTypeRepo := repository.NewEventTypeRepository(dbPool)
+	rewardChargeRepo := repository.NewRewardChargeRepository(dbPool)
+	feePlanRepo := repository.NewFeePlanRepository(dbPool)
+
+	// One scheduler runs every background job
+	scheduler := cron.New()
 
 	// Initialize services
-	priceService = services.NewPriceService(stockPriceRepo, log)
+	priceService = services.NewPriceService(stockPriceRepo, scheduler, log)
 	rewardService := services.NewRewardService(
 		rewardRepo,
 		ledgerRepo,
 		rewardRequestRepo,
 		userRepo,
+		campaignRepo,
+		vestingRepo,
+		rewardChargeRepo,
+		rewardQuoteRepo,
+		basketRepo,
+		eventTypeRepo,
+		feePlanRepo,
 		priceService,
 		log,
 	)
-	portfolioService := services.NewPortfolioService(portfolioRepo, rewardRepo, log)
+	campaignService := services.NewCampaignService(campaignRepo, eventTypeRepo, log)
+	eventTypeService := services.NewEventTypeService(eventTypeRepo, log)
+	feePlanService := services.NewFeePlanService(feePlanRepo, log)
+	basketService := services.NewBasketService(basketRepo, log)
+	clawbackService := services.NewClawbackService(rewardRepo, ledgerRepo, vestingRepo, clawbackRepo, transferRepo, redemptionRepo, userRepo, eventTypeRepo, log)
+	transferService := services.NewTransferService(rewardRepo, ledgerRepo, vestingRepo, transferRepo, redemptionRepo, userRepo, priceService, log)
+	redemptionService := services.NewRedemptionService(rewardRepo, ledgerRepo, vestingRepo, rewardChargeRepo, redemptionRepo, userRepo, feePlanRepo, priceService, log)
+	portfolioService := services.NewPortfolioService(portfolioRepo, rewardRepo, vestingRepo, redemptionRepo, log)
 
 	// Start price service
 	if err := priceService.Start(); err != nil {
@@ -92,11 +122,45 @@ func main() {
 	}
 	defer priceService.Stop()
 
+	// Start recovery of reward requests stuck in PROCESSING
+	recoveryService := services.NewRecoveryService(rewardService, rewardRequestRepo, rewardRepo, ledgerRepo, scheduler, log)
+	if err := recoveryService.Start(); err != nil {
+		log.Fatalf("Failed to start reward recovery: %v", err)
+	}
+	defer recoveryService.Stop()
+
+	// Start the daily job that vests due reward tranches
+	vestingService := services.NewVestingService(vestingRepo, ledgerRepo, scheduler, log)
+	if err := vestingService.Start(); err != nil {
+		log.Fatalf("Failed to start vesting job: %v", err)
+	}
+	defer vestingService.Stop()
+
+	// Start the scheduler that grants scheduled and recurring rewards
+	scheduledRewardService := services.NewScheduledRewardService(scheduledRewardRepo, userRepo, rewardService, scheduler, log)
+	if err := scheduledRewardService.Start(); err != nil {
+		log.Fatalf("Failed to start reward scheduler: %v", err)
+	}
+	defer scheduledRewardService.Stop()
+
+	// Run the jobs registered above
+	scheduler.Start()
+	defer scheduler.Stop()
+
 	// Initialize controllers
 	userController := controllers.NewUserController(userRepo, log)
 	priceController := controllers.NewPriceController(priceService, log)
 	rewardController := controllers.NewRewardController(rewardService, log)
 	portfolioController := controllers.NewPortfolioController(portfolioService, log)
+	adminController := controllers.NewAdminController(rewardService, vestingService, scheduledRewardService, log)
+	campaignController := controllers.NewCampaignController(campaignService, log)
+	basketController := controllers.NewBasketController(basketService, log)
+	clawbackController := controllers.NewClawbackController(clawbackService, log)
+	transferController := controllers.NewTransferController(transferService, log)
+	redemptionController := controllers.NewRedemptionController(redemptionService, log)
+	scheduledRewardController := controllers.NewScheduledRewardController(scheduledRewardService, log)
+	eventTypeController := controllers.NewEventTypeController(eventTypeService, log)
+	feePlanController := controllers.NewFeePlanController(feePlanService, log)
 
 	// Set Gin mode
 	if mode := os.Getenv("GIN_MODE"); mode != "" {
@@ -110,9 +174,14 @@ func main() {
 	router.Use(ginLogger())
 	router.Use(gin.Recovery())
 	router.Use(corsMiddleware())
+	// Writes the response for handlers that fail with a domain error
+	router.Use(controllers.ErrorHandler(log))
 
 	// Register routes
-	registerRoutes(router, userController, priceController, rewardController, portfolioController)
+	registerRoutes(router, userController, priceController, rewardController, portfolioController,
+		adminController, campaignController, basketController, clawbackController,
+		transferController, redemptionController, scheduledRewardController, eventTypeController,
+		feePlanController)
 
 	// Get port from environment
 	port := os.Getenv("PORT")
@@ -137,6 +206,27 @@ func main() {
 		}
 	}()
 
+	// Runtime metrics (expvar), including reward recovery counters, are
+	// served on a separate listener that is only reachable internally
+	metricsAddr := os.Getenv("METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = "127.0.0.1:9090"
+	}
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/debug/vars", expvar.Handler())
+	metricsSrv := &http.Server{
+		Addr:         metricsAddr,
+		Handler:      metricsMux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+	go func() {
+		log.Infof("Metrics server starting on %s", metricsAddr)
+		if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Errorf("Metrics server stopped: %v", err)
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -151,6 +241,9 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
+	if err := metricsSrv.Shutdown(ctx); err != nil {
+		log.Errorf("Metrics server forced to shutdown: %v", err)
+	}
 
 	log.Info("Server exited")
 }
@@ -236,6 +329,15 @@ func registerRoutes(
 	priceController *controllers.PriceController,
 	rewardController *controllers.RewardController,
 	portfolioController *controllers.PortfolioController,
+	adminController *controllers.AdminController,
+	campaignController *controllers.CampaignController,
+	basketController *controllers.BasketController,
+	clawbackController *controllers.ClawbackController,
+	transferController *controllers.TransferController,
+	redemptionController *controllers.RedemptionController,
+	scheduledRewardController *controllers.ScheduledRewardController,
+	eventTypeController *controllers.EventTypeController,
+	feePlanController *controllers.FeePlanController,
 ) {
 	// Basic health check endpoint - useful for monitoring
 	router.GET("/health", healthCheckHandler)
@@ -257,15 +359,77 @@ func registerRoutes(
 
 		// Reward management endpoints
 		v1.POST("/reward", rewardController.CreateReward)
+		v1.POST("/reward/quote", rewardController.QuoteReward)
 		v1.GET("/reward/:eventId", rewardController.GetRewardByEventID)
+		v1.GET("/reward/:eventId/legs", rewardController.GetBasketRewards)
+		v1.POST("/reward/:eventId/reverse", rewardController.ReverseReward)
+		v1.POST("/reward/:eventId/approve", rewardController.ApproveReward)
+		v1.POST("/reward/:eventId/reject", rewardController.RejectReward)
+		v1.POST("/rewards/batch", rewardController.CreateRewardBatch)
 		v1.GET("/rewards/:userId", rewardController.GetUserRewards)
 
+		// Reward clawback endpoints
+		v1.POST("/users/:userId/clawback", clawbackController.ClawbackUser)
+		v1.GET("/users/:userId/clawbacks", clawbackController.GetUserClawbacks)
+		v1.GET("/clawbacks/:clawbackId", clawbackController.GetClawback)
+
+		// User-to-user transfer endpoints
+		v1.POST("/transfers", transferController.CreateTransfer)
+		v1.GET("/transfers/:transferId", transferController.GetTransfer)
+		v1.GET("/users/:userId/transfers", transferController.GetUserTransfers)
+
+		// Redemption endpoints
+		v1.POST("/redemptions", redemptionController.CreateRedemption)
+		v1.GET("/redemptions/:redemptionId", redemptionController.GetRedemption)
+		v1.POST("/redemptions/:redemptionId/execute", redemptionController.ExecuteRedemption)
+		v1.POST("/redemptions/:redemptionId/pay", redemptionController.PayRedemption)
+		v1.POST("/redemptions/:redemptionId/cancel", redemptionController.CancelRedemption)
+		v1.GET("/users/:userId/redemptions", redemptionController.GetUserRedemptions)
+
+		// Scheduled and recurring reward endpoints
+		v1.POST("/scheduled-rewards", scheduledRewardController.CreateScheduledReward)
+		v1.GET("/scheduled-rewards", scheduledRewardController.ListScheduledRewards)
+		v1.GET("/scheduled-rewards/:id", scheduledRewardController.GetScheduledReward)
+		v1.DELETE("/scheduled-rewards/:id", scheduledRewardController.CancelScheduledReward)
+
+		// Reward campaign endpoints
+		v1.POST("/campaigns", campaignController.CreateCampaign)
+		v1.GET("/campaigns", campaignController.ListCampaigns)
+		v1.GET("/campaigns/:id", campaignController.GetCampaign)
+		v1.PUT("/campaigns/:id", campaignController.UpdateCampaign)
+		v1.DELETE("/campaigns/:id", campaignController.DeactivateCampaign)
+
+		// Stock basket endpoints
+		v1.POST("/baskets", basketController.CreateBasket)
+		v1.GET("/baskets", basketController.ListBaskets)
+		v1.GET("/baskets/:id", basketController.GetBasket)
+		v1.DELETE("/baskets/:id", basketController.DeactivateBasket)
+
 		// Portfolio and analytics endpoints
 		v1.GET("/today-stocks/:userId", portfolioController.GetTodayStocks)
 		v1.GET("/historical-inr/:userId", portfolioController.GetHistoricalINR)
 		v1.GET("/stats/:userId", portfolioController.GetUserStats)
 		v1.GET("/portfolio/:userId", portfolioController.GetUserPortfolio)
 		v1.GET("/holdings/:userId", portfolioController.GetDailyHoldings)
+
+		// Operational endpoints for failed reward requests
+		v1.GET("/admin/rewards", adminController.ListRewards)
+		v1.GET("/admin/reward-requests", adminController.ListRewardRequests)
+		v1.POST("/admin/reward-requests/:eventId/replay", adminController.ReplayRewardRequest)
+		v1.POST("/admin/vesting/run", adminController.RunVesting)
+		v1.POST("/admin/scheduled-rewards/run", adminController.RunScheduledRewards)
+
+		// Event type registry endpoints
+		v1.POST("/admin/event-types", eventTypeController.CreateEventType)
+		v1.GET("/admin/event-types", eventTypeController.ListEventTypes)
+		v1.GET("/admin/event-types/:code", eventTypeController.GetEventType)
+		v1.PUT("/admin/event-types/:code", eventTypeController.UpdateEventType)
+
+		// Fee plan endpoints
+		v1.POST("/admin/fee-plans", feePlanController.CreateFeePlan)
+		v1.GET("/admin/fee-plans", feePlanController.ListFeePlans)
+		v1.GET("/admin/fee-plans/:id", feePlanController.GetFeePlan)
+		v1.PUT("/admin/fee-plans/:id", feePlanController.UpdateFeePlan)
 	}
 
 	log.Info("Routes registered successfully")
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"stockBackend/internal/models"
+	"stockBackend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminController handles operational endpoints
+type AdminController struct {
+	rewardService          *services.RewardService
+	vestingService         *services.VestingService
+	scheduledRewardService *services.ScheduledRewardService
+	log                    *logrus.Logger
+}
+
+// NewAdminController creates a new admin controller
+func NewAdminController(
+	rewardService *services.RewardService,
+	vestingService *services.VestingService,
+	scheduledRewardService *services.ScheduledRewardService,
+	log *logrus.Logger,
+) *AdminController {
+	return &AdminController{
+		rewardService:          rewardService,
+		vestingService:         vestingService,
+		scheduledRewardService: scheduledRewardService,
+		log:                    log,
+	}
+}
+
+// ListRewardRequests lists reward requests by status, FAILED by default
+// GET /api/v1/admin/reward-requests?status=FAILED&limit=50&offset=0
+func (ac *AdminController) ListRewardRequests(c *gin.Context) {
+	status := c.DefaultQuery("status", models.RequestStatusFailed)
+	switch status {
+	case models.RequestStatusProcessing, models.RequestStatusCompleted, models.RequestStatusFailed:
+	default:
+		invalidRequest(c, "Invalid status", errors.New("status must be one of PROCESSING, COMPLETED, FAILED"))
+		return
+	}
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	requests, err := ac.rewardService.ListRewardRequests(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve reward requests", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    requests,
+		"count":   len(requests),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// ListRewards lists rewards by status, PENDING_APPROVAL by default, oldest first
+// GET /api/v1/admin/rewards?status=PENDING_APPROVAL&limit=50&offset=0
+func (ac *AdminController) ListRewards(c *gin.Context) {
+	status := c.DefaultQuery("status", models.RewardStatusPendingApproval)
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	rewards, err := ac.rewardService.ListRewardsByStatus(c.Request.Context(), status, limit, offset)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve rewards", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rewards,
+		"count":   len(rewards),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// ReplayRewardRequest re-runs a FAILED reward request from its stored payload
+// POST /api/v1/admin/reward-requests/:eventId/replay
+func (ac *AdminController) ReplayRewardRequest(c *gin.Context) {
+	eventID := c.Param("eventId")
+
+	response, err := ac.rewardService.ReplayRequest(c.Request.Context(), eventID)
+	if err != nil {
+		abortWithError(c, "Replay failed", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+	})
+}
+
+// RunVesting vests due reward tranches now instead of waiting for the daily job
+// POST /api/v1/admin/vesting/run
+func (ac *AdminController) RunVesting(c *gin.Context) {
+	vested, err := ac.vestingService.VestDueTranches(c.Request.Context())
+	if err != nil {
+		ac.log.Errorf("Failed to run vesting: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Vesting run failed",
+			"code":    services.AsError(err).Code,
+			"message": err.Error(),
+			"vested":  vested,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"vested":  vested,
+	})
+}
+
+// RunScheduledRewards processes due scheduled reward runs now instead of
+// waiting for the scheduler
+// POST /api/v1/admin/scheduled-rewards/run
+func (ac *AdminController) RunScheduledRewards(c *gin.Context) {
+	runs, err := ac.scheduledRewardService.RunDue(c.Request.Context())
+	if err != nil {
+		ac.log.Errorf("Failed to run scheduled rewards: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Scheduled reward run failed",
+			"code":    services.AsError(err).Code,
+			"message": err.Error(),
+			"runs":    runs,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"runs":    runs,
+	})
+}
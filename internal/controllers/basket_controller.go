@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"stockBackend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// BasketController handles stock basket endpoints
+type BasketController struct {
+	basketService *services.BasketService
+	log           *logrus.Logger
+}
+
+// NewBasketController creates a new basket controller
+func NewBasketController(basketService *services.BasketService, log *logrus.Logger) *BasketController {
+	return &BasketController{
+		basketService: basketService,
+		log:           log,
+	}
+}
+
+// CreateBasket creates a weighted stock basket
+// POST /api/v1/baskets
+func (bc *BasketController) CreateBasket(c *gin.Context) {
+	var req services.BasketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	basket, err := bc.basketService.CreateBasket(c.Request.Context(), &req)
+	if err != nil {
+		abortWithError(c, "Failed to create basket", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    basket,
+	})
+}
+
+// GetBasket retrieves a basket with its legs
+// GET /api/v1/baskets/:id
+func (bc *BasketController) GetBasket(c *gin.Context) {
+	id, ok := bc.basketID(c)
+	if !ok {
+		return
+	}
+
+	basket, err := bc.basketService.GetBasket(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve basket", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    basket,
+	})
+}
+
+// ListBaskets lists baskets, newest first
+// GET /api/v1/baskets?active=true&limit=50&offset=0
+func (bc *BasketController) ListBaskets(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	baskets, err := bc.basketService.ListBaskets(c.Request.Context(), activeOnly, limit, offset)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve baskets", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    baskets,
+		"count":   len(baskets),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// DeactivateBasket stops a basket from being rewarded
+// DELETE /api/v1/baskets/:id
+func (bc *BasketController) DeactivateBasket(c *gin.Context) {
+	id, ok := bc.basketID(c)
+	if !ok {
+		return
+	}
+
+	basket, err := bc.basketService.DeactivateBasket(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, "Failed to deactivate basket", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    basket,
+	})
+}
+
+// basketID parses the :id path parameter, aborting with a validation error if it is invalid
+func (bc *BasketController) basketID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		invalidRequest(c, "Invalid basket ID", errors.New("id must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"stockBackend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CampaignController handles reward campaign endpoints
+type CampaignController struct {
+	campaignService *services.CampaignService
+	log             *logrus.Logger
+}
+
+// NewCampaignController creates a new campaign controller
+func NewCampaignController(campaignService *services.CampaignService, log *logrus.Logger) *CampaignController {
+	return &CampaignController{
+		campaignService: campaignService,
+		log:             log,
+	}
+}
+
+// CreateCampaign creates a reward campaign
+// POST /api/v1/campaigns
+func (cc *CampaignController) CreateCampaign(c *gin.Context) {
+	var req services.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	campaign, err := cc.campaignService.CreateCampaign(c.Request.Context(), &req)
+	if err != nil {
+		abortWithError(c, "Failed to create campaign", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    campaign,
+	})
+}
+
+// GetCampaign retrieves a campaign with its current spend
+// GET /api/v1/campaigns/:id
+func (cc *CampaignController) GetCampaign(c *gin.Context) {
+	id, ok := cc.campaignID(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := cc.campaignService.GetCampaign(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve campaign", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    campaign,
+	})
+}
+
+// ListCampaigns lists campaigns, newest first
+// GET /api/v1/campaigns?active=true&limit=50&offset=0
+func (cc *CampaignController) ListCampaigns(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	campaigns, err := cc.campaignService.ListCampaigns(c.Request.Context(), activeOnly, limit, offset)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve campaigns", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    campaigns,
+		"count":   len(campaigns),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// UpdateCampaign changes a campaign's name, dates, budget, cap or active flag
+// PUT /api/v1/campaigns/:id
+func (cc *CampaignController) UpdateCampaign(c *gin.Context) {
+	id, ok := cc.campaignID(c)
+	if !ok {
+		return
+	}
+
+	var req services.CampaignUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	campaign, err := cc.campaignService.UpdateCampaign(c.Request.Context(), id, &req)
+	if err != nil {
+		abortWithError(c, "Failed to update campaign", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    campaign,
+	})
+}
+
+// DeactivateCampaign stops a campaign from granting further rewards
+// DELETE /api/v1/campaigns/:id
+func (cc *CampaignController) DeactivateCampaign(c *gin.Context) {
+	id, ok := cc.campaignID(c)
+	if !ok {
+		return
+	}
+
+	campaign, err := cc.campaignService.DeactivateCampaign(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, "Failed to deactivate campaign", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    campaign,
+	})
+}
+
+// campaignID parses the :id path parameter, aborting with a validation error if it is invalid
+func (cc *CampaignController) campaignID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		invalidRequest(c, "Invalid campaign ID", errors.New("id must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"stockBackend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ClawbackController handles reward clawback endpoints
+type ClawbackController struct {
+	clawbackService *services.ClawbackService
+	log             *logrus.Logger
+}
+
+// NewClawbackController creates a new clawback controller
+func NewClawbackController(clawbackService *services.ClawbackService, log *logrus.Logger) *ClawbackController {
+	return &ClawbackController{
+		clawbackService: clawbackService,
+		log:             log,
+	}
+}
+
+// ClawbackUser reclaims stock granted to a user in a recent window
+// POST /api/v1/users/:userId/clawback
+func (cc *ClawbackController) ClawbackUser(c *gin.Context) {
+	userID := c.Param("userId")
+
+	var req services.ClawbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	clawback, err := cc.clawbackService.ClawbackUser(c.Request.Context(), userID, &req)
+	if err != nil {
+		abortWithError(c, "Failed to claw back rewards", err)
+		return
+	}
+
+	status := http.StatusCreated
+	if req.DryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{
+		"success": true,
+		"dry_run": req.DryRun,
+		"data":    clawback,
+	})
+}
+
+// GetClawback retrieves a clawback with its per-symbol outcome
+// GET /api/v1/clawbacks/:clawbackId
+func (cc *ClawbackController) GetClawback(c *gin.Context) {
+	clawback, err := cc.clawbackService.GetClawback(c.Request.Context(), c.Param("clawbackId"))
+	if err != nil {
+		abortWithError(c, "Failed to retrieve clawback", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    clawback,
+	})
+}
+
+// GetUserClawbacks lists a user's clawbacks, newest first
+// GET /api/v1/users/:userId/clawbacks?limit=50&offset=0
+func (cc *ClawbackController) GetUserClawbacks(c *gin.Context) {
+	userID := c.Param("userId")
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	clawbacks, err := cc.clawbackService.GetUserClawbacks(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve clawbacks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    clawbacks,
+		"count":   len(clawbacks),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
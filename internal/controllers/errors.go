@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"stockBackend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// statusForKind maps each kind of domain error to its HTTP status
+var statusForKind = map[services.ErrorKind]int{
+	services.KindValidation:  http.StatusBadRequest,
+	services.KindNotFound:    http.StatusNotFound,
+	services.KindConflict:    http.StatusConflict,
+	services.KindUnavailable: http.StatusServiceUnavailable,
+	services.KindInternal:    http.StatusInternalServerError,
+}
+
+// ErrorHandler writes the error response for a handler that failed through
+// abortWithError. The status comes from the kind of the domain error, unless
+// the error sets its own, and the body carries its code, so every endpoint reports a failure the same way:
+//
+//	{"error": "<what failed>", "code": "<CODE>", "message": "<detail>"}
+//
+// Internal errors are logged with their detail, which may name tables or
+// queries, and the client only sees the generic message.
+func ErrorHandler(log *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		last := c.Errors.Last()
+		domainErr := services.AsError(last.Err)
+
+		message, _ := last.Meta.(string)
+		if message == "" {
+			message = domainErr.Message
+		}
+		detail := last.Err.Error()
+		if domainErr.Kind == services.KindInternal {
+			log.WithFields(logrus.Fields{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+			}).Errorf("%s: %v", message, last.Err)
+			detail = domainErr.Message
+		}
+		body := gin.H{
+			"error":   message,
+			"code":    domainErr.Code,
+			"message": detail,
+		}
+		if domainErr.RetryAfter > 0 {
+			retryAfter := int(domainErr.RetryAfter.Seconds())
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			body["retry_after_seconds"] = retryAfter
+		}
+		var breach *services.RiskBreach
+		if errors.As(last.Err, &breach) {
+			body["risk_rule"] = breach.Rule
+		}
+
+		status := statusForKind[domainErr.Kind]
+		if domainErr.Status != 0 {
+			status = domainErr.Status
+		}
+		c.JSON(status, body)
+	}
+}
+
+// abortWithError stops the handler chain and leaves err for ErrorHandler to
+// write; message summarises what failed
+func abortWithError(c *gin.Context, message string, err error) {
+	_ = c.Error(err).SetMeta(message)
+	c.Abort()
+}
+
+// invalidRequest aborts with a validation error for a malformed body or
+// path or query parameter
+func invalidRequest(c *gin.Context, message string, err error) {
+	abortWithError(c, message, fmt.Errorf("%w: %v", services.ErrValidation, err))
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"stockBackend/internal/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantRetry   string
+		wantRule    string
+	}{
+		{
+			name:        "validation",
+			err:         fmt.Errorf("%w: event_id is required", services.ErrValidation),
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "VALIDATION_FAILED",
+			wantMessage: "validation failed: event_id is required",
+		},
+		{
+			name:        "not found",
+			err:         services.ErrRewardNotFound,
+			wantStatus:  http.StatusNotFound,
+			wantCode:    "REWARD_NOT_FOUND",
+			wantMessage: "reward not found",
+		},
+		{
+			name:        "conflict",
+			err:         fmt.Errorf("%w: COMPLETED to APPROVED", services.ErrInvalidStatusTransition),
+			wantStatus:  http.StatusConflict,
+			wantCode:    "INVALID_STATUS_TRANSITION",
+			wantMessage: "invalid reward status transition: COMPLETED to APPROVED",
+		},
+		{
+			name:        "unavailable",
+			err:         &services.Error{Kind: services.KindUnavailable, Code: "PRICE_FEED_DOWN", Message: "price feed down"},
+			wantStatus:  http.StatusServiceUnavailable,
+			wantCode:    "PRICE_FEED_DOWN",
+			wantMessage: "price feed down",
+		},
+		{
+			name:        "status set by the error",
+			err:         services.ErrSelfApproval,
+			wantStatus:  http.StatusForbidden,
+			wantCode:    "SELF_APPROVAL",
+			wantMessage: "reviewer must differ from the reward creator",
+		},
+		{
+			name:        "price unavailable",
+			err:         fmt.Errorf("failed to get stock price: %w", services.ErrPriceUnavailable),
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "PRICE_UNAVAILABLE",
+			wantMessage: "failed to get stock price: no stock price available at event time",
+		},
+		{
+			name:        "retry after",
+			err:         services.ErrRequestInProgress,
+			wantStatus:  http.StatusTooEarly,
+			wantCode:    "REQUEST_IN_PROGRESS",
+			wantMessage: "request already processing",
+			wantRetry:   "2",
+		},
+		{
+			name:        "risk breach",
+			err:         &services.RiskBreach{Rule: "max_quantity_per_event", Detail: "quantity 500 exceeds 100"},
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    "RISK_LIMIT_EXCEEDED",
+			wantMessage: "risk limit exceeded: max_quantity_per_event: quantity 500 exceeds 100",
+			wantRule:    "max_quantity_per_event",
+		},
+		{
+			// Internal detail stays in the log
+			name:        "internal",
+			err:         errors.New("failed to insert into rewards: connection reset"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    "INTERNAL_ERROR",
+			wantMessage: "internal error",
+		},
+	}
+	for _, tt := range tests {
+		router := gin.New()
+		router.Use(ErrorHandler(log))
+		router.GET("/", func(c *gin.Context) {
+			abortWithError(c, "Request failed", tt.err)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.wantStatus)
+		}
+		if got := w.Header().Get("Retry-After"); got != tt.wantRetry {
+			t.Errorf("%s: Retry-After = %q, want %q", tt.name, got, tt.wantRetry)
+		}
+		var body struct {
+			Error    string `json:"error"`
+			Code     string `json:"code"`
+			Message  string `json:"message"`
+			RiskRule string `json:"risk_rule"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: invalid body %s: %v", tt.name, w.Body.String(), err)
+			continue
+		}
+		if body.Error != "Request failed" || body.Code != tt.wantCode || body.Message != tt.wantMessage {
+			t.Errorf("%s: body = %+v, want code %s and message %q", tt.name, body, tt.wantCode, tt.wantMessage)
+		}
+		if body.RiskRule != tt.wantRule {
+			t.Errorf("%s: risk_rule = %q, want %q", tt.name, body.RiskRule, tt.wantRule)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+	"stockBackend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// EventTypeController handles the event type registry endpoints
+type EventTypeController struct {
+	eventTypeService *services.EventTypeService
+	log              *logrus.Logger
+}
+
+// NewEventTypeController creates a new event type controller
+func NewEventTypeController(eventTypeService *services.EventTypeService, log *logrus.Logger) *EventTypeController {
+	return &EventTypeController{
+		eventTypeService: eventTypeService,
+		log:              log,
+	}
+}
+
+// CreateEventType registers an event type
+// POST /api/v1/admin/event-types
+func (ec *EventTypeController) CreateEventType(c *gin.Context) {
+	var req services.EventTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	eventType, err := ec.eventTypeService.CreateEventType(c.Request.Context(), &req)
+	if err != nil {
+		abortWithError(c, "Failed to create event type", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    eventType,
+	})
+}
+
+// GetEventType retrieves an event type and its rules
+// GET /api/v1/admin/event-types/:code
+func (ec *EventTypeController) GetEventType(c *gin.Context) {
+	eventType, err := ec.eventTypeService.GetEventType(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		abortWithError(c, "Failed to retrieve event type", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    eventType,
+	})
+}
+
+// ListEventTypes lists the registered event types by code
+// GET /api/v1/admin/event-types?active=true
+func (ec *EventTypeController) ListEventTypes(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+
+	eventTypes, err := ec.eventTypeService.ListEventTypes(c.Request.Context(), activeOnly)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve event types", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    eventTypes,
+		"count":   len(eventTypes),
+	})
+}
+
+// UpdateEventType changes an event type's rules, accounts or active flag
+// PUT /api/v1/admin/event-types/:code
+func (ec *EventTypeController) UpdateEventType(c *gin.Context) {
+	var req services.EventTypeUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	eventType, err := ec.eventTypeService.UpdateEventType(c.Request.Context(), c.Param("code"), &req)
+	if err != nil {
+		abortWithError(c, "Failed to update event type", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    eventType,
+	})
+}
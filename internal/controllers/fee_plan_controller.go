@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"stockBackend/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// FeePlanController handles the fee plan endpoints
+type FeePlanController struct {
+	feePlanService *services.FeePlanService
+	log            *logrus.Logger
+}
+
+// NewFeePlanController creates a new fee plan controller
+func NewFeePlanController(feePlanService *services.FeePlanService, log *logrus.Logger) *FeePlanController {
+	return &FeePlanController{
+		feePlanService: feePlanService,
+		log:            log,
+	}
+}
+
+// CreateFeePlan creates a fee plan, ending the open-ended plan it replaces
+// POST /api/v1/admin/fee-plans
+func (fc *FeePlanController) CreateFeePlan(c *gin.Context) {
+	var req services.FeePlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	plan, err := fc.feePlanService.CreateFeePlan(c.Request.Context(), &req)
+	if err != nil {
+		abortWithError(c, "Failed to create fee plan", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    plan,
+	})
+}
+
+// GetFeePlan retrieves a fee plan and its charges
+// GET /api/v1/admin/fee-plans/:id
+func (fc *FeePlanController) GetFeePlan(c *gin.Context) {
+	id, ok := fc.feePlanID(c)
+	if !ok {
+		return
+	}
+
+	plan, err := fc.feePlanService.GetFeePlan(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve fee plan", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    plan,
+	})
+}
+
+// ListFeePlans lists the plans that apply to a symbol and event type
+// GET /api/v1/admin/fee-plans?stock_symbol=TCS&event_type=REFERRAL&at=2024-08-01T00:00:00Z
+func (fc *FeePlanController) ListFeePlans(c *gin.Context) {
+	var at *time.Time
+	if a := c.Query("at"); a != "" {
+		parsed, err := time.Parse(time.RFC3339, a)
+		if err != nil {
+			invalidRequest(c, "Invalid at", errors.New("at must be an RFC 3339 time"))
+			return
+		}
+		at = &parsed
+	}
+
+	plans, err := fc.feePlanService.ListFeePlans(c.Request.Context(), c.Query("stock_symbol"), c.Query("event_type"), at)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve fee plans", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    plans,
+		"count":   len(plans),
+	})
+}
+
+// UpdateFeePlan renames a fee plan or moves its end
+// PUT /api/v1/admin/fee-plans/:id
+func (fc *FeePlanController) UpdateFeePlan(c *gin.Context) {
+	id, ok := fc.feePlanID(c)
+	if !ok {
+		return
+	}
+
+	var req services.FeePlanUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	plan, err := fc.feePlanService.UpdateFeePlan(c.Request.Context(), id, &req)
+	if err != nil {
+		abortWithError(c, "Failed to update fee plan", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    plan,
+	})
+}
+
+// feePlanID parses the :id path parameter, aborting with a validation error if it is invalid
+func (fc *FeePlanController) feePlanID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		invalidRequest(c, "Invalid fee plan ID", errors.New("id must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"stockBackend/internal/services"
+	"stockBackend/pkg/decimal"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -27,28 +29,22 @@ func NewPortfolioController(portfolioService *services.PortfolioService, log *lo
 func (pc *PortfolioController) GetTodayStocks(c *gin.Context) {
 	userID := c.Param("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
-		})
+		invalidRequest(c, "User ID is required", errors.New("userId is required"))
 		return
 	}
 
 	rewards, err := pc.portfolioService.GetTodayStocks(c.Request.Context(), userID)
 	if err != nil {
-		pc.log.Errorf("Failed to get today's stocks: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get today's stocks",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to get today's stocks", err)
 		return
 	}
 
 	// Calculate total
-	totalINR := 0.0
-	totalQuantity := 0.0
+	totalINR := decimal.Zero
+	totalQuantity := decimal.Zero
 	for _, reward := range rewards {
-		totalINR += reward.TotalValueINR
-		totalQuantity += reward.Quantity
+		totalINR = totalINR.Add(reward.TotalValueINR)
+		totalQuantity = totalQuantity.Add(reward.Quantity)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -66,9 +62,7 @@ func (pc *PortfolioController) GetTodayStocks(c *gin.Context) {
 func (pc *PortfolioController) GetHistoricalINR(c *gin.Context) {
 	userID := c.Param("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
-		})
+		invalidRequest(c, "User ID is required", errors.New("userId is required"))
 		return
 	}
 
@@ -77,22 +71,18 @@ func (pc *PortfolioController) GetHistoricalINR(c *gin.Context) {
 
 	rewards, err := pc.portfolioService.GetHistoricalINR(c.Request.Context(), userID, startDate, endDate)
 	if err != nil {
-		pc.log.Errorf("Failed to get historical INR: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get historical data",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to get historical data", err)
 		return
 	}
 
 	// Calculate totals
-	totalINR := 0.0
-	totalQuantity := 0.0
-	totalFees := 0.0
+	totalINR := decimal.Zero
+	totalQuantity := decimal.Zero
+	totalFees := decimal.Zero
 	for _, reward := range rewards {
-		totalINR += reward.TotalValueINR
-		totalQuantity += reward.Quantity
-		totalFees += reward.BrokerageFee + reward.TransactionFee
+		totalINR = totalINR.Add(reward.TotalValueINR)
+		totalQuantity = totalQuantity.Add(reward.Quantity)
+		totalFees = totalFees.Add(reward.BrokerageFee).Add(reward.TransactionFee)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -104,7 +94,7 @@ func (pc *PortfolioController) GetHistoricalINR(c *gin.Context) {
 		"total_quantity": totalQuantity,
 		"total_inr":      totalINR,
 		"total_fees":     totalFees,
-		"net_inr":        totalINR - totalFees,
+		"net_inr":        totalINR.Sub(totalFees),
 	})
 }
 
@@ -113,19 +103,13 @@ func (pc *PortfolioController) GetHistoricalINR(c *gin.Context) {
 func (pc *PortfolioController) GetUserStats(c *gin.Context) {
 	userID := c.Param("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
-		})
+		invalidRequest(c, "User ID is required", errors.New("userId is required"))
 		return
 	}
 
 	stats, err := pc.portfolioService.GetUserStats(c.Request.Context(), userID)
 	if err != nil {
-		pc.log.Errorf("Failed to get user stats: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get stats",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to get stats", err)
 		return
 	}
 
@@ -140,30 +124,24 @@ func (pc *PortfolioController) GetUserStats(c *gin.Context) {
 func (pc *PortfolioController) GetUserPortfolio(c *gin.Context) {
 	userID := c.Param("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
-		})
+		invalidRequest(c, "User ID is required", errors.New("userId is required"))
 		return
 	}
 
 	portfolio, err := pc.portfolioService.GetUserPortfolio(c.Request.Context(), userID)
 	if err != nil {
-		pc.log.Errorf("Failed to get portfolio: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get portfolio",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to get portfolio", err)
 		return
 	}
 
 	// Calculate totals
-	totalInvested := 0.0
-	totalCurrentValue := 0.0
-	totalProfitLoss := 0.0
+	totalInvested := decimal.Zero
+	totalCurrentValue := decimal.Zero
+	totalProfitLoss := decimal.Zero
 	for _, item := range portfolio {
-		totalInvested += item.TotalInvestedINR
-		totalCurrentValue += item.CurrentValueINR
-		totalProfitLoss += item.ProfitLossINR
+		totalInvested = totalInvested.Add(item.TotalInvestedINR)
+		totalCurrentValue = totalCurrentValue.Add(item.CurrentValueINR)
+		totalProfitLoss = totalProfitLoss.Add(item.ProfitLossINR)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -174,8 +152,8 @@ func (pc *PortfolioController) GetUserPortfolio(c *gin.Context) {
 		"total_current_value": totalCurrentValue,
 		"total_profit_loss":   totalProfitLoss,
 		"profit_loss_percent": func() float64 {
-			if totalInvested > 0 {
-				return (totalProfitLoss / totalInvested) * 100
+			if totalInvested.IsPositive() {
+				return (totalProfitLoss.Float64() / totalInvested.Float64()) * 100
 			}
 			return 0
 		}(),
@@ -187,9 +165,7 @@ func (pc *PortfolioController) GetUserPortfolio(c *gin.Context) {
 func (pc *PortfolioController) GetDailyHoldings(c *gin.Context) {
 	userID := c.Param("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
-		})
+		invalidRequest(c, "User ID is required", errors.New("userId is required"))
 		return
 	}
 
@@ -197,11 +173,7 @@ func (pc *PortfolioController) GetDailyHoldings(c *gin.Context) {
 
 	holdings, err := pc.portfolioService.GetDailyHoldings(c.Request.Context(), userID, date)
 	if err != nil {
-		pc.log.Errorf("Failed to get daily holdings: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get holdings",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to get holdings", err)
 		return
 	}
 
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"stockBackend/internal/services"
 	"strconv"
@@ -29,11 +30,7 @@ func (pc *PriceController) TriggerPriceUpdate(c *gin.Context) {
 	pc.log.Info("Manual price update triggered")
 
 	if err := pc.priceService.UpdatePrices(c.Request.Context()); err != nil {
-		pc.log.Errorf("Failed to update prices: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update prices",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to update prices", err)
 		return
 	}
 
@@ -48,19 +45,13 @@ func (pc *PriceController) TriggerPriceUpdate(c *gin.Context) {
 func (pc *PriceController) UpdateSingleStockPrice(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Stock symbol is required",
-		})
+		invalidRequest(c, "Stock symbol is required", errors.New("symbol is required"))
 		return
 	}
 
 	price, err := pc.priceService.UpdateSinglePrice(c.Request.Context(), symbol)
 	if err != nil {
-		pc.log.Errorf("Failed to update price for %s: %v", symbol, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update price",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to update price", err)
 		return
 	}
 
@@ -75,19 +66,13 @@ func (pc *PriceController) UpdateSingleStockPrice(c *gin.Context) {
 func (pc *PriceController) GetLatestPrice(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Stock symbol is required",
-		})
+		invalidRequest(c, "Stock symbol is required", errors.New("symbol is required"))
 		return
 	}
 
 	price, err := pc.priceService.GetLatestPrice(c.Request.Context(), symbol)
 	if err != nil {
-		pc.log.Errorf("Failed to get price for %s: %v", symbol, err)
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Price not found",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to get price", err)
 		return
 	}
 
@@ -101,9 +86,7 @@ func (pc *PriceController) GetLatestPrice(c *gin.Context) {
 func (pc *PriceController) GetPriceHistory(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Stock symbol is required",
-		})
+		invalidRequest(c, "Stock symbol is required", errors.New("symbol is required"))
 		return
 	}
 
@@ -116,11 +99,7 @@ func (pc *PriceController) GetPriceHistory(c *gin.Context) {
 
 	prices, err := pc.priceService.GetPriceHistory(c.Request.Context(), symbol, limit)
 	if err != nil {
-		pc.log.Errorf("Failed to get price history for %s: %v", symbol, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get price history",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to get price history", err)
 		return
 	}
 
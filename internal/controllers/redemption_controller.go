@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"net/http"
+	"stockBackend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RedemptionController handles redemption endpoints
+type RedemptionController struct {
+	redemptionService *services.RedemptionService
+	log               *logrus.Logger
+}
+
+// NewRedemptionController creates a new redemption controller
+func NewRedemptionController(redemptionService *services.RedemptionService, log *logrus.Logger) *RedemptionController {
+	return &RedemptionController{
+		redemptionService: redemptionService,
+		log:               log,
+	}
+}
+
+// CreateRedemption requests the sale of a quantity of a held symbol
+// POST /api/v1/redemptions
+func (rc *RedemptionController) CreateRedemption(c *gin.Context) {
+	var req services.RedemptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	redemption, err := rc.redemptionService.RequestRedemption(c.Request.Context(), &req)
+	if err != nil {
+		abortWithError(c, "Failed to request redemption", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    redemption,
+	})
+}
+
+// GetRedemption retrieves a redemption by its redemption ID
+// GET /api/v1/redemptions/:redemptionId
+func (rc *RedemptionController) GetRedemption(c *gin.Context) {
+	redemption, err := rc.redemptionService.GetRedemption(c.Request.Context(), c.Param("redemptionId"))
+	if err != nil {
+		abortWithError(c, "Failed to retrieve redemption", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    redemption,
+	})
+}
+
+// ExecuteRedemption sells a requested redemption at the current price
+// POST /api/v1/redemptions/:redemptionId/execute
+func (rc *RedemptionController) ExecuteRedemption(c *gin.Context) {
+	redemption, err := rc.redemptionService.ExecuteRedemption(c.Request.Context(), c.Param("redemptionId"))
+	if err != nil {
+		abortWithError(c, "Failed to execute redemption", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    redemption,
+	})
+}
+
+// PayRedemption records the payout of an executed redemption
+// POST /api/v1/redemptions/:redemptionId/pay
+func (rc *RedemptionController) PayRedemption(c *gin.Context) {
+	var req services.RedemptionPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	redemption, err := rc.redemptionService.PayRedemption(c.Request.Context(), c.Param("redemptionId"), &req)
+	if err != nil {
+		abortWithError(c, "Failed to pay redemption", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    redemption,
+	})
+}
+
+// CancelRedemption cancels a redemption that has not been executed
+// POST /api/v1/redemptions/:redemptionId/cancel
+func (rc *RedemptionController) CancelRedemption(c *gin.Context) {
+	redemption, err := rc.redemptionService.CancelRedemption(c.Request.Context(), c.Param("redemptionId"))
+	if err != nil {
+		abortWithError(c, "Failed to cancel redemption", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    redemption,
+	})
+}
+
+// GetUserRedemptions lists a user's redemptions, newest first
+// GET /api/v1/users/:userId/redemptions?status=EXECUTED&limit=50&offset=0
+func (rc *RedemptionController) GetUserRedemptions(c *gin.Context) {
+	userID := c.Param("userId")
+	status := c.Query("status")
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	redemptions, err := rc.redemptionService.GetUserRedemptions(c.Request.Context(), userID, status, limit, offset)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve redemptions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    redemptions,
+		"count":   len(redemptions),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
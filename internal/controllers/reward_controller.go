@@ -1,6 +1,9 @@
 package controllers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"stockBackend/internal/services"
 	"strconv"
@@ -28,21 +31,13 @@ func NewRewardController(rewardService *services.RewardService, log *logrus.Logg
 func (rc *RewardController) CreateReward(c *gin.Context) {
 	var req services.RewardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		rc.log.Errorf("Invalid request body: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"message": err.Error(),
-		})
+		invalidRequest(c, "Invalid request", err)
 		return
 	}
 
 	response, err := rc.rewardService.ProcessReward(c.Request.Context(), &req)
 	if err != nil {
-		rc.log.Errorf("Failed to process reward: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to process reward",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to process reward", err)
 		return
 	}
 
@@ -52,24 +47,92 @@ func (rc *RewardController) CreateReward(c *gin.Context) {
 	})
 }
 
+// QuoteReward prices a reward without booking it and locks the price for a while
+// POST /api/v1/reward/quote
+func (rc *RewardController) QuoteReward(c *gin.Context) {
+	var req services.QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	quote, err := rc.rewardService.QuoteReward(c.Request.Context(), &req)
+	if err != nil {
+		abortWithError(c, "Failed to quote reward", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    quote,
+	})
+}
+
+// CreateRewardBatch processes an array of reward requests in one call
+// POST /api/v1/rewards/batch
+func (rc *RewardController) CreateRewardBatch(c *gin.Context) {
+	// Items are validated one by one in the service so a bad item
+	// gets its own failed result instead of rejecting the whole batch
+	var reqs []*services.RewardRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&reqs); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	if len(reqs) == 0 {
+		invalidRequest(c, "At least one reward is required", errors.New("batch is empty"))
+		return
+	}
+
+	if len(reqs) > rc.rewardService.MaxBatchSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "Batch too large",
+			"code":    "BATCH_TOO_LARGE",
+			"message": "Split the batch into smaller requests",
+			"max":     rc.rewardService.MaxBatchSize(),
+		})
+		return
+	}
+
+	results, err := rc.rewardService.ProcessRewardBatch(c.Request.Context(), reqs)
+	if err != nil {
+		abortWithError(c, "Failed to process reward batch", err)
+		return
+	}
+
+	summary := map[string]int{
+		services.BatchItemCreated:   0,
+		services.BatchItemDuplicate: 0,
+		services.BatchItemFailed:    0,
+	}
+	for _, result := range results {
+		summary[result.Status]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    results,
+		"count":   len(results),
+		"summary": gin.H{
+			"created":   summary[services.BatchItemCreated],
+			"duplicate": summary[services.BatchItemDuplicate],
+			"failed":    summary[services.BatchItemFailed],
+		},
+	})
+}
+
 // GetRewardByEventID retrieves a reward by event ID
 // GET /api/v1/reward/:eventId
 func (rc *RewardController) GetRewardByEventID(c *gin.Context) {
 	eventID := c.Param("eventId")
 	if eventID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Event ID is required",
-		})
+		invalidRequest(c, "Event ID is required", errors.New("eventId is required"))
 		return
 	}
 
 	reward, err := rc.rewardService.GetRewardByEventID(c.Request.Context(), eventID)
 	if err != nil {
-		rc.log.Errorf("Failed to get reward: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "Reward not found",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to retrieve reward", err)
 		return
 	}
 
@@ -78,14 +141,91 @@ func (rc *RewardController) GetRewardByEventID(c *gin.Context) {
 	})
 }
 
+// GetBasketRewards retrieves the per-symbol rewards booked for a basket reward event
+// GET /api/v1/reward/:eventId/legs
+func (rc *RewardController) GetBasketRewards(c *gin.Context) {
+	eventID := c.Param("eventId")
+
+	legs, err := rc.rewardService.GetBasketRewards(c.Request.Context(), eventID)
+	if err != nil {
+		abortWithError(c, "Failed to get basket rewards", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    legs,
+		"count":   len(legs),
+	})
+}
+
+// ReverseReward reverses a processed reward with compensating entries
+// POST /api/v1/reward/:eventId/reverse
+func (rc *RewardController) ReverseReward(c *gin.Context) {
+	eventID := c.Param("eventId")
+	if eventID == "" {
+		invalidRequest(c, "Event ID is required", errors.New("eventId is required"))
+		return
+	}
+
+	var req services.ReversalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	response, err := rc.rewardService.ReverseReward(c.Request.Context(), eventID, &req)
+	if err != nil {
+		abortWithError(c, "Failed to reverse reward", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    response,
+	})
+}
+
+// ApproveReward approves a reward awaiting approval and posts it to the ledger
+// POST /api/v1/reward/:eventId/approve
+func (rc *RewardController) ApproveReward(c *gin.Context) {
+	rc.reviewReward(c, rc.rewardService.ApproveReward)
+}
+
+// RejectReward rejects a reward awaiting approval
+// POST /api/v1/reward/:eventId/reject
+func (rc *RewardController) RejectReward(c *gin.Context) {
+	rc.reviewReward(c, rc.rewardService.RejectReward)
+}
+
+// reviewReward handles an approve or reject decision
+func (rc *RewardController) reviewReward(c *gin.Context, review func(context.Context, string, *services.ReviewRequest) (*services.RewardResponse, error)) {
+	eventID := c.Param("eventId")
+
+	var req services.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	response, err := review(c.Request.Context(), eventID, &req)
+	if err != nil {
+		abortWithError(c, "Failed to review reward", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+	})
+}
+
 // GetUserRewards retrieves rewards for a user
 // GET /api/v1/rewards/:userId?limit=10&offset=0
 func (rc *RewardController) GetUserRewards(c *gin.Context) {
 	userID := c.Param("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
-		})
+		invalidRequest(c, "User ID is required", errors.New("userId is required"))
 		return
 	}
 
@@ -105,11 +245,7 @@ func (rc *RewardController) GetUserRewards(c *gin.Context) {
 
 	rewards, err := rc.rewardService.GetUserRewards(c.Request.Context(), userID, limit, offset)
 	if err != nil {
-		rc.log.Errorf("Failed to get user rewards: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to get rewards",
-			"message": err.Error(),
-		})
+		abortWithError(c, "Failed to get rewards", err)
 		return
 	}
 
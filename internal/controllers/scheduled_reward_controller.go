@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"stockBackend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ScheduledRewardController handles scheduled and recurring reward endpoints
+type ScheduledRewardController struct {
+	scheduledRewardService *services.ScheduledRewardService
+	log                    *logrus.Logger
+}
+
+// NewScheduledRewardController creates a new scheduled reward controller
+func NewScheduledRewardController(scheduledRewardService *services.ScheduledRewardService, log *logrus.Logger) *ScheduledRewardController {
+	return &ScheduledRewardController{
+		scheduledRewardService: scheduledRewardService,
+		log:                    log,
+	}
+}
+
+// CreateScheduledReward schedules a future or recurring reward
+// POST /api/v1/scheduled-rewards
+func (sc *ScheduledRewardController) CreateScheduledReward(c *gin.Context) {
+	var req services.ScheduledRewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	schedule, err := sc.scheduledRewardService.CreateScheduledReward(c.Request.Context(), &req)
+	if err != nil {
+		abortWithError(c, "Failed to create scheduled reward", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// GetScheduledReward retrieves a schedule with its progress
+// GET /api/v1/scheduled-rewards/:id
+func (sc *ScheduledRewardController) GetScheduledReward(c *gin.Context) {
+	id, ok := sc.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduledRewardService.GetScheduledReward(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve scheduled reward", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// ListScheduledRewards lists schedules, newest first
+// GET /api/v1/scheduled-rewards?user_id=USR001&status=ACTIVE&limit=50&offset=0
+func (sc *ScheduledRewardController) ListScheduledRewards(c *gin.Context) {
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	schedules, err := sc.scheduledRewardService.ListScheduledRewards(c.Request.Context(),
+		c.Query("user_id"), c.Query("status"), limit, offset)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve scheduled rewards", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedules,
+		"count":   len(schedules),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// CancelScheduledReward cancels a schedule's remaining runs
+// DELETE /api/v1/scheduled-rewards/:id
+func (sc *ScheduledRewardController) CancelScheduledReward(c *gin.Context) {
+	id, ok := sc.scheduleID(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := sc.scheduledRewardService.CancelScheduledReward(c.Request.Context(), id)
+	if err != nil {
+		abortWithError(c, "Failed to cancel scheduled reward", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    schedule,
+	})
+}
+
+// scheduleID parses the :id path parameter, aborting with a validation error if it is invalid
+func (sc *ScheduledRewardController) scheduleID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		invalidRequest(c, "Invalid scheduled reward ID", errors.New("id must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
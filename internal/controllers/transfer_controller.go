@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"net/http"
+	"stockBackend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// TransferController handles user-to-user transfer endpoints
+type TransferController struct {
+	transferService *services.TransferService
+	log             *logrus.Logger
+}
+
+// NewTransferController creates a new transfer controller
+func NewTransferController(transferService *services.TransferService, log *logrus.Logger) *TransferController {
+	return &TransferController{
+		transferService: transferService,
+		log:             log,
+	}
+}
+
+// CreateTransfer gifts stock from one user to another
+// POST /api/v1/transfers
+func (tc *TransferController) CreateTransfer(c *gin.Context) {
+	var req services.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidRequest(c, "Invalid request", err)
+		return
+	}
+
+	transfer, err := tc.transferService.Transfer(c.Request.Context(), &req)
+	if err != nil {
+		abortWithError(c, "Failed to transfer stock", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    transfer,
+	})
+}
+
+// GetTransfer retrieves a transfer by its transfer ID
+// GET /api/v1/transfers/:transferId
+func (tc *TransferController) GetTransfer(c *gin.Context) {
+	transfer, err := tc.transferService.GetTransfer(c.Request.Context(), c.Param("transferId"))
+	if err != nil {
+		abortWithError(c, "Failed to retrieve transfer", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    transfer,
+	})
+}
+
+// GetUserTransfers lists the transfers a user sent or received, newest first
+// GET /api/v1/users/:userId/transfers?limit=50&offset=0
+func (tc *TransferController) GetUserTransfers(c *gin.Context) {
+	userID := c.Param("userId")
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	offset := 0
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	transfers, err := tc.transferService.GetUserTransfers(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		abortWithError(c, "Failed to retrieve transfers", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    transfers,
+		"count":   len(transfers),
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
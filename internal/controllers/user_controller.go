@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"stockBackend/internal/models"
 	"stockBackend/internal/repository"
+	"stockBackend/internal/services"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 )
 
@@ -34,27 +38,19 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 
 	// Parse and validate request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+		invalidRequest(c, "Invalid request body", err)
 		return
 	}
 
 	// Check if user already exists
 	exists, err := uc.userRepo.Exists(c.Request.Context(), req.UserID)
 	if err != nil {
-		uc.log.Errorf("Failed to check user existence: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to check user existence",
-		})
+		abortWithError(c, "Failed to check user existence", err)
 		return
 	}
 
 	if exists {
-		c.JSON(http.StatusConflict, gin.H{
-			"error": "User already exists with this user_id",
-		})
+		abortWithError(c, "User already exists with this user_id", fmt.Errorf("%w: %s", services.ErrUserExists, req.UserID))
 		return
 	}
 
@@ -66,10 +62,7 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 	}
 
 	if err := uc.userRepo.Create(c.Request.Context(), user); err != nil {
-		uc.log.Errorf("Failed to create user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create user",
-		})
+		abortWithError(c, "Failed to create user", err)
 		return
 	}
 
@@ -92,18 +85,16 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 func (uc *UserController) GetUser(c *gin.Context) {
 	userID := c.Param("userId")
 	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
-		})
+		invalidRequest(c, "User ID is required", errors.New("userId is required"))
 		return
 	}
 
 	user, err := uc.userRepo.GetByUserID(c.Request.Context(), userID)
 	if err != nil {
-		uc.log.Errorf("Failed to get user: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = fmt.Errorf("%w: %s", services.ErrUserNotFound, userID)
+		}
+		abortWithError(c, "Failed to retrieve user", err)
 		return
 	}
 
@@ -140,10 +131,7 @@ func (uc *UserController) ListUsers(c *gin.Context) {
 
 	users, err := uc.userRepo.List(c.Request.Context(), limit, offset)
 	if err != nil {
-		uc.log.Errorf("Failed to list users: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve users",
-		})
+		abortWithError(c, "Failed to retrieve users", err)
 		return
 	}
 
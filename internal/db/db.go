@@ -3,12 +3,26 @@ package db
 import (
 	"context"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // DB holds the database connection pool
 var DB *pgxpool.Pool
 
+// Querier is the set of query methods shared by the pool and a transaction
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// txKey is the context key under which the active transaction is stored
+type txKey struct{}
+
 // InitDB initializes the database connection pool
 func InitDB(pool *pgxpool.Pool) {
 	DB = pool
@@ -19,8 +33,28 @@ func GetDB() *pgxpool.Pool {
 	return DB
 }
 
-// WithTransaction executes a function within a database transaction
-func WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+// TxFromContext returns the transaction carried by ctx, if any
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// Conn returns the transaction carried by ctx, falling back to the given pool
+func Conn(ctx context.Context, pool *pgxpool.Pool) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return pool
+}
+
+// WithTransaction executes a function within a database transaction.
+// The transaction is passed to fn through its context so repositories pick it up.
+// If ctx already carries a transaction, fn simply joins it.
+func WithTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
 	tx, err := DB.Begin(ctx)
 	if err != nil {
 		return err
@@ -37,6 +71,6 @@ func WithTransaction(ctx context.Context, fn func(ctx context.Context) error) er
 		}
 	}()
 
-	err = fn(ctx)
+	err = fn(context.WithValue(ctx, txKey{}, tx))
 	return err
 }
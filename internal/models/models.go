@@ -1,9 +1,20 @@
 package models
 
 import (
+	"stockBackend/pkg/decimal"
 	"time"
 )
 
+// Money and quantities are exact decimals at the scale of their columns and
+// are written to JSON as strings. Each field notes its places and how a
+// computed value is rounded to them; "exact" fields are sums and differences
+// of values already at their scale, or input taken as given.
+const (
+	PriceScale    = 4 // stock prices, DECIMAL(15,4)
+	AmountScale   = 2 // INR values and fees, DECIMAL(15,2)
+	QuantityScale = 6 // share quantities, DECIMAL(15,6)
+)
+
 // User represents a user in the system
 type User struct {
 	ID        int       `json:"id" db:"id"`
@@ -16,64 +27,144 @@ type User struct {
 
 // StockPrice represents a stock price record
 type StockPrice struct {
-	ID          int       `json:"id" db:"id"`
-	StockSymbol string    `json:"stock_symbol" db:"stock_symbol"`
-	Price       float64   `json:"price" db:"price"`
-	Currency    string    `json:"currency" db:"currency"`
-	Timestamp   time.Time `json:"timestamp" db:"timestamp"`
-	Source      string    `json:"source" db:"source"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID          int             `json:"id" db:"id"`
+	StockSymbol string          `json:"stock_symbol" db:"stock_symbol"`
+	Price       decimal.Decimal `json:"price" db:"price"` // 4 places; mock prices have 2, rounded down
+	Currency    string          `json:"currency" db:"currency"`
+	Timestamp   time.Time       `json:"timestamp" db:"timestamp"`
+	Source      string          `json:"source" db:"source"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
 }
 
 // Reward represents a stock reward transaction
 type Reward struct {
-	ID             int       `json:"id" db:"id"`
-	UserID         string    `json:"user_id" db:"user_id"`
-	StockSymbol    string    `json:"stock_symbol" db:"stock_symbol"`
-	Quantity       float64   `json:"quantity" db:"quantity"`
-	EventType      string    `json:"event_type" db:"event_type"`
-	EventID        string    `json:"event_id" db:"event_id"`
-	EventTimestamp time.Time `json:"event_timestamp" db:"event_timestamp"`
-	StockPrice     float64   `json:"stock_price" db:"stock_price"`
-	TotalValueINR  float64   `json:"total_value_inr" db:"total_value_inr"`
-	BrokerageFee   float64   `json:"brokerage_fee" db:"brokerage_fee"`
-	TransactionFee float64   `json:"transaction_fee" db:"transaction_fee"`
-	NetValueINR    float64   `json:"net_value_inr" db:"net_value_inr"`
-	Status         string    `json:"status" db:"status"`
-	Notes          *string   `json:"notes,omitempty" db:"notes"`
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	ID          int    `json:"id" db:"id"`
+	UserID      string `json:"user_id" db:"user_id"`
+	StockSymbol string `json:"stock_symbol" db:"stock_symbol"`
+	// Quantity has 6 places: exact as requested, or rounded by
+	// QUANTITY_ROUNDING (down by default) when bought with an INR amount
+	Quantity       decimal.Decimal `json:"quantity" db:"quantity"`
+	EventType      string          `json:"event_type" db:"event_type"`
+	EventID        string          `json:"event_id" db:"event_id"`
+	EventTimestamp time.Time       `json:"event_timestamp" db:"event_timestamp"`
+	StockPrice     decimal.Decimal `json:"stock_price" db:"stock_price"` // 4 places, the stored price
+	StockPriceID   *int            `json:"stock_price_id,omitempty" db:"stock_price_id"`
+	TotalValueINR  decimal.Decimal `json:"total_value_inr" db:"total_value_inr"` // 2 places, quantity × price rounded half-up
+	BrokerageFee   decimal.Decimal `json:"brokerage_fee" db:"brokerage_fee"`     // 2 places, the BROKERAGE charge
+	TransactionFee decimal.Decimal `json:"transaction_fee" db:"transaction_fee"` // 2 places, every other charge
+	NetValueINR    decimal.Decimal `json:"net_value_inr" db:"net_value_inr"`     // 2 places, exact: value less (or plus) fees
+	// Charges itemizes BrokerageFee and TransactionFee; it is set on rewards
+	// being booked and on single rewards read back, not on listings
+	Charges []*RewardCharge `json:"charges,omitempty" db:"-"`
+	// FeePlanID is the fee plan in force at the event time; nil when the
+	// charges came from the CHARGES_PLAN configuration
+	FeePlanID *int `json:"fee_plan_id,omitempty" db:"fee_plan_id"`
+	// RequestedAmountINR is set when the reward was requested as an INR
+	// amount; 2 places, exact
+	RequestedAmountINR *decimal.Decimal `json:"requested_amount_inr,omitempty" db:"requested_amount_inr"`
+	DeductFees         bool             `json:"deduct_fees" db:"deduct_fees"`
+	Status             string           `json:"status" db:"status"`
+	Notes              *string          `json:"notes,omitempty" db:"notes"`
+	ReversalOf         *int             `json:"reversal_of,omitempty" db:"reversal_of"`
+	ReversedAt         *time.Time       `json:"reversed_at,omitempty" db:"reversed_at"`
+	CreatedBy          *string          `json:"created_by,omitempty" db:"created_by"`
+	ReviewedBy         *string          `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt         *time.Time       `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewNote         *string          `json:"review_note,omitempty" db:"review_note"`
+	CampaignID         *int             `json:"campaign_id,omitempty" db:"campaign_id"`
+	Vesting            *VestingSchedule `json:"vesting,omitempty" db:"vesting"` // JSONB
+	ClawedBackAt       *time.Time       `json:"clawed_back_at,omitempty" db:"clawed_back_at"`
+	// BasketID and ParentEventID are set on the legs of a basket reward
+	BasketID      *int      `json:"basket_id,omitempty" db:"basket_id"`
+	ParentEventID *string   `json:"parent_event_id,omitempty" db:"parent_event_id"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Reward statuses
+const (
+	RewardStatusCompleted       = "COMPLETED"
+	RewardStatusReversed        = "REVERSED"
+	RewardStatusPendingApproval = "PENDING_APPROVAL"
+	RewardStatusApproved        = "APPROVED"
+	RewardStatusRejected        = "REJECTED"
+)
+
+// rewardTransitions lists the status changes allowed once a reward exists
+var rewardTransitions = map[string][]string{
+	RewardStatusCompleted:       {RewardStatusReversed},
+	RewardStatusPendingApproval: {RewardStatusApproved, RewardStatusRejected},
+	RewardStatusApproved:        {RewardStatusReversed},
+}
+
+// CanTransitionReward reports whether a reward may move from one status to another
+func CanTransitionReward(from, to string) bool {
+	for _, allowed := range rewardTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSettledReward reports whether a reward in this status is booked in the
+// ledger and counts towards holdings
+func IsSettledReward(status string) bool {
+	return status == RewardStatusCompleted || status == RewardStatusApproved
 }
 
 // LedgerEntry represents a double-entry ledger record
 type LedgerEntry struct {
-	ID          int       `json:"id" db:"id"`
-	RewardID    int       `json:"reward_id" db:"reward_id"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	EntryType   string    `json:"entry_type" db:"entry_type"` // DEBIT or CREDIT
-	AccountType string    `json:"account_type" db:"account_type"`
-	Amount      float64   `json:"amount" db:"amount"`
-	Currency    string    `json:"currency" db:"currency"`
-	Description *string   `json:"description,omitempty" db:"description"`
-	ReferenceID *string   `json:"reference_id,omitempty" db:"reference_id"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID          int             `json:"id" db:"id"`
+	RewardID    int             `json:"reward_id" db:"reward_id"`
+	UserID      string          `json:"user_id" db:"user_id"`
+	EntryType   string          `json:"entry_type" db:"entry_type"` // DEBIT or CREDIT
+	AccountType string          `json:"account_type" db:"account_type"`
+	Amount      decimal.Decimal `json:"amount" db:"amount"` // 2 places, the exact reward or fee value
+	Currency    string          `json:"currency" db:"currency"`
+	Description *string         `json:"description,omitempty" db:"description"`
+	ReferenceID *string         `json:"reference_id,omitempty" db:"reference_id"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// RewardCharge is one itemized charge on a reward, such as brokerage or
+// stamp duty, posted to its own ledger account
+type RewardCharge struct {
+	ID       int             `json:"id,omitempty" db:"id"`
+	RewardID int             `json:"reward_id,omitempty" db:"reward_id"`
+	Code     string          `json:"code" db:"code"`
+	Account  string          `json:"account" db:"account"`
+	Amount   decimal.Decimal `json:"amount" db:"amount"` // 2 places, rounded half-up; negative on reversals
 }
 
 // RewardRequest represents an idempotency record for reward requests
 type RewardRequest struct {
-	ID              int       `json:"id" db:"id"`
-	EventID         string    `json:"event_id" db:"event_id"`
-	UserID          string    `json:"user_id" db:"user_id"`
-	StockSymbol     string    `json:"stock_symbol" db:"stock_symbol"`
-	Quantity        float64   `json:"quantity" db:"quantity"`
-	RequestPayload  string    `json:"request_payload" db:"request_payload"`   // JSONB
-	ResponsePayload *string   `json:"response_payload,omitempty" db:"response_payload"` // JSONB
-	Status          string    `json:"status" db:"status"`
-	ProcessedAt     *time.Time `json:"processed_at,omitempty" db:"processed_at"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	ID                 int             `json:"id" db:"id"`
+	EventID            string          `json:"event_id" db:"event_id"`
+	UserID             string          `json:"user_id" db:"user_id"`
+	StockSymbol        string          `json:"stock_symbol" db:"stock_symbol"`
+	Quantity           decimal.Decimal `json:"quantity" db:"quantity"`               // 6 places, as requested
+	RequestPayload     string          `json:"request_payload" db:"request_payload"` // JSONB
+	RequestFingerprint string          `json:"request_fingerprint,omitempty" db:"request_fingerprint"`
+	ResponsePayload    *string         `json:"response_payload,omitempty" db:"response_payload"` // JSONB
+	Status             string          `json:"status" db:"status"`
+	ErrorCode          *string         `json:"error_code,omitempty" db:"error_code"`
+	ErrorMessage       *string         `json:"error_message,omitempty" db:"error_message"`
+	AttemptCount       int             `json:"attempt_count" db:"attempt_count"`
+	RiskRule           *string         `json:"risk_rule,omitempty" db:"risk_rule"`
+	LastAttemptAt      *time.Time      `json:"last_attempt_at,omitempty" db:"last_attempt_at"`
+	ProcessedAt        *time.Time      `json:"processed_at,omitempty" db:"processed_at"`
+	CreatedAt          time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// Reward request statuses
+const (
+	RequestStatusProcessing = "PROCESSING"
+	RequestStatusCompleted  = "COMPLETED"
+	RequestStatusFailed     = "FAILED"
+)
+
 // CorporateAction represents stock splits, mergers, etc.
 type CorporateAction struct {
 	ID          int        `json:"id" db:"id"`
@@ -90,41 +181,363 @@ type CorporateAction struct {
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 }
 
-// Portfolio represents aggregated user portfolio data
+// VestingSchedule is the optional vesting metadata of a reward. Either
+// CliffDays alone (a lock-in), CliffDays with Tranches and IntervalDays, or
+// explicit VestDates; every tranche vests an equal share of the quantity.
+type VestingSchedule struct {
+	CliffDays    int         `json:"cliff_days,omitempty"`
+	Tranches     int         `json:"tranches,omitempty"`
+	IntervalDays int         `json:"interval_days,omitempty"`
+	VestDates    []time.Time `json:"vest_dates,omitempty"`
+}
+
+// VestingTranche is one dated portion of a vesting reward
+type VestingTranche struct {
+	ID          int    `json:"id" db:"id"`
+	RewardID    int    `json:"reward_id" db:"reward_id"`
+	UserID      string `json:"user_id" db:"user_id"`
+	StockSymbol string `json:"stock_symbol" db:"stock_symbol"`
+	// Quantity has 6 places and ValueINR 2: each tranche's share is rounded
+	// down and the last tranche takes the remainder, so tranches sum exactly
+	Quantity  decimal.Decimal `json:"quantity" db:"quantity"`
+	ValueINR  decimal.Decimal `json:"value_inr" db:"value_inr"`
+	VestDate  time.Time       `json:"vest_date" db:"vest_date"`
+	Status    string          `json:"status" db:"status"` // UNVESTED, VESTED, CANCELLED
+	VestedAt  *time.Time      `json:"vested_at,omitempty" db:"vested_at"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Vesting tranche statuses
+const (
+	VestingStatusUnvested  = "UNVESTED"
+	VestingStatusVested    = "VESTED"
+	VestingStatusCancelled = "CANCELLED"
+)
+
+// RewardQuote is a priced reward preview whose price is locked until ExpiresAt
+type RewardQuote struct {
+	ID          int    `json:"id" db:"id"`
+	QuoteID     string `json:"quote_id" db:"quote_id"`
+	UserID      string `json:"user_id" db:"user_id"`
+	StockSymbol string `json:"stock_symbol" db:"stock_symbol"`
+	// Values are rounded as on Reward
+	Quantity       decimal.Decimal  `json:"quantity" db:"quantity"`
+	AmountINR      *decimal.Decimal `json:"amount_inr,omitempty" db:"amount_inr"`
+	DeductFees     bool             `json:"deduct_fees" db:"deduct_fees"`
+	EventType      string           `json:"event_type" db:"event_type"`
+	StockPrice     decimal.Decimal  `json:"stock_price" db:"stock_price"`
+	StockPriceID   int              `json:"stock_price_id" db:"stock_price_id"`
+	TotalValueINR  decimal.Decimal  `json:"total_value_inr" db:"total_value_inr"`
+	BrokerageFee   decimal.Decimal  `json:"brokerage_fee" db:"brokerage_fee"`
+	TransactionFee decimal.Decimal  `json:"transaction_fee" db:"transaction_fee"`
+	NetValueINR    decimal.Decimal  `json:"net_value_inr" db:"net_value_inr"`
+	// FeePlanID names the plan that set the charges; the reward that uses
+	// the quote is charged by it too
+	FeePlanID *int      `json:"fee_plan_id,omitempty" db:"fee_plan_id"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	// UsedByEventID is the event of the reward that used the quote
+	UsedByEventID *string    `json:"used_by_event_id,omitempty" db:"used_by_event_id"`
+	UsedAt        *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+
+	// Charges itemizes the fees when the quote is created; it is not stored
+	Charges []*RewardCharge `json:"charges,omitempty" db:"-"`
+}
+
+// ScheduledReward grants a reward at a future time, once or on a recurring basis
+type ScheduledReward struct {
+	ID          int    `json:"id" db:"id"`
+	UserID      string `json:"user_id" db:"user_id"`
+	StockSymbol string `json:"stock_symbol" db:"stock_symbol"`
+	// Quantity (6 places) and AmountINR (2 places) are exact, as requested
+	Quantity      *decimal.Decimal `json:"quantity,omitempty" db:"quantity"`
+	AmountINR     *decimal.Decimal `json:"amount_inr,omitempty" db:"amount_inr"`
+	EventType     string           `json:"event_type" db:"event_type"`
+	Notes         *string          `json:"notes,omitempty" db:"notes"`
+	CreatedBy     *string          `json:"created_by,omitempty" db:"created_by"`
+	Frequency     string           `json:"frequency" db:"frequency"` // ONCE, DAILY, WEEKLY, MONTHLY
+	StartAt       time.Time        `json:"start_at" db:"start_at"`
+	Occurrences   int              `json:"occurrences" db:"occurrences"`
+	RunsCompleted int              `json:"runs_completed" db:"runs_completed"`
+	NextRunAt     *time.Time       `json:"next_run_at,omitempty" db:"next_run_at"`
+	Status        string           `json:"status" db:"status"` // ACTIVE, COMPLETED, CANCELLED
+	LastError     *string          `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// Scheduled reward frequencies
+const (
+	ScheduleFrequencyOnce    = "ONCE"
+	ScheduleFrequencyDaily   = "DAILY"
+	ScheduleFrequencyWeekly  = "WEEKLY"
+	ScheduleFrequencyMonthly = "MONTHLY"
+)
+
+// Scheduled reward statuses
+const (
+	ScheduleStatusActive    = "ACTIVE"
+	ScheduleStatusCompleted = "COMPLETED"
+	ScheduleStatusCancelled = "CANCELLED"
+)
+
+// Clawback is one run that reclaimed a user's recent grants
+type Clawback struct {
+	ID         int            `json:"id" db:"id"`
+	ClawbackID string         `json:"clawback_id" db:"clawback_id"`
+	UserID     string         `json:"user_id" db:"user_id"`
+	WindowDays int            `json:"window_days" db:"window_days"`
+	EventTypes []string       `json:"event_types,omitempty" db:"event_types"`
+	Reason     *string        `json:"reason,omitempty" db:"reason"`
+	Items      []ClawbackItem `json:"items" db:"items"` // JSONB
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+}
+
+// ClawbackItem is what a clawback granted, recovered and could not recover
+// in one symbol. Quantities have 6 places and are exact sums and differences
+// of the grants; ValueINR has 2 places, rounded half-up.
+type ClawbackItem struct {
+	StockSymbol     string          `json:"stock_symbol"`
+	GrantedQuantity decimal.Decimal `json:"granted_quantity"`
+	// RecoveredQuantity is booked as a negative CLAWBACK reward under EventID
+	RecoveredQuantity decimal.Decimal `json:"recovered_quantity"`
+	// UnrecoverableQuantity was granted but is no longer held, or is held
+	// for a requested redemption. TransferredQuantity and RedeemedQuantity
+	// are the parts sent to other users and redeemed or requested for
+	// redemption; the rest was adjusted away.
+	UnrecoverableQuantity decimal.Decimal `json:"unrecoverable_quantity"`
+	TransferredQuantity   decimal.Decimal `json:"transferred_quantity"`
+	RedeemedQuantity      decimal.Decimal `json:"redeemed_quantity"`
+	CancelledUnvested     decimal.Decimal `json:"cancelled_unvested_quantity"`
+	ValueINR              decimal.Decimal `json:"value_inr"`
+	EventID               string          `json:"event_id,omitempty"`
+	RewardIDs             []int           `json:"reward_ids"`
+}
+
+// Transfer is a gift of stock from one user to another, booked as a
+// TRANSFER_OUT reward on the sender and a TRANSFER_IN reward on the receiver
+type Transfer struct {
+	ID           int              `json:"id" db:"id"`
+	TransferID   string           `json:"transfer_id" db:"transfer_id"`
+	FromUserID   string           `json:"from_user_id" db:"from_user_id"`
+	ToUserID     string           `json:"to_user_id" db:"to_user_id"`
+	StockSymbol  string           `json:"stock_symbol" db:"stock_symbol"`
+	Quantity     decimal.Decimal  `json:"quantity" db:"quantity"`                 // 6 places, exact as requested
+	StockPrice   *decimal.Decimal `json:"stock_price,omitempty" db:"stock_price"` // 4 places, the stored price
+	StockPriceID *int             `json:"stock_price_id,omitempty" db:"stock_price_id"`
+	ValueINR     *decimal.Decimal `json:"value_inr,omitempty" db:"value_inr"` // 2 places, rounded half-up
+	Note         *string          `json:"note,omitempty" db:"note"`
+	OutRewardID  *int             `json:"out_reward_id,omitempty" db:"out_reward_id"`
+	InRewardID   *int             `json:"in_reward_id,omitempty" db:"in_reward_id"`
+	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
+}
+
+// Redemption is a sale of a user's stock back for INR. Once executed it is
+// booked as a negative REDEMPTION reward.
+type Redemption struct {
+	ID               int              `json:"id" db:"id"`
+	RedemptionID     string           `json:"redemption_id" db:"redemption_id"`
+	UserID           string           `json:"user_id" db:"user_id"`
+	StockSymbol      string           `json:"stock_symbol" db:"stock_symbol"`
+	Quantity         decimal.Decimal  `json:"quantity" db:"quantity"` // 6 places, exact as requested
+	Status           string           `json:"status" db:"status"`
+	StockPrice       *decimal.Decimal `json:"stock_price,omitempty" db:"stock_price"` // 4 places, the stored price
+	StockPriceID     *int             `json:"stock_price_id,omitempty" db:"stock_price_id"`
+	GrossValueINR    *decimal.Decimal `json:"gross_value_inr,omitempty" db:"gross_value_inr"`   // 2 places, rounded half-up
+	BrokerageFee     *decimal.Decimal `json:"brokerage_fee,omitempty" db:"brokerage_fee"`       // 2 places, rounded half-up
+	TransactionFee   *decimal.Decimal `json:"transaction_fee,omitempty" db:"transaction_fee"`   // 2 places, rounded half-up
+	NetPayoutINR     *decimal.Decimal `json:"net_payout_inr,omitempty" db:"net_payout_inr"`     // 2 places, exact: gross less fees
+	CostBasisINR     *decimal.Decimal `json:"cost_basis_inr,omitempty" db:"cost_basis_inr"`     // 2 places, rounded half-up
+	RealizedPnLINR   *decimal.Decimal `json:"realized_pnl_inr,omitempty" db:"realized_pnl_inr"` // 2 places, exact: payout less cost
+	RewardID         *int             `json:"reward_id,omitempty" db:"reward_id"`
+	PaymentReference *string          `json:"payment_reference,omitempty" db:"payment_reference"`
+	RequestedAt      time.Time        `json:"requested_at" db:"requested_at"`
+	ExecutedAt       *time.Time       `json:"executed_at,omitempty" db:"executed_at"`
+	PaidAt           *time.Time       `json:"paid_at,omitempty" db:"paid_at"`
+	CancelledAt      *time.Time       `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	UpdatedAt        time.Time        `json:"updated_at" db:"updated_at"`
+
+	// Charges itemizes the fees, stored on the redemption's reward
+	Charges []*RewardCharge `json:"charges,omitempty" db:"-"`
+}
+
+// Redemption statuses
+const (
+	RedemptionStatusRequested = "REQUESTED"
+	RedemptionStatusExecuted  = "EXECUTED"
+	RedemptionStatusPaid      = "PAID"
+	RedemptionStatusCancelled = "CANCELLED"
+)
+
+// redemptionTransitions lists the status changes allowed once a redemption exists
+var redemptionTransitions = map[string][]string{
+	RedemptionStatusRequested: {RedemptionStatusExecuted, RedemptionStatusCancelled},
+	RedemptionStatusExecuted:  {RedemptionStatusPaid},
+}
+
+// CanTransitionRedemption reports whether a redemption may move from one status to another
+func CanTransitionRedemption(from, to string) bool {
+	for _, allowed := range redemptionTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// RedemptionTotals sums a user's executed and paid redemptions; 2 places, exact
+type RedemptionTotals struct {
+	PayoutINR        decimal.Decimal `json:"payout_inr"`
+	PendingPayoutINR decimal.Decimal `json:"pending_payout_inr"`
+	RealizedPnLINR   decimal.Decimal `json:"realized_pnl_inr"`
+}
+
+// UserRewardActivity is a user's recent reward volume, used by risk rules
+type UserRewardActivity struct {
+	RewardsToday      int             `json:"rewards_today"`
+	ValueINRThisMonth decimal.Decimal `json:"value_inr_this_month"` // 2 places, exact sum
+}
+
+// EventType is a registered kind of reward event and the rules its rewards follow
+type EventType struct {
+	ID            int              `json:"id" db:"id"`
+	Code          string           `json:"code" db:"code"`
+	Description   *string          `json:"description,omitempty" db:"description"`
+	AllowNegative bool             `json:"allow_negative" db:"allow_negative"`
+	MaxQuantity   *decimal.Decimal `json:"max_quantity,omitempty" db:"max_quantity"`   // 6 places, exact
+	MaxValueINR   *decimal.Decimal `json:"max_value_inr,omitempty" db:"max_value_inr"` // 2 places, exact
+	NotesRequired bool             `json:"notes_required" db:"notes_required"`
+	CreditAccount string           `json:"credit_account" db:"credit_account"` // credited when stock is granted
+	DebitAccount  string           `json:"debit_account" db:"debit_account"`   // debited when stock is taken back
+	Active        bool             `json:"active" db:"active"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// FeePlan is a dated set of charges for rewards. A plan may be limited to one
+// symbol, one event type or both; the most specific plan in force at a
+// reward's event time applies.
+type FeePlan struct {
+	ID            int        `json:"id" db:"id"`
+	Name          string     `json:"name" db:"name"`
+	StockSymbol   *string    `json:"stock_symbol,omitempty" db:"stock_symbol"` // nil for every symbol
+	EventType     *string    `json:"event_type,omitempty" db:"event_type"`     // nil for every event type
+	EffectiveFrom time.Time  `json:"effective_from" db:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty" db:"effective_to"` // exclusive; nil while open-ended
+	// Charges are computed in order and never change once the plan is
+	// created, so a stored fee_plan_id always names the rates applied
+	Charges   []FeePlanCharge `json:"charges" db:"charges"` // JSONB
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// FeePlanCharge is one charge of a fee plan: Percent of its base plus
+// FlatINR, kept between MinINR and MaxINR and rounded half-up to paise. The
+// base is the trade value, or the total of the earlier charges in LeviedOn.
+type FeePlanCharge struct {
+	Code     string           `json:"code"`
+	Account  string           `json:"account"`
+	Side     string           `json:"side,omitempty"` // BUY or SELL; empty for both
+	Percent  decimal.Decimal  `json:"percent"`
+	FlatINR  decimal.Decimal  `json:"flat_inr"`
+	MinINR   *decimal.Decimal `json:"min_inr,omitempty"`
+	MaxINR   *decimal.Decimal `json:"max_inr,omitempty"`
+	LeviedOn []string         `json:"levied_on,omitempty"`
+}
+
+// Campaign represents a reward campaign whose rule decides symbol and quantity
+type Campaign struct {
+	ID          int      `json:"id" db:"id"`
+	Name        string   `json:"name" db:"name"`
+	EventType   string   `json:"event_type" db:"event_type"`
+	RuleType    string   `json:"rule_type" db:"rule_type"` // FIXED_QUANTITY, FIXED_INR, RANDOM_BASKET
+	StockSymbol *string  `json:"stock_symbol,omitempty" db:"stock_symbol"`
+	Basket      []string `json:"basket,omitempty" db:"basket"`
+	// Quantity has 6 places and the INR fields 2; all are exact, and
+	// SpentINR sums the rounded value of each reward
+	Quantity      *decimal.Decimal `json:"quantity,omitempty" db:"quantity"`
+	AmountINR     *decimal.Decimal `json:"amount_inr,omitempty" db:"amount_inr"`
+	StartsAt      time.Time        `json:"starts_at" db:"starts_at"`
+	EndsAt        *time.Time       `json:"ends_at,omitempty" db:"ends_at"`
+	BudgetINR     decimal.Decimal  `json:"budget_inr" db:"budget_inr"`
+	SpentINR      decimal.Decimal  `json:"spent_inr" db:"spent_inr"`
+	PerUserCapINR *decimal.Decimal `json:"per_user_cap_inr,omitempty" db:"per_user_cap_inr"`
+	Active        bool             `json:"active" db:"active"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// Campaign rule types
+const (
+	CampaignRuleFixedQuantity = "FIXED_QUANTITY"
+	CampaignRuleFixedINR      = "FIXED_INR"
+	CampaignRuleRandomBasket  = "RANDOM_BASKET"
+)
+
+// Basket is a weighted set of symbols; a basket reward splits its INR amount
+// across the legs by weight
+type Basket struct {
+	ID          int         `json:"id" db:"id"`
+	Name        string      `json:"name" db:"name"`
+	Description *string     `json:"description,omitempty" db:"description"`
+	Legs        []BasketLeg `json:"legs" db:"legs"` // JSONB
+	Active      bool        `json:"active" db:"active"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// BasketLeg is one symbol of a basket; weights are relative to the basket's
+// total and exact, so the split of an amount never depends on float rounding
+type BasketLeg struct {
+	StockSymbol string          `json:"stock_symbol"`
+	Weight      decimal.Decimal `json:"weight"`
+}
+
+// Portfolio represents aggregated user portfolio data. Quantities (6 places)
+// and INR sums (2 places) are exact; percentages are floats.
 type Portfolio struct {
-	UserID            string    `json:"user_id" db:"user_id"`
-	StockSymbol       string    `json:"stock_symbol" db:"stock_symbol"`
-	TotalQuantity     float64   `json:"total_quantity" db:"total_quantity"`
-	AvgPurchasePrice  float64   `json:"avg_purchase_price" db:"avg_purchase_price"`
-	TotalInvestedINR  float64   `json:"total_invested_inr" db:"total_invested_inr"`
-	TotalFees         float64   `json:"total_fees" db:"total_fees"`
-	TransactionCount  int       `json:"transaction_count" db:"transaction_count"`
-	FirstRewardDate   time.Time `json:"first_reward_date" db:"first_reward_date"`
-	LastRewardDate    time.Time `json:"last_reward_date" db:"last_reward_date"`
-	CurrentPrice      float64   `json:"current_price,omitempty"`
-	CurrentValueINR   float64   `json:"current_value_inr,omitempty"`
-	ProfitLossINR     float64   `json:"profit_loss_inr,omitempty"`
-	ProfitLossPercent float64   `json:"profit_loss_percent,omitempty"`
-}
-
-// DailyHolding represents daily stock holdings
+	UserID            string          `json:"user_id" db:"user_id"`
+	StockSymbol       string          `json:"stock_symbol" db:"stock_symbol"`
+	TotalQuantity     decimal.Decimal `json:"total_quantity" db:"total_quantity"`
+	AvgPurchasePrice  decimal.Decimal `json:"avg_purchase_price" db:"avg_purchase_price"` // 4 places, rounded half-up
+	TotalInvestedINR  decimal.Decimal `json:"total_invested_inr" db:"total_invested_inr"`
+	TotalFees         decimal.Decimal `json:"total_fees" db:"total_fees"`
+	TransactionCount  int             `json:"transaction_count" db:"transaction_count"`
+	FirstRewardDate   time.Time       `json:"first_reward_date" db:"first_reward_date"`
+	LastRewardDate    time.Time       `json:"last_reward_date" db:"last_reward_date"`
+	CurrentPrice      decimal.Decimal `json:"current_price"`     // 4 places, the latest stored price
+	CurrentValueINR   decimal.Decimal `json:"current_value_inr"` // 2 places, rounded half-up
+	ProfitLossINR     decimal.Decimal `json:"profit_loss_inr"`
+	ProfitLossPercent float64         `json:"profit_loss_percent,omitempty"`
+	VestedQuantity    decimal.Decimal `json:"vested_quantity"`
+	UnvestedQuantity  decimal.Decimal `json:"unvested_quantity"`
+}
+
+// DailyHolding represents daily stock holdings; both sums are exact
 type DailyHolding struct {
-	UserID         string    `json:"user_id" db:"user_id"`
-	StockSymbol    string    `json:"stock_symbol" db:"stock_symbol"`
-	HoldingDate    time.Time `json:"holding_date" db:"holding_date"`
-	DailyQuantity  float64   `json:"daily_quantity" db:"daily_quantity"`
-	DailyValueINR  float64   `json:"daily_value_inr" db:"daily_value_inr"`
+	UserID        string          `json:"user_id" db:"user_id"`
+	StockSymbol   string          `json:"stock_symbol" db:"stock_symbol"`
+	HoldingDate   time.Time       `json:"holding_date" db:"holding_date"`
+	DailyQuantity decimal.Decimal `json:"daily_quantity" db:"daily_quantity"`
+	DailyValueINR decimal.Decimal `json:"daily_value_inr" db:"daily_value_inr"`
 }
 
-// UserStats represents aggregated user statistics
+// UserStats represents aggregated user statistics. Quantities (6 places) and
+// INR totals (2 places) are exact sums; percentages are floats.
 type UserStats struct {
-	UserID               string  `json:"user_id"`
-	TotalRewards         int     `json:"total_rewards"`
-	TotalStocksQuantity  float64 `json:"total_stocks_quantity"`
-	TotalInvestedINR     float64 `json:"total_invested_inr"`
-	TotalFeesINR         float64 `json:"total_fees_inr"`
-	CurrentPortfolioValue float64 `json:"current_portfolio_value"`
-	TotalProfitLossINR   float64 `json:"total_profit_loss_inr"`
-	TotalProfitLossPercent float64 `json:"total_profit_loss_percent"`
-	UniqueStocks         int     `json:"unique_stocks"`
+	UserID                 string          `json:"user_id"`
+	TotalRewards           int             `json:"total_rewards"`
+	TotalStocksQuantity    decimal.Decimal `json:"total_stocks_quantity"`
+	TotalInvestedINR       decimal.Decimal `json:"total_invested_inr"`
+	TotalFeesINR           decimal.Decimal `json:"total_fees_inr"`
+	CurrentPortfolioValue  decimal.Decimal `json:"current_portfolio_value"`
+	TotalProfitLossINR     decimal.Decimal `json:"total_profit_loss_inr"`
+	TotalProfitLossPercent float64         `json:"total_profit_loss_percent"`
+	UniqueStocks           int             `json:"unique_stocks"`
+	VestedQuantity         decimal.Decimal `json:"vested_quantity"`
+	UnvestedQuantity       decimal.Decimal `json:"unvested_quantity"`
+	// Redemption totals: INR paid or owed for executed sales, and their P&L
+	RedemptionPayoutINR decimal.Decimal `json:"redemption_payout_inr"`
+	PendingPayoutINR    decimal.Decimal `json:"pending_payout_inr"`
+	RealizedPnLINR      decimal.Decimal `json:"realized_pnl_inr"`
 }
@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestCanTransitionReward(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{RewardStatusPendingApproval, RewardStatusApproved, true},
+		{RewardStatusPendingApproval, RewardStatusRejected, true},
+		{RewardStatusPendingApproval, RewardStatusReversed, false},
+		{RewardStatusPendingApproval, RewardStatusCompleted, false},
+		{RewardStatusCompleted, RewardStatusReversed, true},
+		{RewardStatusCompleted, RewardStatusApproved, false},
+		{RewardStatusCompleted, RewardStatusRejected, false},
+		{RewardStatusApproved, RewardStatusReversed, true},
+		{RewardStatusApproved, RewardStatusRejected, false},
+		{RewardStatusApproved, RewardStatusApproved, false},
+		{RewardStatusRejected, RewardStatusApproved, false},
+		{RewardStatusRejected, RewardStatusReversed, false},
+		{RewardStatusReversed, RewardStatusCompleted, false},
+		{RewardStatusReversed, RewardStatusReversed, false},
+		{"UNKNOWN", RewardStatusApproved, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransitionReward(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransitionReward(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const basketColumns = `id, name, description, legs, active, created_at, updated_at`
+
+type basketRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewBasketRepository creates a new basket repository
+func NewBasketRepository(db *pgxpool.Pool) BasketRepository {
+	return &basketRepository{db: db}
+}
+
+func (r *basketRepository) Create(ctx context.Context, basket *models.Basket) error {
+	query := `
+		INSERT INTO baskets (name, description, legs, active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		basket.Name, basket.Description, basket.Legs, basket.Active,
+	).Scan(&basket.ID, &basket.CreatedAt, &basket.UpdatedAt)
+}
+
+func (r *basketRepository) GetByID(ctx context.Context, id int) (*models.Basket, error) {
+	query := `
+		SELECT ` + basketColumns + `
+		FROM baskets
+		WHERE id = $1
+	`
+	basket, err := r.scanBasket(db.Conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("basket not found: %w", err)
+	}
+	return basket, nil
+}
+
+func (r *basketRepository) List(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.Basket, error) {
+	query := `
+		SELECT ` + basketColumns + `
+		FROM baskets
+		WHERE active OR NOT $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, activeOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	baskets := make([]*models.Basket, 0)
+	for rows.Next() {
+		basket, err := r.scanBasket(rows)
+		if err != nil {
+			return nil, err
+		}
+		baskets = append(baskets, basket)
+	}
+	return baskets, rows.Err()
+}
+
+// SetActive enables or disables a basket. Legs never change once created, so
+// a stored basket reward splits the same way when it is replayed.
+func (r *basketRepository) SetActive(ctx context.Context, id int, active bool) (*models.Basket, error) {
+	query := `
+		UPDATE baskets
+		SET active = $1
+		WHERE id = $2
+		RETURNING ` + basketColumns
+	basket, err := r.scanBasket(db.Conn(ctx, r.db).QueryRow(ctx, query, active, id))
+	if err != nil {
+		return nil, fmt.Errorf("basket not found: %w", err)
+	}
+	return basket, nil
+}
+
+func (r *basketRepository) scanBasket(row pgx.Row) (*models.Basket, error) {
+	basket := &models.Basket{}
+	err := row.Scan(
+		&basket.ID, &basket.Name, &basket.Description, &basket.Legs,
+		&basket.Active, &basket.CreatedAt, &basket.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return basket, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type campaignRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewCampaignRepository creates a new campaign repository
+func NewCampaignRepository(db *pgxpool.Pool) CampaignRepository {
+	return &campaignRepository{db: db}
+}
+
+const campaignColumns = `id, name, event_type, rule_type, stock_symbol, basket, quantity, amount_inr,
+			starts_at, ends_at, budget_inr, spent_inr, per_user_cap_inr, active, created_at, updated_at`
+
+func (r *campaignRepository) Create(ctx context.Context, campaign *models.Campaign) error {
+	query := `
+		INSERT INTO campaigns (
+			name, event_type, rule_type, stock_symbol, basket, quantity, amount_inr,
+			starts_at, ends_at, budget_inr, per_user_cap_inr, active
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, spent_inr, created_at, updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		campaign.Name, campaign.EventType, campaign.RuleType, campaign.StockSymbol,
+		campaign.Basket, campaign.Quantity, campaign.AmountINR,
+		campaign.StartsAt, campaign.EndsAt, campaign.BudgetINR, campaign.PerUserCapINR, campaign.Active,
+	).Scan(&campaign.ID, &campaign.SpentINR, &campaign.CreatedAt, &campaign.UpdatedAt)
+}
+
+func (r *campaignRepository) GetByID(ctx context.Context, id int) (*models.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		WHERE id = $1
+	`
+	campaign, err := r.scanCampaign(db.Conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("campaign not found: %w", err)
+	}
+	return campaign, nil
+}
+
+func (r *campaignRepository) List(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.Campaign, error) {
+	query := `
+		SELECT ` + campaignColumns + `
+		FROM campaigns
+		WHERE active OR NOT $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, activeOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := make([]*models.Campaign, 0)
+	for rows.Next() {
+		campaign, err := r.scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, campaign)
+	}
+	return campaigns, rows.Err()
+}
+
+// Update changes the mutable fields of a campaign. The reward rule is fixed
+// once created so every reward of a campaign is resolved the same way.
+func (r *campaignRepository) Update(ctx context.Context, campaign *models.Campaign) error {
+	query := `
+		UPDATE campaigns
+		SET name = $1, starts_at = $2, ends_at = $3, budget_inr = $4, per_user_cap_inr = $5, active = $6
+		WHERE id = $7
+		RETURNING spent_inr, updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		campaign.Name, campaign.StartsAt, campaign.EndsAt, campaign.BudgetINR,
+		campaign.PerUserCapINR, campaign.Active, campaign.ID,
+	).Scan(&campaign.SpentINR, &campaign.UpdatedAt)
+}
+
+// ReserveBudget adds amountINR to the campaign's spend if it stays within budget.
+// The conditional update locks the row, so concurrent rewards cannot overspend.
+func (r *campaignRepository) ReserveBudget(ctx context.Context, id int, amountINR decimal.Decimal) (bool, error) {
+	query := `
+		UPDATE campaigns
+		SET spent_inr = spent_inr + $2
+		WHERE id = $1 AND spent_inr + $2 <= budget_inr
+		RETURNING id
+	`
+	var reservedID int
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, id, amountINR).Scan(&reservedID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReserveUserSpend adds amountINR to a user's campaign spend if it stays
+// within capINR; a nil cap only records the spend
+func (r *campaignRepository) ReserveUserSpend(ctx context.Context, id int, userID string, amountINR decimal.Decimal, capINR *decimal.Decimal) (bool, error) {
+	query := `
+		INSERT INTO campaign_user_spend (campaign_id, user_id, spent_inr)
+		SELECT $1, $2, $3
+		WHERE $4::numeric IS NULL OR $3 <= $4::numeric
+		ON CONFLICT (campaign_id, user_id) DO UPDATE
+		SET spent_inr = campaign_user_spend.spent_inr + EXCLUDED.spent_inr, updated_at = CURRENT_TIMESTAMP
+		WHERE $4::numeric IS NULL OR campaign_user_spend.spent_inr + EXCLUDED.spent_inr <= $4::numeric
+		RETURNING spent_inr
+	`
+	var spent decimal.Decimal
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, id, userID, amountINR, capINR).Scan(&spent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseSpend returns reserved spend to the campaign and the user
+func (r *campaignRepository) ReleaseSpend(ctx context.Context, id int, userID string, amountINR decimal.Decimal) error {
+	conn := db.Conn(ctx, r.db)
+	if _, err := conn.Exec(ctx, `
+		UPDATE campaigns SET spent_inr = GREATEST(spent_inr - $2, 0) WHERE id = $1
+	`, id, amountINR); err != nil {
+		return err
+	}
+	_, err := conn.Exec(ctx, `
+		UPDATE campaign_user_spend
+		SET spent_inr = GREATEST(spent_inr - $3, 0), updated_at = CURRENT_TIMESTAMP
+		WHERE campaign_id = $1 AND user_id = $2
+	`, id, userID, amountINR)
+	return err
+}
+
+func (r *campaignRepository) scanCampaign(row pgx.Row) (*models.Campaign, error) {
+	campaign := &models.Campaign{}
+	err := row.Scan(
+		&campaign.ID, &campaign.Name, &campaign.EventType, &campaign.RuleType,
+		&campaign.StockSymbol, &campaign.Basket, &campaign.Quantity, &campaign.AmountINR,
+		&campaign.StartsAt, &campaign.EndsAt, &campaign.BudgetINR, &campaign.SpentINR,
+		&campaign.PerUserCapINR, &campaign.Active, &campaign.CreatedAt, &campaign.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const clawbackColumns = `id, clawback_id, user_id, window_days, event_types, reason, items, created_at`
+
+type clawbackRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewClawbackRepository creates a new clawback repository
+func NewClawbackRepository(db *pgxpool.Pool) ClawbackRepository {
+	return &clawbackRepository{db: db}
+}
+
+// Claim inserts a clawback unless its clawback_id is already taken. It returns
+// false when another clawback holds the ID; a concurrent claim waits for the
+// first to commit or roll back.
+func (r *clawbackRepository) Claim(ctx context.Context, clawback *models.Clawback) (bool, error) {
+	query := `
+		INSERT INTO clawbacks (clawback_id, user_id, window_days, event_types, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (clawback_id) DO NOTHING
+		RETURNING id, created_at
+	`
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query,
+		clawback.ClawbackID, clawback.UserID, clawback.WindowDays, clawback.EventTypes, clawback.Reason,
+	).Scan(&clawback.ID, &clawback.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetItems stores the per-symbol outcome of a clawback
+func (r *clawbackRepository) SetItems(ctx context.Context, id int, items []models.ClawbackItem) error {
+	query := `UPDATE clawbacks SET items = $1 WHERE id = $2`
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, items, id)
+	return err
+}
+
+func (r *clawbackRepository) GetByClawbackID(ctx context.Context, clawbackID string) (*models.Clawback, error) {
+	query := `
+		SELECT ` + clawbackColumns + `
+		FROM clawbacks
+		WHERE clawback_id = $1
+	`
+	clawback, err := r.scanClawback(db.Conn(ctx, r.db).QueryRow(ctx, query, clawbackID))
+	if err != nil {
+		return nil, fmt.Errorf("clawback not found: %w", err)
+	}
+	return clawback, nil
+}
+
+func (r *clawbackRepository) GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Clawback, error) {
+	query := `
+		SELECT ` + clawbackColumns + `
+		FROM clawbacks
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clawbacks := make([]*models.Clawback, 0)
+	for rows.Next() {
+		clawback, err := r.scanClawback(rows)
+		if err != nil {
+			return nil, err
+		}
+		clawbacks = append(clawbacks, clawback)
+	}
+	return clawbacks, rows.Err()
+}
+
+func (r *clawbackRepository) scanClawback(row pgx.Row) (*models.Clawback, error) {
+	clawback := &models.Clawback{}
+	err := row.Scan(
+		&clawback.ID, &clawback.ClawbackID, &clawback.UserID, &clawback.WindowDays,
+		&clawback.EventTypes, &clawback.Reason, &clawback.Items, &clawback.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return clawback, nil
+}
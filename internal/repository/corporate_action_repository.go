@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"stockBackend/internal/db"
 	"stockBackend/internal/models"
 	"time"
 
@@ -27,7 +28,7 @@ func (r *corporateActionRepository) Create(ctx context.Context, action *models.C
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at, updated_at
 	`
-	return r.db.QueryRow(ctx, query,
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
 		action.StockSymbol, action.ActionType, action.ActionDate,
 		action.RatioFrom, action.RatioTo, action.NewSymbol,
 		action.Description, action.Applied,
@@ -42,7 +43,7 @@ func (r *corporateActionRepository) GetByID(ctx context.Context, id int) (*model
 		WHERE id = $1
 	`
 	action := &models.CorporateAction{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, id).Scan(
 		&action.ID, &action.StockSymbol, &action.ActionType, &action.ActionDate,
 		&action.RatioFrom, &action.RatioTo, &action.NewSymbol, &action.Description,
 		&action.Applied, &action.AppliedAt, &action.CreatedAt, &action.UpdatedAt,
@@ -61,7 +62,7 @@ func (r *corporateActionRepository) GetByStockSymbol(ctx context.Context, stockS
 		WHERE stock_symbol = $1
 		ORDER BY action_date DESC
 	`
-	rows, err := r.db.Query(ctx, query, stockSymbol)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, stockSymbol)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +79,7 @@ func (r *corporateActionRepository) GetPendingActions(ctx context.Context) ([]*m
 		WHERE applied = FALSE AND action_date <= CURRENT_DATE
 		ORDER BY action_date ASC
 	`
-	rows, err := r.db.Query(ctx, query)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +95,7 @@ func (r *corporateActionRepository) MarkApplied(ctx context.Context, id int) err
 		WHERE id = $2
 	`
 	now := time.Now()
-	_, err := r.db.Exec(ctx, query, now, id)
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, now, id)
 	return err
 }
 
@@ -106,7 +107,7 @@ func (r *corporateActionRepository) Update(ctx context.Context, action *models.C
 		WHERE id = $8
 		RETURNING updated_at
 	`
-	return r.db.QueryRow(ctx, query,
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
 		action.StockSymbol, action.ActionType, action.ActionDate,
 		action.RatioFrom, action.RatioTo, action.NewSymbol,
 		action.Description, action.ID,
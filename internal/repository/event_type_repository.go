@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const eventTypeColumns = `id, code, description, allow_negative, max_quantity, max_value_inr,
+			notes_required, credit_account, debit_account, active, created_at, updated_at`
+
+type eventTypeRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewEventTypeRepository creates a new event type repository
+func NewEventTypeRepository(db *pgxpool.Pool) EventTypeRepository {
+	return &eventTypeRepository{db: db}
+}
+
+// Create registers an event type unless its code is already taken. It
+// returns false when another event type holds the code.
+func (r *eventTypeRepository) Create(ctx context.Context, eventType *models.EventType) (bool, error) {
+	query := `
+		INSERT INTO event_types (
+			code, description, allow_negative, max_quantity, max_value_inr,
+			notes_required, credit_account, debit_account, active
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (code) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query,
+		eventType.Code, eventType.Description, eventType.AllowNegative, eventType.MaxQuantity,
+		eventType.MaxValueINR, eventType.NotesRequired, eventType.CreditAccount,
+		eventType.DebitAccount, eventType.Active,
+	).Scan(&eventType.ID, &eventType.CreatedAt, &eventType.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *eventTypeRepository) GetByCode(ctx context.Context, code string) (*models.EventType, error) {
+	query := `
+		SELECT ` + eventTypeColumns + `
+		FROM event_types
+		WHERE code = $1
+	`
+	eventType, err := r.scanEventType(db.Conn(ctx, r.db).QueryRow(ctx, query, code))
+	if err != nil {
+		return nil, fmt.Errorf("event type not found: %w", err)
+	}
+	return eventType, nil
+}
+
+// List returns the registered event types ordered by code
+func (r *eventTypeRepository) List(ctx context.Context, activeOnly bool) ([]*models.EventType, error) {
+	query := `
+		SELECT ` + eventTypeColumns + `
+		FROM event_types
+		WHERE active OR NOT $1
+		ORDER BY code
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	eventTypes := make([]*models.EventType, 0)
+	for rows.Next() {
+		eventType, err := r.scanEventType(rows)
+		if err != nil {
+			return nil, err
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+	return eventTypes, rows.Err()
+}
+
+// Update stores an event type's rules; the code is fixed once registered
+func (r *eventTypeRepository) Update(ctx context.Context, eventType *models.EventType) error {
+	query := `
+		UPDATE event_types
+		SET description = $1, allow_negative = $2, max_quantity = $3, max_value_inr = $4,
+			notes_required = $5, credit_account = $6, debit_account = $7, active = $8
+		WHERE id = $9
+		RETURNING updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		eventType.Description, eventType.AllowNegative, eventType.MaxQuantity, eventType.MaxValueINR,
+		eventType.NotesRequired, eventType.CreditAccount, eventType.DebitAccount, eventType.Active,
+		eventType.ID,
+	).Scan(&eventType.UpdatedAt)
+}
+
+func (r *eventTypeRepository) scanEventType(row pgx.Row) (*models.EventType, error) {
+	eventType := &models.EventType{}
+	err := row.Scan(
+		&eventType.ID, &eventType.Code, &eventType.Description, &eventType.AllowNegative,
+		&eventType.MaxQuantity, &eventType.MaxValueINR, &eventType.NotesRequired,
+		&eventType.CreditAccount, &eventType.DebitAccount, &eventType.Active,
+		&eventType.CreatedAt, &eventType.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return eventType, nil
+}
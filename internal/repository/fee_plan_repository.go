@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const feePlanColumns = `id, name, stock_symbol, event_type, effective_from, effective_to,
+			charges, created_at, updated_at`
+
+type feePlanRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewFeePlanRepository creates a new fee plan repository
+func NewFeePlanRepository(db *pgxpool.Pool) FeePlanRepository {
+	return &feePlanRepository{db: db}
+}
+
+func (r *feePlanRepository) Create(ctx context.Context, plan *models.FeePlan) error {
+	query := `
+		INSERT INTO fee_plans (name, stock_symbol, event_type, effective_from, effective_to, charges)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		plan.Name, plan.StockSymbol, plan.EventType, plan.EffectiveFrom, plan.EffectiveTo, plan.Charges,
+	).Scan(&plan.ID, &plan.CreatedAt, &plan.UpdatedAt)
+}
+
+func (r *feePlanRepository) GetByID(ctx context.Context, id int) (*models.FeePlan, error) {
+	query := `
+		SELECT ` + feePlanColumns + `
+		FROM fee_plans
+		WHERE id = $1
+	`
+	plan, err := r.scanFeePlan(db.Conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("fee plan not found: %w", err)
+	}
+	return plan, nil
+}
+
+// List returns the matching plans, latest first
+func (r *feePlanRepository) List(ctx context.Context, stockSymbol, eventType string, at *time.Time) ([]*models.FeePlan, error) {
+	query := `
+		SELECT ` + feePlanColumns + `
+		FROM fee_plans
+		WHERE ($1 = '' OR stock_symbol IS NULL OR stock_symbol = $1)
+		  AND ($2 = '' OR event_type IS NULL OR event_type = $2)
+		  AND ($3::timestamptz IS NULL
+		       OR (effective_from <= $3 AND (effective_to IS NULL OR effective_to > $3)))
+		ORDER BY effective_from DESC, id DESC
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, stockSymbol, eventType, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := make([]*models.FeePlan, 0)
+	for rows.Next() {
+		plan, err := r.scanFeePlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+// Update stores a plan's name and end; its scope, start and charges are fixed
+func (r *feePlanRepository) Update(ctx context.Context, plan *models.FeePlan) error {
+	query := `
+		UPDATE fee_plans
+		SET name = $1, effective_to = $2
+		WHERE id = $3
+		RETURNING updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query, plan.Name, plan.EffectiveTo, plan.ID).Scan(&plan.UpdatedAt)
+}
+
+// LockScope serializes changes to the plans of one symbol and event type
+// until the surrounding transaction ends, so two plans cannot both pass the
+// overlap check
+func (r *feePlanRepository) LockScope(ctx context.Context, stockSymbol, eventType *string) error {
+	key := "fee_plan:"
+	if stockSymbol != nil {
+		key += *stockSymbol
+	}
+	key += ":"
+	if eventType != nil {
+		key += *eventType
+	}
+
+	query := `SELECT pg_advisory_xact_lock(hashtext($1))`
+	if _, err := db.Conn(ctx, r.db).Exec(ctx, query, key); err != nil {
+		return fmt.Errorf("failed to lock fee plans: %w", err)
+	}
+	return nil
+}
+
+func (r *feePlanRepository) scanFeePlan(row pgx.Row) (*models.FeePlan, error) {
+	plan := &models.FeePlan{}
+	err := row.Scan(
+		&plan.ID, &plan.Name, &plan.StockSymbol, &plan.EventType, &plan.EffectiveFrom,
+		&plan.EffectiveTo, &plan.Charges, &plan.CreatedAt, &plan.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
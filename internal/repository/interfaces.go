@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"time"
 )
 
 // UserRepository defines the interface for user data operations
@@ -15,6 +17,7 @@ type UserRepository interface {
 	Delete(ctx context.Context, userID string) error
 	List(ctx context.Context, limit, offset int) ([]*models.User, error)
 	Exists(ctx context.Context, userID string) (bool, error)
+	ExistsBatch(ctx context.Context, userIDs []string) (map[string]bool, error)
 }
 
 // StockPriceRepository defines the interface for stock price operations
@@ -24,21 +27,138 @@ type StockPriceRepository interface {
 	GetLatestBatch(ctx context.Context, stockSymbols []string) (map[string]*models.StockPrice, error)
 	GetHistory(ctx context.Context, stockSymbol string, limit int) ([]*models.StockPrice, error)
 	GetByTimeRange(ctx context.Context, stockSymbol string, start, end string) ([]*models.StockPrice, error)
+	GetAtOrBefore(ctx context.Context, stockSymbol string, at time.Time) (*models.StockPrice, error)
+	GetAtOrBeforeBatch(ctx context.Context, stockSymbols []string, ats []time.Time) ([]*models.StockPrice, error)
 	BulkCreate(ctx context.Context, prices []*models.StockPrice) error
 }
 
 // RewardRepository defines the interface for reward operations
 type RewardRepository interface {
 	Create(ctx context.Context, reward *models.Reward) (*models.Reward, error)
+	BulkCreate(ctx context.Context, rewards []*models.Reward) error
 	GetByID(ctx context.Context, id int) (*models.Reward, error)
 	GetByEventID(ctx context.Context, eventID string) (*models.Reward, error)
+	GetByParentEventID(ctx context.Context, parentEventID string) ([]*models.Reward, error)
+	LockByEventID(ctx context.Context, eventID string) (*models.Reward, error)
+	LockHolding(ctx context.Context, userID, stockSymbol string) error
+	GetNetQuantity(ctx context.Context, userID, stockSymbol string) (decimal.Decimal, error)
+	GetAcquisitionCost(ctx context.Context, userID, stockSymbol string) (quantity, valueINR decimal.Decimal, err error)
+	LockUser(ctx context.Context, userID string) error
+	GetUserActivity(ctx context.Context, userID string) (*models.UserRewardActivity, error)
 	GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Reward, error)
 	GetTodayRewards(ctx context.Context, userID string) ([]*models.Reward, error)
 	GetHistoricalINR(ctx context.Context, userID string, startDate, endDate string) ([]*models.Reward, error)
 	Update(ctx context.Context, reward *models.Reward) error
+	MarkReversed(ctx context.Context, id int) error
+	Review(ctx context.Context, reward *models.Reward) error
+	ListByStatus(ctx context.Context, status string, limit, offset int) ([]*models.Reward, error)
+	LockGrantedSince(ctx context.Context, userID string, since time.Time, eventTypes []string) ([]*models.Reward, error)
+	MarkClawedBack(ctx context.Context, ids []int, at time.Time) error
 	Delete(ctx context.Context, id int) error
 }
 
+// CampaignRepository defines the interface for campaign operations
+type CampaignRepository interface {
+	Create(ctx context.Context, campaign *models.Campaign) error
+	GetByID(ctx context.Context, id int) (*models.Campaign, error)
+	List(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.Campaign, error)
+	Update(ctx context.Context, campaign *models.Campaign) error
+	ReserveBudget(ctx context.Context, id int, amountINR decimal.Decimal) (bool, error)
+	ReserveUserSpend(ctx context.Context, id int, userID string, amountINR decimal.Decimal, capINR *decimal.Decimal) (bool, error)
+	ReleaseSpend(ctx context.Context, id int, userID string, amountINR decimal.Decimal) error
+}
+
+// EventTypeRepository defines the interface for the event type registry
+type EventTypeRepository interface {
+	Create(ctx context.Context, eventType *models.EventType) (bool, error)
+	GetByCode(ctx context.Context, code string) (*models.EventType, error)
+	List(ctx context.Context, activeOnly bool) ([]*models.EventType, error)
+	Update(ctx context.Context, eventType *models.EventType) error
+}
+
+// BasketRepository defines the interface for stock basket operations
+type BasketRepository interface {
+	Create(ctx context.Context, basket *models.Basket) error
+	GetByID(ctx context.Context, id int) (*models.Basket, error)
+	List(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.Basket, error)
+	SetActive(ctx context.Context, id int, active bool) (*models.Basket, error)
+}
+
+// VestingRepository defines the interface for reward vesting tranches
+type VestingRepository interface {
+	BulkCreate(ctx context.Context, tranches []*models.VestingTranche) error
+	GetByRewardID(ctx context.Context, rewardID int) ([]*models.VestingTranche, error)
+	LockDue(ctx context.Context, asOf time.Time, limit int) ([]*models.VestingTranche, error)
+	MarkVested(ctx context.Context, ids []int, vestedAt time.Time) error
+	CancelByRewardID(ctx context.Context, rewardID int) ([]*models.VestingTranche, error)
+	GetUnvestedQuantities(ctx context.Context, userID string) (map[string]decimal.Decimal, error)
+}
+
+// FeePlanRepository defines the interface for effective-dated fee plans
+type FeePlanRepository interface {
+	Create(ctx context.Context, plan *models.FeePlan) error
+	GetByID(ctx context.Context, id int) (*models.FeePlan, error)
+	// List returns the plans that apply to stockSymbol and eventType, those
+	// limited to them and those for every one; empty matches any. With at,
+	// only the plans in force then are returned.
+	List(ctx context.Context, stockSymbol, eventType string, at *time.Time) ([]*models.FeePlan, error)
+	Update(ctx context.Context, plan *models.FeePlan) error
+	LockScope(ctx context.Context, stockSymbol, eventType *string) error
+}
+
+// RewardChargeRepository defines the interface for itemized reward charge operations
+type RewardChargeRepository interface {
+	BulkCreate(ctx context.Context, charges []*models.RewardCharge) error
+	GetByRewardID(ctx context.Context, rewardID int) ([]*models.RewardCharge, error)
+}
+
+// RewardQuoteRepository defines the interface for reward quote operations
+type RewardQuoteRepository interface {
+	Create(ctx context.Context, quote *models.RewardQuote) error
+	GetByQuoteID(ctx context.Context, quoteID string) (*models.RewardQuote, error)
+	Redeem(ctx context.Context, quoteID, eventID string) (bool, error)
+}
+
+// ScheduledRewardRepository defines the interface for scheduled reward operations
+type ScheduledRewardRepository interface {
+	Create(ctx context.Context, schedule *models.ScheduledReward) error
+	GetByID(ctx context.Context, id int) (*models.ScheduledReward, error)
+	List(ctx context.Context, userID, status string, limit, offset int) ([]*models.ScheduledReward, error)
+	ListDue(ctx context.Context, asOf time.Time, limit int) ([]*models.ScheduledReward, error)
+	Advance(ctx context.Context, schedule *models.ScheduledReward) (bool, error)
+	SetLastError(ctx context.Context, id int, message string) error
+	Cancel(ctx context.Context, id int) (bool, error)
+}
+
+// ClawbackRepository defines the interface for clawback operations
+type ClawbackRepository interface {
+	Claim(ctx context.Context, clawback *models.Clawback) (bool, error)
+	SetItems(ctx context.Context, id int, items []models.ClawbackItem) error
+	GetByClawbackID(ctx context.Context, clawbackID string) (*models.Clawback, error)
+	GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Clawback, error)
+}
+
+// TransferRepository defines the interface for user-to-user transfer operations
+type TransferRepository interface {
+	Claim(ctx context.Context, transfer *models.Transfer) (bool, error)
+	Complete(ctx context.Context, transfer *models.Transfer) error
+	GetByTransferID(ctx context.Context, transferID string) (*models.Transfer, error)
+	GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Transfer, error)
+	GetSentQuantitySince(ctx context.Context, userID, stockSymbol string, since time.Time) (decimal.Decimal, error)
+}
+
+// RedemptionRepository defines the interface for redemption operations
+type RedemptionRepository interface {
+	Claim(ctx context.Context, redemption *models.Redemption) (bool, error)
+	GetByRedemptionID(ctx context.Context, redemptionID string) (*models.Redemption, error)
+	LockByRedemptionID(ctx context.Context, redemptionID string) (*models.Redemption, error)
+	Update(ctx context.Context, redemption *models.Redemption) error
+	GetByUserID(ctx context.Context, userID, status string, limit, offset int) ([]*models.Redemption, error)
+	GetRequestedQuantity(ctx context.Context, userID, stockSymbol string, excludeID int) (decimal.Decimal, error)
+	GetExecutedQuantitySince(ctx context.Context, userID, stockSymbol string, since time.Time) (decimal.Decimal, error)
+	GetUserTotals(ctx context.Context, userID string) (*models.RedemptionTotals, error)
+}
+
 // LedgerRepository defines the interface for ledger operations
 type LedgerRepository interface {
 	Create(ctx context.Context, entry *models.LedgerEntry) error
@@ -51,10 +171,20 @@ type LedgerRepository interface {
 // RewardRequestRepository defines the interface for idempotency operations
 type RewardRequestRepository interface {
 	Create(ctx context.Context, request *models.RewardRequest) error
+	Claim(ctx context.Context, request *models.RewardRequest) (bool, error)
+	BulkCreate(ctx context.Context, requests []*models.RewardRequest) (map[string]bool, error)
 	GetByEventID(ctx context.Context, eventID string) (*models.RewardRequest, error)
+	GetByEventIDs(ctx context.Context, eventIDs []string) (map[string]*models.RewardRequest, error)
 	Update(ctx context.Context, request *models.RewardRequest) error
 	MarkProcessed(ctx context.Context, eventID string, responsePayload string) error
+	BulkMarkProcessed(ctx context.Context, responsePayloads map[string]string) error
+	Retry(ctx context.Context, request *models.RewardRequest) (bool, error)
+	RecordFailures(ctx context.Context, requests []*models.RewardRequest) error
+	MarkFailed(ctx context.Context, eventID string, errorCode, errorMessage string) error
+	FlagRisk(ctx context.Context, eventID, rule string) error
+	ListByStatus(ctx context.Context, status string, limit, offset int) ([]*models.RewardRequest, error)
 	GetPending(ctx context.Context, limit int) ([]*models.RewardRequest, error)
+	LockPending(ctx context.Context, eventID string) (*models.RewardRequest, error)
 }
 
 // CorporateActionRepository defines the interface for corporate action operations
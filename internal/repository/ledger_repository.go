@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"stockBackend/internal/db"
 	"stockBackend/internal/models"
 
 	"github.com/jackc/pgx/v5"
@@ -25,38 +26,31 @@ func (r *ledgerRepository) Create(ctx context.Context, entry *models.LedgerEntry
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at
 	`
-	return r.db.QueryRow(ctx, query,
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
 		entry.RewardID, entry.UserID, entry.EntryType, entry.AccountType,
 		entry.Amount, entry.Currency, entry.Description, entry.ReferenceID,
 	).Scan(&entry.ID, &entry.CreatedAt)
 }
 
+// BulkCreate writes ledger entries with COPY, which stays fast for large batches
 func (r *ledgerRepository) BulkCreate(ctx context.Context, entries []*models.LedgerEntry) error {
 	if len(entries) == 0 {
 		return nil
 	}
 
-	query := `
-		INSERT INTO ledger_entries (
-			reward_id, user_id, entry_type, account_type, amount, currency, description, reference_id
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	`
-	
-	batch := &pgx.Batch{}
+	columns := []string{
+		"reward_id", "user_id", "entry_type", "account_type", "amount", "currency", "description", "reference_id",
+	}
+	rows := make([][]any, 0, len(entries))
 	for _, entry := range entries {
-		batch.Queue(query,
+		rows = append(rows, []any{
 			entry.RewardID, entry.UserID, entry.EntryType, entry.AccountType,
 			entry.Amount, entry.Currency, entry.Description, entry.ReferenceID,
-		)
+		})
 	}
 
-	br := r.db.SendBatch(ctx, batch)
-	defer br.Close()
-
-	for range entries {
-		if _, err := br.Exec(); err != nil {
-			return fmt.Errorf("failed to insert ledger entry: %w", err)
-		}
+	if _, err := db.Conn(ctx, r.db).CopyFrom(ctx, pgx.Identifier{"ledger_entries"}, columns, pgx.CopyFromRows(rows)); err != nil {
+		return fmt.Errorf("failed to insert ledger entries: %w", err)
 	}
 
 	return nil
@@ -70,7 +64,7 @@ func (r *ledgerRepository) GetByRewardID(ctx context.Context, rewardID int) ([]*
 		WHERE reward_id = $1
 		ORDER BY created_at ASC
 	`
-	rows, err := r.db.Query(ctx, query, rewardID)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, rewardID)
 	if err != nil {
 		return nil, err
 	}
@@ -88,7 +82,7 @@ func (r *ledgerRepository) GetByUserID(ctx context.Context, userID string, limit
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
-	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +94,7 @@ func (r *ledgerRepository) GetByUserID(ctx context.Context, userID string, limit
 func (r *ledgerRepository) ValidateBalance(ctx context.Context, rewardID int) (bool, error) {
 	query := `SELECT validate_ledger_balance($1)`
 	var isBalanced bool
-	err := r.db.QueryRow(ctx, query, rewardID).Scan(&isBalanced)
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, rewardID).Scan(&isBalanced)
 	return isBalanced, err
 }
 
@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"fmt"
+	"stockBackend/internal/db"
 	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -27,7 +29,7 @@ func (r *portfolioRepository) GetUserPortfolio(ctx context.Context, userID strin
 		WHERE user_id = $1
 		ORDER BY total_invested_inr DESC
 	`
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -43,15 +45,17 @@ func (r *portfolioRepository) GetUserPortfolio(ctx context.Context, userID strin
 		); err != nil {
 			return nil, err
 		}
+		// AVG carries more places than a price
+		portfolio.AvgPurchasePrice = portfolio.AvgPurchasePrice.Round(models.PriceScale, decimal.RoundHalfUp)
 		
 		// Get current price for this stock
 		currentPrice, err := r.getCurrentPrice(ctx, portfolio.StockSymbol)
-		if err == nil && currentPrice > 0 {
+		if err == nil && currentPrice.IsPositive() {
 			portfolio.CurrentPrice = currentPrice
-			portfolio.CurrentValueINR = portfolio.TotalQuantity * currentPrice
-			portfolio.ProfitLossINR = portfolio.CurrentValueINR - portfolio.TotalInvestedINR
-			if portfolio.TotalInvestedINR > 0 {
-				portfolio.ProfitLossPercent = (portfolio.ProfitLossINR / portfolio.TotalInvestedINR) * 100
+			portfolio.CurrentValueINR = portfolio.TotalQuantity.Mul(currentPrice).Round(models.AmountScale, decimal.RoundHalfUp)
+			portfolio.ProfitLossINR = portfolio.CurrentValueINR.Sub(portfolio.TotalInvestedINR)
+			if portfolio.TotalInvestedINR.IsPositive() {
+				portfolio.ProfitLossPercent = (portfolio.ProfitLossINR.Float64() / portfolio.TotalInvestedINR.Float64()) * 100
 			}
 		}
 		
@@ -67,7 +71,7 @@ func (r *portfolioRepository) GetDailyHoldings(ctx context.Context, userID strin
 		WHERE user_id = $1 AND holding_date = $2
 		ORDER BY daily_value_inr DESC
 	`
-	rows, err := r.db.Query(ctx, query, userID, date)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, date)
 	if err != nil {
 		return nil, err
 	}
@@ -97,11 +101,11 @@ func (r *portfolioRepository) GetUserStats(ctx context.Context, userID string) (
 			SUM(brokerage_fee + transaction_fee) as total_fees_inr,
 			COUNT(DISTINCT stock_symbol) as unique_stocks
 		FROM rewards
-		WHERE user_id = $1 AND status = 'COMPLETED'
+		WHERE user_id = $1 AND ` + settledRewardFilter + `
 	`
 	
 	stats := &models.UserStats{UserID: userID}
-	err := r.db.QueryRow(ctx, statsQuery, userID).Scan(
+	err := db.Conn(ctx, r.db).QueryRow(ctx, statsQuery, userID).Scan(
 		&stats.TotalRewards,
 		&stats.TotalStocksQuantity,
 		&stats.TotalInvestedINR,
@@ -114,25 +118,25 @@ func (r *portfolioRepository) GetUserStats(ctx context.Context, userID string) (
 
 	// Get current portfolio value
 	portfolioValueQuery := `SELECT get_user_portfolio_value($1)`
-	err = r.db.QueryRow(ctx, portfolioValueQuery, userID).Scan(&stats.CurrentPortfolioValue)
+	err = db.Conn(ctx, r.db).QueryRow(ctx, portfolioValueQuery, userID).Scan(&stats.CurrentPortfolioValue)
 	if err != nil {
 		// If function doesn't exist or fails, calculate manually
-		stats.CurrentPortfolioValue = 0
+		stats.CurrentPortfolioValue = decimal.Zero
 	}
 
 	// Calculate profit/loss
-	stats.TotalProfitLossINR = stats.CurrentPortfolioValue - stats.TotalInvestedINR
-	if stats.TotalInvestedINR > 0 {
-		stats.TotalProfitLossPercent = (stats.TotalProfitLossINR / stats.TotalInvestedINR) * 100
+	stats.TotalProfitLossINR = stats.CurrentPortfolioValue.Sub(stats.TotalInvestedINR)
+	if stats.TotalInvestedINR.IsPositive() {
+		stats.TotalProfitLossPercent = (stats.TotalProfitLossINR.Float64() / stats.TotalInvestedINR.Float64()) * 100
 	}
 
 	return stats, nil
 }
 
-func (r *portfolioRepository) getCurrentPrice(ctx context.Context, stockSymbol string) (float64, error) {
+func (r *portfolioRepository) getCurrentPrice(ctx context.Context, stockSymbol string) (decimal.Decimal, error) {
 	query := `SELECT get_latest_stock_price($1)`
-	var price float64
-	err := r.db.QueryRow(ctx, query, stockSymbol).Scan(&price)
+	var price decimal.Decimal
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, stockSymbol).Scan(&price)
 	if err != nil {
 		// Fallback to direct query if function doesn't exist
 		fallbackQuery := `
@@ -141,7 +145,7 @@ func (r *portfolioRepository) getCurrentPrice(ctx context.Context, stockSymbol s
 			ORDER BY timestamp DESC
 			LIMIT 1
 		`
-		err = r.db.QueryRow(ctx, fallbackQuery, stockSymbol).Scan(&price)
+		err = db.Conn(ctx, r.db).QueryRow(ctx, fallbackQuery, stockSymbol).Scan(&price)
 	}
 	return price, err
 }
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const redemptionColumns = `id, redemption_id, user_id, stock_symbol, quantity, status,
+			stock_price, stock_price_id, gross_value_inr, brokerage_fee, transaction_fee,
+			net_payout_inr, cost_basis_inr, realized_pnl_inr, reward_id, payment_reference,
+			requested_at, executed_at, paid_at, cancelled_at, updated_at`
+
+type redemptionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRedemptionRepository creates a new redemption repository
+func NewRedemptionRepository(db *pgxpool.Pool) RedemptionRepository {
+	return &redemptionRepository{db: db}
+}
+
+// Claim inserts a REQUESTED redemption unless its redemption_id is already
+// taken. It returns false when another redemption holds the ID.
+func (r *redemptionRepository) Claim(ctx context.Context, redemption *models.Redemption) (bool, error) {
+	query := `
+		INSERT INTO redemptions (redemption_id, user_id, stock_symbol, quantity, status)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (redemption_id) DO NOTHING
+		RETURNING id, requested_at, updated_at
+	`
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query,
+		redemption.RedemptionID, redemption.UserID, redemption.StockSymbol,
+		redemption.Quantity, redemption.Status,
+	).Scan(&redemption.ID, &redemption.RequestedAt, &redemption.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *redemptionRepository) GetByRedemptionID(ctx context.Context, redemptionID string) (*models.Redemption, error) {
+	query := `
+		SELECT ` + redemptionColumns + `
+		FROM redemptions
+		WHERE redemption_id = $1
+	`
+	redemption, err := r.scanRedemption(db.Conn(ctx, r.db).QueryRow(ctx, query, redemptionID))
+	if err != nil {
+		return nil, fmt.Errorf("redemption not found: %w", err)
+	}
+	return redemption, nil
+}
+
+// LockByRedemptionID loads a redemption and locks its row until the
+// surrounding transaction ends; ctx must carry a transaction
+func (r *redemptionRepository) LockByRedemptionID(ctx context.Context, redemptionID string) (*models.Redemption, error) {
+	query := `
+		SELECT ` + redemptionColumns + `
+		FROM redemptions
+		WHERE redemption_id = $1
+		FOR UPDATE
+	`
+	redemption, err := r.scanRedemption(db.Conn(ctx, r.db).QueryRow(ctx, query, redemptionID))
+	if err != nil {
+		return nil, fmt.Errorf("redemption not found: %w", err)
+	}
+	return redemption, nil
+}
+
+// Update stores a redemption's status and the details of its sale and payment
+func (r *redemptionRepository) Update(ctx context.Context, redemption *models.Redemption) error {
+	query := `
+		UPDATE redemptions
+		SET status = $1, stock_price = $2, stock_price_id = $3, gross_value_inr = $4,
+			brokerage_fee = $5, transaction_fee = $6, net_payout_inr = $7, cost_basis_inr = $8,
+			realized_pnl_inr = $9, reward_id = $10, payment_reference = $11,
+			executed_at = $12, paid_at = $13, cancelled_at = $14
+		WHERE id = $15
+		RETURNING updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		redemption.Status, redemption.StockPrice, redemption.StockPriceID, redemption.GrossValueINR,
+		redemption.BrokerageFee, redemption.TransactionFee, redemption.NetPayoutINR, redemption.CostBasisINR,
+		redemption.RealizedPnLINR, redemption.RewardID, redemption.PaymentReference,
+		redemption.ExecutedAt, redemption.PaidAt, redemption.CancelledAt, redemption.ID,
+	).Scan(&redemption.UpdatedAt)
+}
+
+// GetByUserID lists a user's redemptions, newest first, optionally filtered by status
+func (r *redemptionRepository) GetByUserID(ctx context.Context, userID, status string, limit, offset int) ([]*models.Redemption, error) {
+	query := `
+		SELECT ` + redemptionColumns + `
+		FROM redemptions
+		WHERE user_id = $1
+			AND ($2 = '' OR status = $2)
+		ORDER BY requested_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redemptions := make([]*models.Redemption, 0)
+	for rows.Next() {
+		redemption, err := r.scanRedemption(rows)
+		if err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, redemption)
+	}
+	return redemptions, rows.Err()
+}
+
+// GetRequestedQuantity sums the quantity of a symbol the user has asked to
+// redeem but that is not executed yet, leaving out the redemption excludeID
+func (r *redemptionRepository) GetRequestedQuantity(ctx context.Context, userID, stockSymbol string, excludeID int) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0)
+		FROM redemptions
+		WHERE user_id = $1
+			AND stock_symbol = $2
+			AND status = 'REQUESTED'
+			AND id <> $3
+	`
+	var quantity decimal.Decimal
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID, stockSymbol, excludeID).Scan(&quantity)
+	return quantity, err
+}
+
+// GetExecutedQuantitySince sums the quantity of a symbol the user has sold in
+// redemptions executed since the given time, paid or not
+func (r *redemptionRepository) GetExecutedQuantitySince(ctx context.Context, userID, stockSymbol string, since time.Time) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0)
+		FROM redemptions
+		WHERE user_id = $1
+			AND stock_symbol = $2
+			AND status IN ('EXECUTED', 'PAID')
+			AND executed_at >= $3
+	`
+	var quantity decimal.Decimal
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID, stockSymbol, since).Scan(&quantity)
+	return quantity, err
+}
+
+// GetUserTotals sums the payouts and realized P&L of a user's executed and
+// paid redemptions
+func (r *redemptionRepository) GetUserTotals(ctx context.Context, userID string) (*models.RedemptionTotals, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(net_payout_inr), 0),
+			COALESCE(SUM(net_payout_inr) FILTER (WHERE status = 'EXECUTED'), 0),
+			COALESCE(SUM(realized_pnl_inr), 0)
+		FROM redemptions
+		WHERE user_id = $1 AND status IN ('EXECUTED', 'PAID')
+	`
+	totals := &models.RedemptionTotals{}
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID).Scan(
+		&totals.PayoutINR, &totals.PendingPayoutINR, &totals.RealizedPnLINR,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+func (r *redemptionRepository) scanRedemption(row pgx.Row) (*models.Redemption, error) {
+	redemption := &models.Redemption{}
+	err := row.Scan(
+		&redemption.ID, &redemption.RedemptionID, &redemption.UserID, &redemption.StockSymbol,
+		&redemption.Quantity, &redemption.Status, &redemption.StockPrice, &redemption.StockPriceID,
+		&redemption.GrossValueINR, &redemption.BrokerageFee, &redemption.TransactionFee,
+		&redemption.NetPayoutINR, &redemption.CostBasisINR, &redemption.RealizedPnLINR,
+		&redemption.RewardID, &redemption.PaymentReference, &redemption.RequestedAt,
+		&redemption.ExecutedAt, &redemption.PaidAt, &redemption.CancelledAt, &redemption.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return redemption, nil
+}
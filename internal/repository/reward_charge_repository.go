@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type rewardChargeRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRewardChargeRepository creates a new reward charge repository
+func NewRewardChargeRepository(db *pgxpool.Pool) RewardChargeRepository {
+	return &rewardChargeRepository{db: db}
+}
+
+// BulkCreate inserts reward charges in one round trip
+func (r *rewardChargeRepository) BulkCreate(ctx context.Context, charges []*models.RewardCharge) error {
+	if len(charges) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO reward_charges (reward_id, code, account, amount)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	batch := &pgx.Batch{}
+	for _, charge := range charges {
+		batch.Queue(query, charge.RewardID, charge.Code, charge.Account, charge.Amount)
+	}
+
+	br := db.Conn(ctx, r.db).SendBatch(ctx, batch)
+	defer br.Close()
+
+	for _, charge := range charges {
+		if err := br.QueryRow().Scan(&charge.ID); err != nil {
+			return fmt.Errorf("failed to create %s charge for reward %d: %w", charge.Code, charge.RewardID, err)
+		}
+	}
+
+	return nil
+}
+
+// GetByRewardID returns a reward's charges in the order they were itemized
+func (r *rewardChargeRepository) GetByRewardID(ctx context.Context, rewardID int) ([]*models.RewardCharge, error) {
+	query := `
+		SELECT id, reward_id, code, account, amount
+		FROM reward_charges
+		WHERE reward_id = $1
+		ORDER BY id ASC
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, rewardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	charges := make([]*models.RewardCharge, 0)
+	for rows.Next() {
+		charge := &models.RewardCharge{}
+		if err := rows.Scan(&charge.ID, &charge.RewardID, &charge.Code, &charge.Account, &charge.Amount); err != nil {
+			return nil, err
+		}
+		charges = append(charges, charge)
+	}
+	return charges, rows.Err()
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type rewardQuoteRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRewardQuoteRepository creates a new reward quote repository
+func NewRewardQuoteRepository(db *pgxpool.Pool) RewardQuoteRepository {
+	return &rewardQuoteRepository{db: db}
+}
+
+func (r *rewardQuoteRepository) Create(ctx context.Context, quote *models.RewardQuote) error {
+	query := `
+		INSERT INTO reward_quotes (
+			quote_id, user_id, stock_symbol, quantity, amount_inr, deduct_fees, event_type,
+			stock_price, stock_price_id, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
+			fee_plan_id, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id, created_at
+	`
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query,
+		quote.QuoteID, quote.UserID, quote.StockSymbol, quote.Quantity, quote.AmountINR, quote.DeductFees,
+		quote.EventType, quote.StockPrice, quote.StockPriceID, quote.TotalValueINR, quote.BrokerageFee,
+		quote.TransactionFee, quote.NetValueINR, quote.FeePlanID, quote.ExpiresAt,
+	).Scan(&quote.ID, &quote.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create reward quote: %w", err)
+	}
+	return nil
+}
+
+func (r *rewardQuoteRepository) GetByQuoteID(ctx context.Context, quoteID string) (*models.RewardQuote, error) {
+	query := `
+		SELECT id, quote_id, user_id, stock_symbol, quantity, amount_inr, deduct_fees, event_type,
+			stock_price, stock_price_id, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
+			fee_plan_id, expires_at, used_by_event_id, used_at, created_at
+		FROM reward_quotes
+		WHERE quote_id = $1
+	`
+	quote := &models.RewardQuote{}
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, quoteID).Scan(
+		&quote.ID, &quote.QuoteID, &quote.UserID, &quote.StockSymbol, &quote.Quantity, &quote.AmountINR,
+		&quote.DeductFees, &quote.EventType, &quote.StockPrice, &quote.StockPriceID, &quote.TotalValueINR,
+		&quote.BrokerageFee, &quote.TransactionFee, &quote.NetValueINR, &quote.FeePlanID, &quote.ExpiresAt,
+		&quote.UsedByEventID, &quote.UsedAt, &quote.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("reward quote not found: %w", err)
+	}
+	return quote, nil
+}
+
+// Redeem marks an unexpired, unused quote as used by an event. It returns
+// false if the quote has expired or was used already; a concurrent redemption
+// waits for the first to commit or roll back.
+func (r *rewardQuoteRepository) Redeem(ctx context.Context, quoteID, eventID string) (bool, error) {
+	query := `
+		UPDATE reward_quotes
+		SET used_by_event_id = $2, used_at = CURRENT_TIMESTAMP
+		WHERE quote_id = $1
+			AND used_by_event_id IS NULL
+			AND expires_at > CURRENT_TIMESTAMP
+	`
+	tag, err := db.Conn(ctx, r.db).Exec(ctx, query, quoteID, eventID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
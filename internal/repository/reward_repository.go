@@ -3,12 +3,37 @@ package repository
 import (
 	"context"
 	"fmt"
+	"stockBackend/internal/db"
 	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// rewardColumns is the column list shared by every reward SELECT, in scanReward order
+const rewardColumns = `id, user_id, stock_symbol, quantity, event_type, event_id, event_timestamp,
+			stock_price, stock_price_id, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
+			requested_amount_inr, deduct_fees, status, notes, reversal_of, reversed_at,
+			created_by, reviewed_by, reviewed_at, review_note, campaign_id, vesting, clawed_back_at,
+			basket_id, parent_event_id, fee_plan_id, created_at, updated_at`
+
+// settledRewardFilter selects the rewards that count towards holdings:
+// completed or approved, and neither a reversal nor reversed
+const settledRewardFilter = `status IN ('COMPLETED', 'APPROVED') AND reversal_of IS NULL`
+
+// rewardInsertQuery inserts one reward; its arguments come from rewardInsertArgs
+const rewardInsertQuery = `
+		INSERT INTO rewards (
+			user_id, stock_symbol, quantity, event_type, event_id, event_timestamp,
+			stock_price, stock_price_id, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
+			requested_amount_inr, deduct_fees, status, notes, reversal_of, created_by, campaign_id, vesting,
+			basket_id, parent_event_id, fee_plan_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+		RETURNING id, created_at, updated_at
+	`
+
 type rewardRepository struct {
 	db *pgxpool.Pool
 }
@@ -19,43 +44,45 @@ func NewRewardRepository(db *pgxpool.Pool) RewardRepository {
 }
 
 func (r *rewardRepository) Create(ctx context.Context, reward *models.Reward) (*models.Reward, error) {
-	query := `
-		INSERT INTO rewards (
-			user_id, stock_symbol, quantity, event_type, event_id, event_timestamp,
-			stock_price, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
-			status, notes
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
-		RETURNING id, created_at, updated_at
-	`
-	err := r.db.QueryRow(ctx, query,
-		reward.UserID, reward.StockSymbol, reward.Quantity, reward.EventType,
-		reward.EventID, reward.EventTimestamp, reward.StockPrice, reward.TotalValueINR,
-		reward.BrokerageFee, reward.TransactionFee, reward.NetValueINR,
-		reward.Status, reward.Notes,
-	).Scan(&reward.ID, &reward.CreatedAt, &reward.UpdatedAt)
-	
+	err := db.Conn(ctx, r.db).QueryRow(ctx, rewardInsertQuery, rewardInsertArgs(reward)...).
+		Scan(&reward.ID, &reward.CreatedAt, &reward.UpdatedAt)
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to create reward: %w", err)
 	}
 	return reward, nil
 }
 
+// BulkCreate inserts rewards in one round trip and fills in their generated IDs
+func (r *rewardRepository) BulkCreate(ctx context.Context, rewards []*models.Reward) error {
+	if len(rewards) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, reward := range rewards {
+		batch.Queue(rewardInsertQuery, rewardInsertArgs(reward)...)
+	}
+
+	br := db.Conn(ctx, r.db).SendBatch(ctx, batch)
+	defer br.Close()
+
+	for _, reward := range rewards {
+		if err := br.QueryRow().Scan(&reward.ID, &reward.CreatedAt, &reward.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to create reward %s: %w", reward.EventID, err)
+		}
+	}
+
+	return nil
+}
+
 func (r *rewardRepository) GetByID(ctx context.Context, id int) (*models.Reward, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, quantity, event_type, event_id, event_timestamp,
-			stock_price, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
-			status, notes, created_at, updated_at
+		SELECT ` + rewardColumns + `
 		FROM rewards
 		WHERE id = $1
 	`
-	reward := &models.Reward{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&reward.ID, &reward.UserID, &reward.StockSymbol, &reward.Quantity,
-		&reward.EventType, &reward.EventID, &reward.EventTimestamp, &reward.StockPrice,
-		&reward.TotalValueINR, &reward.BrokerageFee, &reward.TransactionFee,
-		&reward.NetValueINR, &reward.Status, &reward.Notes,
-		&reward.CreatedAt, &reward.UpdatedAt,
-	)
+	reward, err := r.scanReward(db.Conn(ctx, r.db).QueryRow(ctx, query, id))
 	if err != nil {
 		return nil, fmt.Errorf("reward not found: %w", err)
 	}
@@ -64,37 +91,137 @@ func (r *rewardRepository) GetByID(ctx context.Context, id int) (*models.Reward,
 
 func (r *rewardRepository) GetByEventID(ctx context.Context, eventID string) (*models.Reward, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, quantity, event_type, event_id, event_timestamp,
-			stock_price, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
-			status, notes, created_at, updated_at
+		SELECT ` + rewardColumns + `
 		FROM rewards
 		WHERE event_id = $1
 	`
-	reward := &models.Reward{}
-	err := r.db.QueryRow(ctx, query, eventID).Scan(
-		&reward.ID, &reward.UserID, &reward.StockSymbol, &reward.Quantity,
-		&reward.EventType, &reward.EventID, &reward.EventTimestamp, &reward.StockPrice,
-		&reward.TotalValueINR, &reward.BrokerageFee, &reward.TransactionFee,
-		&reward.NetValueINR, &reward.Status, &reward.Notes,
-		&reward.CreatedAt, &reward.UpdatedAt,
-	)
+	reward, err := r.scanReward(db.Conn(ctx, r.db).QueryRow(ctx, query, eventID))
+	if err != nil {
+		return nil, fmt.Errorf("reward not found: %w", err)
+	}
+	return reward, nil
+}
+
+// GetByParentEventID returns the legs of a basket reward in the order they were booked
+func (r *rewardRepository) GetByParentEventID(ctx context.Context, parentEventID string) ([]*models.Reward, error) {
+	query := `
+		SELECT ` + rewardColumns + `
+		FROM rewards
+		WHERE parent_event_id = $1
+		ORDER BY id ASC
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, parentEventID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRewards(rows)
+}
+
+// LockByEventID loads a reward and locks its row until the surrounding transaction ends
+func (r *rewardRepository) LockByEventID(ctx context.Context, eventID string) (*models.Reward, error) {
+	query := `
+		SELECT ` + rewardColumns + `
+		FROM rewards
+		WHERE event_id = $1
+		FOR UPDATE
+	`
+	reward, err := r.scanReward(db.Conn(ctx, r.db).QueryRow(ctx, query, eventID))
 	if err != nil {
 		return nil, fmt.Errorf("reward not found: %w", err)
 	}
 	return reward, nil
 }
 
+// LockHolding serializes changes to one user's position in a symbol until the
+// surrounding transaction ends. It is an advisory lock, so it also covers
+// positions that have no reward rows yet.
+func (r *rewardRepository) LockHolding(ctx context.Context, userID, stockSymbol string) error {
+	query := `SELECT pg_advisory_xact_lock(hashtext($1))`
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, userID+":"+stockSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to lock holding: %w", err)
+	}
+	return nil
+}
+
+// GetNetQuantity returns the user's current net quantity of a symbol,
+// counting the same rewards as v_user_portfolio
+func (r *rewardRepository) GetNetQuantity(ctx context.Context, userID, stockSymbol string) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0)
+		FROM rewards
+		WHERE user_id = $1
+			AND stock_symbol = $2
+			AND ` + settledRewardFilter + `
+	`
+	var quantity decimal.Decimal
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID, stockSymbol).Scan(&quantity)
+	return quantity, err
+}
+
+// GetAcquisitionCost returns the quantity and INR value of every settled
+// reward that added a symbol to the user's holding; their ratio is the
+// holding's average cost
+func (r *rewardRepository) GetAcquisitionCost(ctx context.Context, userID, stockSymbol string) (decimal.Decimal, decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0), COALESCE(SUM(total_value_inr), 0)
+		FROM rewards
+		WHERE user_id = $1
+			AND stock_symbol = $2
+			AND quantity > 0
+			AND ` + settledRewardFilter + `
+	`
+	var quantity, valueINR decimal.Decimal
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID, stockSymbol).Scan(&quantity, &valueINR)
+	return quantity, valueINR, err
+}
+
+// LockUser serializes risk checks on one user's rewards until the surrounding
+// transaction ends; callers take it after any holding locks they need
+func (r *rewardRepository) LockUser(ctx context.Context, userID string) error {
+	query := `SELECT pg_advisory_xact_lock(hashtext($1))`
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, "risk:"+userID)
+	if err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	return nil
+}
+
+// GetUserActivity counts the stock a user was granted today and this month.
+// Pending rewards count; rejected ones, adjustments, reversals and stock
+// received by transfer do not.
+func (r *rewardRepository) GetUserActivity(ctx context.Context, userID string) (*models.UserRewardActivity, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE created_at >= date_trunc('day', CURRENT_TIMESTAMP)),
+			COALESCE(SUM(total_value_inr), 0)
+		FROM rewards
+		WHERE user_id = $1
+			AND created_at >= date_trunc('month', CURRENT_TIMESTAMP)
+			AND quantity > 0
+			AND status <> 'REJECTED'
+			AND reversal_of IS NULL
+			AND event_type <> 'TRANSFER_IN'
+	`
+	activity := &models.UserRewardActivity{}
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID).Scan(&activity.RewardsToday, &activity.ValueINRThisMonth)
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
 func (r *rewardRepository) GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Reward, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, quantity, event_type, event_id, event_timestamp,
-			stock_price, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
-			status, notes, created_at, updated_at
+		SELECT ` + rewardColumns + `
 		FROM rewards
 		WHERE user_id = $1
 		ORDER BY event_timestamp DESC
 		LIMIT $2 OFFSET $3
 	`
-	rows, err := r.db.Query(ctx, query, userID, limit, offset)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -105,16 +232,14 @@ func (r *rewardRepository) GetByUserID(ctx context.Context, userID string, limit
 
 func (r *rewardRepository) GetTodayRewards(ctx context.Context, userID string) ([]*models.Reward, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, quantity, event_type, event_id, event_timestamp,
-			stock_price, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
-			status, notes, created_at, updated_at
+		SELECT ` + rewardColumns + `
 		FROM rewards
-		WHERE user_id = $1 
+		WHERE user_id = $1
 			AND DATE(event_timestamp) = CURRENT_DATE
-			AND status = 'COMPLETED'
+			AND ` + settledRewardFilter + `
 		ORDER BY event_timestamp DESC
 	`
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -125,16 +250,14 @@ func (r *rewardRepository) GetTodayRewards(ctx context.Context, userID string) (
 
 func (r *rewardRepository) GetHistoricalINR(ctx context.Context, userID string, startDate, endDate string) ([]*models.Reward, error) {
 	query := `
-		SELECT id, user_id, stock_symbol, quantity, event_type, event_id, event_timestamp,
-			stock_price, total_value_inr, brokerage_fee, transaction_fee, net_value_inr,
-			status, notes, created_at, updated_at
+		SELECT ` + rewardColumns + `
 		FROM rewards
-		WHERE user_id = $1 
+		WHERE user_id = $1
 			AND event_timestamp BETWEEN $2 AND $3
-			AND status = 'COMPLETED'
+			AND ` + settledRewardFilter + `
 		ORDER BY event_timestamp DESC
 	`
-	rows, err := r.db.Query(ctx, query, userID, startDate, endDate)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
@@ -150,27 +273,129 @@ func (r *rewardRepository) Update(ctx context.Context, reward *models.Reward) er
 		WHERE id = $3
 		RETURNING updated_at
 	`
-	return r.db.QueryRow(ctx, query, reward.Status, reward.Notes, reward.ID).
+	return db.Conn(ctx, r.db).QueryRow(ctx, query, reward.Status, reward.Notes, reward.ID).
 		Scan(&reward.UpdatedAt)
 }
 
+func (r *rewardRepository) MarkReversed(ctx context.Context, id int) error {
+	query := `
+		UPDATE rewards
+		SET status = $1, reversed_at = $2
+		WHERE id = $3
+	`
+	now := time.Now()
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, models.RewardStatusReversed, now, id)
+	return err
+}
+
+// Review records an approval decision on a reward
+func (r *rewardRepository) Review(ctx context.Context, reward *models.Reward) error {
+	query := `
+		UPDATE rewards
+		SET status = $1, reviewed_by = $2, reviewed_at = $3, review_note = $4
+		WHERE id = $5
+		RETURNING updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		reward.Status, reward.ReviewedBy, reward.ReviewedAt, reward.ReviewNote, reward.ID,
+	).Scan(&reward.UpdatedAt)
+}
+
+// ListByStatus lists rewards in a status, oldest first so reviewers work in order
+func (r *rewardRepository) ListByStatus(ctx context.Context, status string, limit, offset int) ([]*models.Reward, error) {
+	query := `
+		SELECT ` + rewardColumns + `
+		FROM rewards
+		WHERE status = $1
+		ORDER BY created_at ASC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRewards(rows)
+}
+
+// LockGrantedSince locks a user's settled grants since the given time that no
+// clawback has covered yet, optionally limited to some event types. Stock
+// received by transfer was not granted to the user and is not included.
+func (r *rewardRepository) LockGrantedSince(ctx context.Context, userID string, since time.Time, eventTypes []string) ([]*models.Reward, error) {
+	query := `
+		SELECT ` + rewardColumns + `
+		FROM rewards
+		WHERE user_id = $1
+			AND event_timestamp >= $2
+			AND quantity > 0
+			AND clawed_back_at IS NULL
+			AND event_type <> 'TRANSFER_IN'
+			AND ` + settledRewardFilter + `
+			AND (cardinality($3::text[]) = 0 OR event_type = ANY($3))
+		ORDER BY id ASC
+		FOR UPDATE
+	`
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, since, eventTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRewards(rows)
+}
+
+// MarkClawedBack records that a clawback covered the given grants
+func (r *rewardRepository) MarkClawedBack(ctx context.Context, ids []int, at time.Time) error {
+	query := `UPDATE rewards SET clawed_back_at = $1, updated_at = CURRENT_TIMESTAMP WHERE id = ANY($2)`
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, at, ids)
+	return err
+}
+
 func (r *rewardRepository) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM rewards WHERE id = $1`
-	_, err := r.db.Exec(ctx, query, id)
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, id)
 	return err
 }
 
+func rewardInsertArgs(reward *models.Reward) []any {
+	return []any{
+		reward.UserID, reward.StockSymbol, reward.Quantity, reward.EventType,
+		reward.EventID, reward.EventTimestamp, reward.StockPrice, reward.StockPriceID, reward.TotalValueINR,
+		reward.BrokerageFee, reward.TransactionFee, reward.NetValueINR,
+		reward.RequestedAmountINR, reward.DeductFees, reward.Status, reward.Notes, reward.ReversalOf,
+		reward.CreatedBy, reward.CampaignID, reward.Vesting, reward.BasketID, reward.ParentEventID,
+		reward.FeePlanID,
+	}
+}
+
+func (r *rewardRepository) scanReward(row pgx.Row) (*models.Reward, error) {
+	reward := &models.Reward{}
+	err := row.Scan(
+		&reward.ID, &reward.UserID, &reward.StockSymbol, &reward.Quantity,
+		&reward.EventType, &reward.EventID, &reward.EventTimestamp, &reward.StockPrice,
+		&reward.StockPriceID, &reward.TotalValueINR, &reward.BrokerageFee, &reward.TransactionFee,
+		&reward.NetValueINR, &reward.RequestedAmountINR, &reward.DeductFees,
+		&reward.Status, &reward.Notes,
+		&reward.ReversalOf, &reward.ReversedAt,
+		&reward.CreatedBy, &reward.ReviewedBy, &reward.ReviewedAt, &reward.ReviewNote, &reward.CampaignID, &reward.Vesting,
+		&reward.ClawedBackAt, &reward.BasketID, &reward.ParentEventID, &reward.FeePlanID,
+		&reward.CreatedAt, &reward.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return reward, nil
+}
+
 func (r *rewardRepository) scanRewards(rows pgx.Rows) ([]*models.Reward, error) {
 	var rewards []*models.Reward
 	for rows.Next() {
-		reward := &models.Reward{}
-		if err := rows.Scan(
-			&reward.ID, &reward.UserID, &reward.StockSymbol, &reward.Quantity,
-			&reward.EventType, &reward.EventID, &reward.EventTimestamp, &reward.StockPrice,
-			&reward.TotalValueINR, &reward.BrokerageFee, &reward.TransactionFee,
-			&reward.NetValueINR, &reward.Status, &reward.Notes,
-			&reward.CreatedAt, &reward.UpdatedAt,
-		); err != nil {
+		reward, err := r.scanReward(rows)
+		if err != nil {
 			return nil, err
 		}
 		rewards = append(rewards, reward)
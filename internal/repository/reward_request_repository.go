@@ -2,13 +2,22 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"stockBackend/internal/db"
 	"stockBackend/internal/models"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// rewardRequestColumns is the column list shared by every reward_requests SELECT
+const rewardRequestColumns = `id, event_id, user_id, stock_symbol, quantity, request_payload,
+			COALESCE(request_fingerprint, ''), response_payload, status, error_code, error_message,
+			attempt_count, risk_rule, last_attempt_at, processed_at, created_at, updated_at`
+
 type rewardRequestRepository struct {
 	db *pgxpool.Pool
 }
@@ -21,35 +30,135 @@ func NewRewardRequestRepository(db *pgxpool.Pool) RewardRequestRepository {
 func (r *rewardRequestRepository) Create(ctx context.Context, request *models.RewardRequest) error {
 	query := `
 		INSERT INTO reward_requests (
-			event_id, user_id, stock_symbol, quantity, request_payload, status
-		) VALUES ($1, $2, $3, $4, $5, $6)
+			event_id, user_id, stock_symbol, quantity, request_payload,
+			request_fingerprint, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		request.EventID, request.UserID, request.StockSymbol,
+		request.Quantity, request.RequestPayload, request.RequestFingerprint, request.Status,
+	).Scan(&request.ID, &request.CreatedAt, &request.UpdatedAt)
+}
+
+// Claim inserts the idempotency record of a new event. It returns false if the
+// event_id is already taken. When the existing record belongs to a transaction
+// that is still open, the insert waits for it to commit or roll back first.
+func (r *rewardRequestRepository) Claim(ctx context.Context, request *models.RewardRequest) (bool, error) {
+	query := `
+		INSERT INTO reward_requests (
+			event_id, user_id, stock_symbol, quantity, request_payload,
+			request_fingerprint, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (event_id) DO NOTHING
 		RETURNING id, created_at, updated_at
 	`
-	return r.db.QueryRow(ctx, query,
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query,
 		request.EventID, request.UserID, request.StockSymbol,
-		request.Quantity, request.RequestPayload, request.Status,
+		request.Quantity, request.RequestPayload, request.RequestFingerprint, request.Status,
 	).Scan(&request.ID, &request.CreatedAt, &request.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// BulkCreate inserts idempotency records in one round trip. A FAILED record
+// with the same event_id is reclaimed for another attempt; any other existing
+// record is left alone. The returned set holds the event IDs that were claimed.
+// Rows are written in event_id order so overlapping concurrent batches cannot deadlock.
+func (r *rewardRequestRepository) BulkCreate(ctx context.Context, requests []*models.RewardRequest) (map[string]bool, error) {
+	created := make(map[string]bool, len(requests))
+	if len(requests) == 0 {
+		return created, nil
+	}
+
+	requests = append([]*models.RewardRequest(nil), requests...)
+	sort.Slice(requests, func(i, j int) bool { return requests[i].EventID < requests[j].EventID })
+
+	query := `
+		INSERT INTO reward_requests (
+			event_id, user_id, stock_symbol, quantity, request_payload,
+			request_fingerprint, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (event_id) DO UPDATE
+		SET request_payload = EXCLUDED.request_payload,
+			request_fingerprint = EXCLUDED.request_fingerprint,
+			status = EXCLUDED.status,
+			error_code = NULL,
+			error_message = NULL,
+			risk_rule = NULL,
+			attempt_count = reward_requests.attempt_count + 1,
+			last_attempt_at = CURRENT_TIMESTAMP
+		WHERE reward_requests.status = 'FAILED'
+		RETURNING id, created_at, updated_at
+	`
+
+	batch := &pgx.Batch{}
+	for _, request := range requests {
+		batch.Queue(query,
+			request.EventID, request.UserID, request.StockSymbol,
+			request.Quantity, request.RequestPayload, request.RequestFingerprint, request.Status,
+		)
+	}
+
+	br := db.Conn(ctx, r.db).SendBatch(ctx, batch)
+	defer br.Close()
+
+	for _, request := range requests {
+		err := br.QueryRow().Scan(&request.ID, &request.CreatedAt, &request.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert reward request: %w", err)
+		}
+		created[request.EventID] = true
+	}
+
+	return created, nil
 }
 
 func (r *rewardRequestRepository) GetByEventID(ctx context.Context, eventID string) (*models.RewardRequest, error) {
 	query := `
-		SELECT id, event_id, user_id, stock_symbol, quantity, request_payload,
-			response_payload, status, processed_at, created_at, updated_at
+		SELECT ` + rewardRequestColumns + `
 		FROM reward_requests
 		WHERE event_id = $1
 	`
-	request := &models.RewardRequest{}
-	err := r.db.QueryRow(ctx, query, eventID).Scan(
-		&request.ID, &request.EventID, &request.UserID, &request.StockSymbol,
-		&request.Quantity, &request.RequestPayload, &request.ResponsePayload,
-		&request.Status, &request.ProcessedAt, &request.CreatedAt, &request.UpdatedAt,
-	)
+	request, err := r.scanRequest(db.Conn(ctx, r.db).QueryRow(ctx, query, eventID))
 	if err != nil {
 		return nil, fmt.Errorf("reward request not found: %w", err)
 	}
 	return request, nil
 }
 
+func (r *rewardRequestRepository) GetByEventIDs(ctx context.Context, eventIDs []string) (map[string]*models.RewardRequest, error) {
+	query := `
+		SELECT ` + rewardRequestColumns + `
+		FROM reward_requests
+		WHERE event_id = ANY($1)
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests, err := r.scanRequests(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	byEventID := make(map[string]*models.RewardRequest, len(requests))
+	for _, request := range requests {
+		byEventID[request.EventID] = request
+	}
+	return byEventID, nil
+}
+
 func (r *rewardRequestRepository) Update(ctx context.Context, request *models.RewardRequest) error {
 	query := `
 		UPDATE reward_requests
@@ -57,7 +166,7 @@ func (r *rewardRequestRepository) Update(ctx context.Context, request *models.Re
 		WHERE event_id = $4
 		RETURNING updated_at
 	`
-	return r.db.QueryRow(ctx, query,
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
 		request.ResponsePayload, request.Status, request.ProcessedAt, request.EventID,
 	).Scan(&request.UpdatedAt)
 }
@@ -69,33 +178,194 @@ func (r *rewardRequestRepository) MarkProcessed(ctx context.Context, eventID str
 		WHERE event_id = $3
 	`
 	now := time.Now()
-	_, err := r.db.Exec(ctx, query, responsePayload, now, eventID)
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, responsePayload, now, eventID)
+	return err
+}
+
+// BulkMarkProcessed completes many requests in one round trip, keyed by event ID
+func (r *rewardRequestRepository) BulkMarkProcessed(ctx context.Context, responsePayloads map[string]string) error {
+	if len(responsePayloads) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE reward_requests
+		SET response_payload = $1, status = 'COMPLETED', processed_at = $2
+		WHERE event_id = $3
+	`
+	now := time.Now()
+
+	batch := &pgx.Batch{}
+	for eventID, payload := range responsePayloads {
+		batch.Queue(query, payload, now, eventID)
+	}
+
+	br := db.Conn(ctx, r.db).SendBatch(ctx, batch)
+	defer br.Close()
+
+	for range responsePayloads {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("failed to mark reward request processed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Retry reclaims a FAILED request for another attempt. It returns false if
+// the request is no longer FAILED, e.g. because another attempt claimed it.
+func (r *rewardRequestRepository) Retry(ctx context.Context, request *models.RewardRequest) (bool, error) {
+	query := `
+		UPDATE reward_requests
+		SET request_payload = $1, request_fingerprint = $2, status = 'PROCESSING',
+			error_code = NULL, error_message = NULL, risk_rule = NULL,
+			attempt_count = attempt_count + 1, last_attempt_at = CURRENT_TIMESTAMP
+		WHERE event_id = $3 AND status = 'FAILED'
+		RETURNING id, attempt_count, created_at, updated_at
+	`
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query,
+		request.RequestPayload, request.RequestFingerprint, request.EventID,
+	).Scan(&request.ID, &request.AttemptCount, &request.CreatedAt, &request.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RecordFailures stores failed attempts outside the failed transaction.
+// A missing record is inserted as FAILED; an existing FAILED record gets its
+// error replaced and attempt count bumped. Completed records are never touched.
+func (r *rewardRequestRepository) RecordFailures(ctx context.Context, requests []*models.RewardRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO reward_requests (
+			event_id, user_id, stock_symbol, quantity, request_payload,
+			request_fingerprint, status, error_code, error_message, risk_rule, attempt_count, last_attempt_at
+		) VALUES ($1, $2, $3, $4, $5, $6, 'FAILED', $7, $8, $9, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (event_id) DO UPDATE
+		SET error_code = EXCLUDED.error_code,
+			error_message = EXCLUDED.error_message,
+			risk_rule = EXCLUDED.risk_rule,
+			attempt_count = reward_requests.attempt_count + 1,
+			last_attempt_at = CURRENT_TIMESTAMP
+		WHERE reward_requests.status = 'FAILED'
+	`
+
+	batch := &pgx.Batch{}
+	for _, request := range requests {
+		batch.Queue(query,
+			request.EventID, request.UserID, request.StockSymbol, request.Quantity,
+			request.RequestPayload, request.RequestFingerprint,
+			request.ErrorCode, request.ErrorMessage, request.RiskRule,
+		)
+	}
+
+	br := db.Conn(ctx, r.db).SendBatch(ctx, batch)
+	defer br.Close()
+
+	for range requests {
+		if _, err := br.Exec(); err != nil {
+			return fmt.Errorf("failed to record reward request failure: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FlagRisk records the risk rule that sent a request's reward to review
+func (r *rewardRequestRepository) FlagRisk(ctx context.Context, eventID, rule string) error {
+	query := `UPDATE reward_requests SET risk_rule = $1 WHERE event_id = $2`
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, rule, eventID)
+	return err
+}
+
+// MarkFailed moves an existing request to FAILED with the given reason
+func (r *rewardRequestRepository) MarkFailed(ctx context.Context, eventID string, errorCode, errorMessage string) error {
+	query := `
+		UPDATE reward_requests
+		SET status = 'FAILED', error_code = $1, error_message = $2, last_attempt_at = $3
+		WHERE event_id = $4
+	`
+	now := time.Now()
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, errorCode, errorMessage, now, eventID)
 	return err
 }
 
+func (r *rewardRequestRepository) ListByStatus(ctx context.Context, status string, limit, offset int) ([]*models.RewardRequest, error) {
+	query := `
+		SELECT ` + rewardRequestColumns + `
+		FROM reward_requests
+		WHERE status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanRequests(rows)
+}
+
 func (r *rewardRequestRepository) GetPending(ctx context.Context, limit int) ([]*models.RewardRequest, error) {
 	query := `
-		SELECT id, event_id, user_id, stock_symbol, quantity, request_payload,
-			response_payload, status, processed_at, created_at, updated_at
+		SELECT ` + rewardRequestColumns + `
 		FROM reward_requests
 		WHERE status = 'PROCESSING'
 		ORDER BY created_at ASC
 		LIMIT $1
 	`
-	rows, err := r.db.Query(ctx, query, limit)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	return r.scanRequests(rows)
+}
+
+// LockPending locks a request that is still PROCESSING for the surrounding
+// transaction. Rows already locked by another worker are skipped, returning pgx.ErrNoRows.
+func (r *rewardRequestRepository) LockPending(ctx context.Context, eventID string) (*models.RewardRequest, error) {
+	query := `
+		SELECT ` + rewardRequestColumns + `
+		FROM reward_requests
+		WHERE event_id = $1 AND status = 'PROCESSING'
+		FOR UPDATE SKIP LOCKED
+	`
+	request, err := r.scanRequest(db.Conn(ctx, r.db).QueryRow(ctx, query, eventID))
+	if err != nil {
+		return nil, fmt.Errorf("pending reward request not found: %w", err)
+	}
+	return request, nil
+}
+
+func (r *rewardRequestRepository) scanRequest(row pgx.Row) (*models.RewardRequest, error) {
+	request := &models.RewardRequest{}
+	err := row.Scan(
+		&request.ID, &request.EventID, &request.UserID, &request.StockSymbol,
+		&request.Quantity, &request.RequestPayload, &request.RequestFingerprint,
+		&request.ResponsePayload, &request.Status, &request.ErrorCode, &request.ErrorMessage,
+		&request.AttemptCount, &request.RiskRule, &request.LastAttemptAt, &request.ProcessedAt, &request.CreatedAt, &request.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (r *rewardRequestRepository) scanRequests(rows pgx.Rows) ([]*models.RewardRequest, error) {
 	var requests []*models.RewardRequest
 	for rows.Next() {
-		request := &models.RewardRequest{}
-		if err := rows.Scan(
-			&request.ID, &request.EventID, &request.UserID, &request.StockSymbol,
-			&request.Quantity, &request.RequestPayload, &request.ResponsePayload,
-			&request.Status, &request.ProcessedAt, &request.CreatedAt, &request.UpdatedAt,
-		); err != nil {
+		request, err := r.scanRequest(rows)
+		if err != nil {
 			return nil, err
 		}
 		requests = append(requests, request)
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const scheduledRewardColumns = `id, user_id, stock_symbol, quantity, amount_inr, event_type, notes, created_by,
+			frequency, start_at, occurrences, runs_completed, next_run_at, status, last_error, created_at, updated_at`
+
+type scheduledRewardRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewScheduledRewardRepository creates a new scheduled reward repository
+func NewScheduledRewardRepository(db *pgxpool.Pool) ScheduledRewardRepository {
+	return &scheduledRewardRepository{db: db}
+}
+
+func (r *scheduledRewardRepository) Create(ctx context.Context, schedule *models.ScheduledReward) error {
+	query := `
+		INSERT INTO scheduled_rewards (
+			user_id, stock_symbol, quantity, amount_inr, event_type, notes, created_by,
+			frequency, start_at, occurrences, next_run_at, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, runs_completed, created_at, updated_at
+	`
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
+		schedule.UserID, schedule.StockSymbol, schedule.Quantity, schedule.AmountINR,
+		schedule.EventType, schedule.Notes, schedule.CreatedBy,
+		schedule.Frequency, schedule.StartAt, schedule.Occurrences, schedule.NextRunAt, schedule.Status,
+	).Scan(&schedule.ID, &schedule.RunsCompleted, &schedule.CreatedAt, &schedule.UpdatedAt)
+}
+
+func (r *scheduledRewardRepository) GetByID(ctx context.Context, id int) (*models.ScheduledReward, error) {
+	query := `
+		SELECT ` + scheduledRewardColumns + `
+		FROM scheduled_rewards
+		WHERE id = $1
+	`
+	schedule, err := r.scanScheduledReward(db.Conn(ctx, r.db).QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("scheduled reward not found: %w", err)
+	}
+	return schedule, nil
+}
+
+// List lists schedules, newest first; an empty userID or status matches all
+func (r *scheduledRewardRepository) List(ctx context.Context, userID, status string, limit, offset int) ([]*models.ScheduledReward, error) {
+	query := `
+		SELECT ` + scheduledRewardColumns + `
+		FROM scheduled_rewards
+		WHERE ($1 = '' OR user_id = $1)
+			AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanScheduledRewards(rows)
+}
+
+// ListDue lists up to limit active schedules whose next run is due by asOf,
+// most overdue first
+func (r *scheduledRewardRepository) ListDue(ctx context.Context, asOf time.Time, limit int) ([]*models.ScheduledReward, error) {
+	query := `
+		SELECT ` + scheduledRewardColumns + `
+		FROM scheduled_rewards
+		WHERE status = 'ACTIVE' AND next_run_at <= $1
+		ORDER BY next_run_at ASC, id ASC
+		LIMIT $2
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, asOf, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanScheduledRewards(rows)
+}
+
+// Advance stores a schedule's progress after a run. It only applies if no
+// other worker has advanced the schedule past the previous run, and returns
+// false otherwise.
+func (r *scheduledRewardRepository) Advance(ctx context.Context, schedule *models.ScheduledReward) (bool, error) {
+	query := `
+		UPDATE scheduled_rewards
+		SET runs_completed = $1, next_run_at = $2, status = $3, last_error = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $5 AND runs_completed = $1 - 1 AND status = 'ACTIVE'
+		RETURNING updated_at
+	`
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query,
+		schedule.RunsCompleted, schedule.NextRunAt, schedule.Status, schedule.LastError, schedule.ID,
+	).Scan(&schedule.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetLastError records why the schedule's next run could not be processed
+func (r *scheduledRewardRepository) SetLastError(ctx context.Context, id int, message string) error {
+	query := `UPDATE scheduled_rewards SET last_error = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, message, id)
+	return err
+}
+
+// Cancel stops an active schedule; it returns false if the schedule was not active
+func (r *scheduledRewardRepository) Cancel(ctx context.Context, id int) (bool, error) {
+	query := `
+		UPDATE scheduled_rewards
+		SET status = 'CANCELLED', next_run_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'ACTIVE'
+	`
+	tag, err := db.Conn(ctx, r.db).Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *scheduledRewardRepository) scanScheduledReward(row pgx.Row) (*models.ScheduledReward, error) {
+	schedule := &models.ScheduledReward{}
+	err := row.Scan(
+		&schedule.ID, &schedule.UserID, &schedule.StockSymbol, &schedule.Quantity, &schedule.AmountINR,
+		&schedule.EventType, &schedule.Notes, &schedule.CreatedBy,
+		&schedule.Frequency, &schedule.StartAt, &schedule.Occurrences, &schedule.RunsCompleted,
+		&schedule.NextRunAt, &schedule.Status, &schedule.LastError, &schedule.CreatedAt, &schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (r *scheduledRewardRepository) scanScheduledRewards(rows pgx.Rows) ([]*models.ScheduledReward, error) {
+	schedules := make([]*models.ScheduledReward, 0)
+	for rows.Next() {
+		schedule, err := r.scanScheduledReward(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
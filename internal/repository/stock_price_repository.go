@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"fmt"
+	"stockBackend/internal/db"
 	"stockBackend/internal/models"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -30,7 +32,7 @@ func (r *stockPriceRepository) Create(ctx context.Context, price *models.StockPr
 		timestamp = &ts
 	}
 	
-	return r.db.QueryRow(ctx, query,
+	return db.Conn(ctx, r.db).QueryRow(ctx, query,
 		price.StockSymbol, price.Price, price.Currency, price.Source, timestamp,
 	).Scan(&price.ID, &price.Timestamp, &price.CreatedAt)
 }
@@ -44,7 +46,7 @@ func (r *stockPriceRepository) GetLatest(ctx context.Context, stockSymbol string
 		LIMIT 1
 	`
 	price := &models.StockPrice{}
-	err := r.db.QueryRow(ctx, query, stockSymbol).Scan(
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, stockSymbol).Scan(
 		&price.ID, &price.StockSymbol, &price.Price, &price.Currency,
 		&price.Timestamp, &price.Source, &price.CreatedAt,
 	)
@@ -62,7 +64,7 @@ func (r *stockPriceRepository) GetLatestBatch(ctx context.Context, stockSymbols
 		WHERE stock_symbol = ANY($1)
 		ORDER BY stock_symbol, timestamp DESC
 	`
-	rows, err := r.db.Query(ctx, query, stockSymbols)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, stockSymbols)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +92,7 @@ func (r *stockPriceRepository) GetHistory(ctx context.Context, stockSymbol strin
 		ORDER BY timestamp DESC
 		LIMIT $2
 	`
-	rows, err := r.db.Query(ctx, query, stockSymbol, limit)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, stockSymbol, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -117,7 +119,7 @@ func (r *stockPriceRepository) GetByTimeRange(ctx context.Context, stockSymbol s
 		WHERE stock_symbol = $1 AND timestamp BETWEEN $2 AND $3
 		ORDER BY timestamp DESC
 	`
-	rows, err := r.db.Query(ctx, query, stockSymbol, start, end)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, stockSymbol, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -137,6 +139,65 @@ func (r *stockPriceRepository) GetByTimeRange(ctx context.Context, stockSymbol s
 	return prices, rows.Err()
 }
 
+// GetAtOrBefore returns the latest price recorded at or before the given time
+func (r *stockPriceRepository) GetAtOrBefore(ctx context.Context, stockSymbol string, at time.Time) (*models.StockPrice, error) {
+	query := `
+		SELECT id, stock_symbol, price, currency, timestamp, source, created_at
+		FROM stock_prices
+		WHERE stock_symbol = $1 AND timestamp <= $2
+		ORDER BY timestamp DESC
+		LIMIT 1
+	`
+	price := &models.StockPrice{}
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, stockSymbol, at).Scan(
+		&price.ID, &price.StockSymbol, &price.Price, &price.Currency,
+		&price.Timestamp, &price.Source, &price.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("stock price not found: %w", err)
+	}
+	return price, nil
+}
+
+// GetAtOrBeforeBatch looks up GetAtOrBefore for many (symbol, time) pairs in one
+// query. The result is aligned with the input; a pair without a price gets nil.
+func (r *stockPriceRepository) GetAtOrBeforeBatch(ctx context.Context, stockSymbols []string, ats []time.Time) ([]*models.StockPrice, error) {
+	if len(stockSymbols) != len(ats) {
+		return nil, fmt.Errorf("got %d symbols but %d timestamps", len(stockSymbols), len(ats))
+	}
+
+	query := `
+		SELECT q.ord, p.id, p.stock_symbol, p.price, p.currency, p.timestamp, p.source, p.created_at
+		FROM unnest($1::text[], $2::timestamptz[]) WITH ORDINALITY AS q(stock_symbol, at, ord)
+		JOIN LATERAL (
+			SELECT id, stock_symbol, price, currency, timestamp, source, created_at
+			FROM stock_prices
+			WHERE stock_symbol = q.stock_symbol AND timestamp <= q.at
+			ORDER BY timestamp DESC
+			LIMIT 1
+		) p ON true
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, stockSymbols, ats)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make([]*models.StockPrice, len(stockSymbols))
+	for rows.Next() {
+		var ord int
+		price := &models.StockPrice{}
+		if err := rows.Scan(
+			&ord, &price.ID, &price.StockSymbol, &price.Price, &price.Currency,
+			&price.Timestamp, &price.Source, &price.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		prices[ord-1] = price
+	}
+	return prices, rows.Err()
+}
+
 func (r *stockPriceRepository) BulkCreate(ctx context.Context, prices []*models.StockPrice) error {
 	if len(prices) == 0 {
 		return nil
@@ -157,7 +218,7 @@ func (r *stockPriceRepository) BulkCreate(ctx context.Context, prices []*models.
 		batch.Queue(query, price.StockSymbol, price.Price, price.Currency, price.Source, timestamp)
 	}
 
-	br := r.db.SendBatch(ctx, batch)
+	br := db.Conn(ctx, r.db).SendBatch(ctx, batch)
 	defer br.Close()
 
 	for range prices {
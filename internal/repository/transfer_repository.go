@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const transferColumns = `id, transfer_id, from_user_id, to_user_id, stock_symbol, quantity,
+			stock_price, stock_price_id, value_inr, note, out_reward_id, in_reward_id, created_at`
+
+type transferRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTransferRepository creates a new transfer repository
+func NewTransferRepository(db *pgxpool.Pool) TransferRepository {
+	return &transferRepository{db: db}
+}
+
+// Claim inserts a transfer unless its transfer_id is already taken. It returns
+// false when another transfer holds the ID; a concurrent claim waits for the
+// first to commit or roll back.
+func (r *transferRepository) Claim(ctx context.Context, transfer *models.Transfer) (bool, error) {
+	query := `
+		INSERT INTO transfers (transfer_id, from_user_id, to_user_id, stock_symbol, quantity, note)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (transfer_id) DO NOTHING
+		RETURNING id, created_at
+	`
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query,
+		transfer.TransferID, transfer.FromUserID, transfer.ToUserID,
+		transfer.StockSymbol, transfer.Quantity, transfer.Note,
+	).Scan(&transfer.ID, &transfer.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Complete stores the price a claimed transfer was booked at and its two rewards
+func (r *transferRepository) Complete(ctx context.Context, transfer *models.Transfer) error {
+	query := `
+		UPDATE transfers
+		SET stock_price = $1, stock_price_id = $2, value_inr = $3, out_reward_id = $4, in_reward_id = $5
+		WHERE id = $6
+	`
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query,
+		transfer.StockPrice, transfer.StockPriceID, transfer.ValueINR,
+		transfer.OutRewardID, transfer.InRewardID, transfer.ID,
+	)
+	return err
+}
+
+func (r *transferRepository) GetByTransferID(ctx context.Context, transferID string) (*models.Transfer, error) {
+	query := `
+		SELECT ` + transferColumns + `
+		FROM transfers
+		WHERE transfer_id = $1
+	`
+	transfer, err := r.scanTransfer(db.Conn(ctx, r.db).QueryRow(ctx, query, transferID))
+	if err != nil {
+		return nil, fmt.Errorf("transfer not found: %w", err)
+	}
+	return transfer, nil
+}
+
+// GetByUserID lists the transfers a user sent or received, newest first
+func (r *transferRepository) GetByUserID(ctx context.Context, userID string, limit, offset int) ([]*models.Transfer, error) {
+	query := `
+		SELECT ` + transferColumns + `
+		FROM transfers
+		WHERE from_user_id = $1 OR to_user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transfers := make([]*models.Transfer, 0)
+	for rows.Next() {
+		transfer, err := r.scanTransfer(rows)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, rows.Err()
+}
+
+// GetSentQuantitySince sums the quantity of a symbol the user has sent in
+// completed transfers since the given time
+func (r *transferRepository) GetSentQuantitySince(ctx context.Context, userID, stockSymbol string, since time.Time) (decimal.Decimal, error) {
+	query := `
+		SELECT COALESCE(SUM(quantity), 0)
+		FROM transfers
+		WHERE from_user_id = $1
+			AND stock_symbol = $2
+			AND out_reward_id IS NOT NULL
+			AND created_at >= $3
+	`
+	var quantity decimal.Decimal
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID, stockSymbol, since).Scan(&quantity)
+	return quantity, err
+}
+
+func (r *transferRepository) scanTransfer(row pgx.Row) (*models.Transfer, error) {
+	transfer := &models.Transfer{}
+	err := row.Scan(
+		&transfer.ID, &transfer.TransferID, &transfer.FromUserID, &transfer.ToUserID,
+		&transfer.StockSymbol, &transfer.Quantity, &transfer.StockPrice, &transfer.StockPriceID,
+		&transfer.ValueINR, &transfer.Note, &transfer.OutRewardID, &transfer.InRewardID, &transfer.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
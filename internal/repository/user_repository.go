@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"stockBackend/internal/db"
 	"stockBackend/internal/models"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -23,7 +24,7 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 		VALUES ($1, $2, $3)
 		RETURNING id, created_at, updated_at
 	`
-	return r.db.QueryRow(ctx, query, user.UserID, user.Name, user.Email).
+	return db.Conn(ctx, r.db).QueryRow(ctx, query, user.UserID, user.Name, user.Email).
 		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 }
 
@@ -34,7 +35,7 @@ func (r *userRepository) GetByUserID(ctx context.Context, userID string) (*model
 		WHERE user_id = $1
 	`
 	user := &models.User{}
-	err := r.db.QueryRow(ctx, query, userID).Scan(
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID).Scan(
 		&user.ID, &user.UserID, &user.Name, &user.Email,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
@@ -51,7 +52,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 		WHERE email = $1
 	`
 	user := &models.User{}
-	err := r.db.QueryRow(ctx, query, email).Scan(
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, email).Scan(
 		&user.ID, &user.UserID, &user.Name, &user.Email,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
@@ -68,7 +69,7 @@ func (r *userRepository) GetByID(ctx context.Context, id int) (*models.User, err
 		WHERE id = $1
 	`
 	user := &models.User{}
-	err := r.db.QueryRow(ctx, query, id).Scan(
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, id).Scan(
 		&user.ID, &user.UserID, &user.Name, &user.Email,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
@@ -85,13 +86,13 @@ func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 		WHERE user_id = $3
 		RETURNING updated_at
 	`
-	return r.db.QueryRow(ctx, query, user.Name, user.Email, user.UserID).
+	return db.Conn(ctx, r.db).QueryRow(ctx, query, user.Name, user.Email, user.UserID).
 		Scan(&user.UpdatedAt)
 }
 
 func (r *userRepository) Delete(ctx context.Context, userID string) error {
 	query := `DELETE FROM users WHERE user_id = $1`
-	_, err := r.db.Exec(ctx, query, userID)
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, userID)
 	return err
 }
 
@@ -102,7 +103,7 @@ func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*models
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`
-	rows, err := r.db.Query(ctx, query, limit, offset)
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -125,6 +126,26 @@ func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*models
 func (r *userRepository) Exists(ctx context.Context, userID string) (bool, error) {
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)`
 	var exists bool
-	err := r.db.QueryRow(ctx, query, userID).Scan(&exists)
+	err := db.Conn(ctx, r.db).QueryRow(ctx, query, userID).Scan(&exists)
 	return exists, err
 }
+
+// ExistsBatch reports which of the given user IDs exist
+func (r *userRepository) ExistsBatch(ctx context.Context, userIDs []string) (map[string]bool, error) {
+	query := `SELECT user_id FROM users WHERE user_id = ANY($1)`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exists := make(map[string]bool, len(userIDs))
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		exists[userID] = true
+	}
+	return exists, rows.Err()
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const vestingTrancheColumns = `t.id, t.reward_id, t.user_id, t.stock_symbol, t.quantity, t.value_inr,
+			t.vest_date, t.status, t.vested_at, t.created_at`
+
+// settledTrancheFilter keeps tranches of rewards that count in the portfolio
+const settledTrancheFilter = "r.status IN ('COMPLETED', 'APPROVED') AND r.reversal_of IS NULL"
+
+type vestingRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewVestingRepository creates a new vesting repository
+func NewVestingRepository(db *pgxpool.Pool) VestingRepository {
+	return &vestingRepository{db: db}
+}
+
+// BulkCreate inserts vesting tranches in one round trip
+func (r *vestingRepository) BulkCreate(ctx context.Context, tranches []*models.VestingTranche) error {
+	if len(tranches) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO reward_vesting_tranches (
+			reward_id, user_id, stock_symbol, quantity, value_inr, vest_date, status
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	batch := &pgx.Batch{}
+	for _, tranche := range tranches {
+		batch.Queue(query,
+			tranche.RewardID, tranche.UserID, tranche.StockSymbol,
+			tranche.Quantity, tranche.ValueINR, tranche.VestDate, tranche.Status,
+		)
+	}
+
+	br := db.Conn(ctx, r.db).SendBatch(ctx, batch)
+	defer br.Close()
+
+	for _, tranche := range tranches {
+		if err := br.QueryRow().Scan(&tranche.ID, &tranche.CreatedAt); err != nil {
+			return fmt.Errorf("failed to create vesting tranche for reward %d: %w", tranche.RewardID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *vestingRepository) GetByRewardID(ctx context.Context, rewardID int) ([]*models.VestingTranche, error) {
+	query := `
+		SELECT ` + vestingTrancheColumns + `
+		FROM reward_vesting_tranches t
+		WHERE t.reward_id = $1
+		ORDER BY t.vest_date ASC
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, rewardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTranches(rows)
+}
+
+// LockDue locks up to limit unvested tranches due by asOf whose reward is
+// settled. Tranches locked by another run are skipped.
+func (r *vestingRepository) LockDue(ctx context.Context, asOf time.Time, limit int) ([]*models.VestingTranche, error) {
+	query := `
+		SELECT ` + vestingTrancheColumns + `
+		FROM reward_vesting_tranches t
+		JOIN rewards r ON r.id = t.reward_id
+		WHERE t.status = 'UNVESTED'
+			AND t.vest_date <= $1
+			AND ` + settledTrancheFilter + `
+		ORDER BY t.vest_date ASC, t.id ASC
+		LIMIT $2
+		FOR UPDATE OF t SKIP LOCKED
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, asOf, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTranches(rows)
+}
+
+// MarkVested moves tranches to VESTED
+func (r *vestingRepository) MarkVested(ctx context.Context, ids []int, vestedAt time.Time) error {
+	query := `
+		UPDATE reward_vesting_tranches
+		SET status = 'VESTED', vested_at = $1
+		WHERE id = ANY($2) AND status = 'UNVESTED'
+	`
+	_, err := db.Conn(ctx, r.db).Exec(ctx, query, vestedAt, ids)
+	return err
+}
+
+// CancelByRewardID cancels a reward's tranches that have not vested yet
+func (r *vestingRepository) CancelByRewardID(ctx context.Context, rewardID int) ([]*models.VestingTranche, error) {
+	query := `
+		UPDATE reward_vesting_tranches t
+		SET status = 'CANCELLED'
+		WHERE t.reward_id = $1 AND t.status = 'UNVESTED'
+		RETURNING ` + vestingTrancheColumns + `
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, rewardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanTranches(rows)
+}
+
+// GetUnvestedQuantities returns a user's unvested quantity per symbol,
+// counting the same rewards as v_user_portfolio
+func (r *vestingRepository) GetUnvestedQuantities(ctx context.Context, userID string) (map[string]decimal.Decimal, error) {
+	query := `
+		SELECT t.stock_symbol, SUM(t.quantity)
+		FROM reward_vesting_tranches t
+		JOIN rewards r ON r.id = t.reward_id
+		WHERE t.user_id = $1
+			AND t.status = 'UNVESTED'
+			AND ` + settledTrancheFilter + `
+		GROUP BY t.stock_symbol
+	`
+	rows, err := db.Conn(ctx, r.db).Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unvested := make(map[string]decimal.Decimal)
+	for rows.Next() {
+		var symbol string
+		var quantity decimal.Decimal
+		if err := rows.Scan(&symbol, &quantity); err != nil {
+			return nil, err
+		}
+		unvested[symbol] = quantity
+	}
+	return unvested, rows.Err()
+}
+
+func (r *vestingRepository) scanTranches(rows pgx.Rows) ([]*models.VestingTranche, error) {
+	tranches := make([]*models.VestingTranche, 0)
+	for rows.Next() {
+		tranche := &models.VestingTranche{}
+		if err := rows.Scan(
+			&tranche.ID, &tranche.RewardID, &tranche.UserID, &tranche.StockSymbol,
+			&tranche.Quantity, &tranche.ValueINR, &tranche.VestDate, &tranche.Status,
+			&tranche.VestedAt, &tranche.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tranches = append(tranches, tranche)
+	}
+	return tranches, rows.Err()
+}
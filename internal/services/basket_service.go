@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBasketLegs caps a basket so one event books a bounded number of rewards
+const maxBasketLegs = 20
+
+var (
+	// ErrBasketNotFound is returned when no basket exists for an ID
+	ErrBasketNotFound = newError(KindNotFound, "BASKET_NOT_FOUND", "basket not found")
+	// ErrBasketInactive is returned when a reward names a deactivated basket
+	ErrBasketInactive = newError(KindConflict, "BASKET_INACTIVE", "basket is not active").
+				withStatus(http.StatusUnprocessableEntity)
+	// ErrInvalidBasket is returned when a basket's legs or weights are invalid
+	ErrInvalidBasket = newError(KindValidation, "INVALID_BASKET", "invalid basket")
+)
+
+// BasketService handles stock basket definitions
+type BasketService struct {
+	basketRepo repository.BasketRepository
+	log        *logrus.Logger
+}
+
+// BasketRequest represents a basket to create
+type BasketRequest struct {
+	Name        string             `json:"name" binding:"required"`
+	Description string             `json:"description"`
+	Legs        []models.BasketLeg `json:"legs" binding:"required"`
+}
+
+// NewBasketService creates a new basket service
+func NewBasketService(basketRepo repository.BasketRepository, log *logrus.Logger) *BasketService {
+	return &BasketService{
+		basketRepo: basketRepo,
+		log:        log,
+	}
+}
+
+// CreateBasket validates and stores a new basket
+func (bs *BasketService) CreateBasket(ctx context.Context, req *BasketRequest) (*models.Basket, error) {
+	basket := &models.Basket{
+		Name:   req.Name,
+		Legs:   req.Legs,
+		Active: true,
+	}
+	if req.Description != "" {
+		basket.Description = &req.Description
+	}
+	for i := range basket.Legs {
+		basket.Legs[i].StockSymbol = strings.ToUpper(strings.TrimSpace(basket.Legs[i].StockSymbol))
+	}
+
+	if err := validateBasket(basket); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBasket, err)
+	}
+
+	if err := bs.basketRepo.Create(ctx, basket); err != nil {
+		return nil, fmt.Errorf("failed to create basket: %w", err)
+	}
+
+	bs.log.Infof("Created basket %d (%s) with %d legs", basket.ID, basket.Name, len(basket.Legs))
+	return basket, nil
+}
+
+// GetBasket retrieves a basket by ID
+func (bs *BasketService) GetBasket(ctx context.Context, id int) (*models.Basket, error) {
+	basket, err := bs.basketRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBasketNotFound
+		}
+		return nil, err
+	}
+	return basket, nil
+}
+
+// ListBaskets lists baskets, newest first
+func (bs *BasketService) ListBaskets(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.Basket, error) {
+	return bs.basketRepo.List(ctx, activeOnly, limit, offset)
+}
+
+// DeactivateBasket stops a basket from being rewarded; rewards already booked are kept
+func (bs *BasketService) DeactivateBasket(ctx context.Context, id int) (*models.Basket, error) {
+	basket, err := bs.basketRepo.SetActive(ctx, id, false)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBasketNotFound
+		}
+		return nil, fmt.Errorf("failed to deactivate basket: %w", err)
+	}
+
+	bs.log.Infof("Deactivated basket %d", basket.ID)
+	return basket, nil
+}
+
+// validateBasket checks that a basket has distinct symbols with positive weights
+func validateBasket(basket *models.Basket) error {
+	if basket.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(basket.Legs) == 0 {
+		return fmt.Errorf("at least one leg is required")
+	}
+	if len(basket.Legs) > maxBasketLegs {
+		return fmt.Errorf("a basket allows at most %d legs", maxBasketLegs)
+	}
+
+	seen := make(map[string]bool, len(basket.Legs))
+	for _, leg := range basket.Legs {
+		if leg.StockSymbol == "" {
+			return fmt.Errorf("leg stock_symbol must not be empty")
+		}
+		if seen[leg.StockSymbol] {
+			return fmt.Errorf("%s appears in more than one leg", leg.StockSymbol)
+		}
+		seen[leg.StockSymbol] = true
+		if !leg.Weight.IsPositive() {
+			return fmt.Errorf("weight of %s must be positive", leg.StockSymbol)
+		}
+	}
+	return nil
+}
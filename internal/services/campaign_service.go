@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrCampaignNotFound is returned when no campaign exists for an ID
+	ErrCampaignNotFound = newError(KindNotFound, "CAMPAIGN_NOT_FOUND", "campaign not found")
+	// ErrCampaignInactive is returned when a campaign is disabled or outside its dates
+	ErrCampaignInactive = newError(KindConflict, "CAMPAIGN_INACTIVE", "campaign is not active").
+				withStatus(http.StatusUnprocessableEntity)
+	// ErrCampaignBudgetExhausted is returned when a reward would take a campaign over budget
+	ErrCampaignBudgetExhausted = newError(KindConflict, "CAMPAIGN_BUDGET_EXHAUSTED", "campaign budget exhausted")
+	// ErrCampaignUserCapReached is returned when a reward would take a user over the campaign's per-user cap
+	ErrCampaignUserCapReached = newError(KindConflict, "CAMPAIGN_USER_CAP_REACHED", "campaign per-user cap reached")
+	// ErrInvalidCampaign is returned when a campaign's rule, dates or budget are invalid
+	ErrInvalidCampaign = newError(KindValidation, "INVALID_CAMPAIGN", "invalid campaign")
+)
+
+// CampaignService handles reward campaign operations
+type CampaignService struct {
+	campaignRepo  repository.CampaignRepository
+	eventTypeRepo repository.EventTypeRepository
+	log           *logrus.Logger
+}
+
+// CampaignRequest represents a campaign to create
+type CampaignRequest struct {
+	Name          string           `json:"name" binding:"required"`
+	EventType     string           `json:"event_type"`
+	RuleType      string           `json:"rule_type" binding:"required"`
+	StockSymbol   string           `json:"stock_symbol"`
+	Basket        []string         `json:"basket"`
+	Quantity      decimal.Decimal  `json:"quantity"`
+	AmountINR     decimal.Decimal  `json:"amount_inr"`
+	StartsAt      time.Time        `json:"starts_at"`
+	EndsAt        *time.Time       `json:"ends_at"`
+	BudgetINR     decimal.Decimal  `json:"budget_inr"`
+	PerUserCapINR *decimal.Decimal `json:"per_user_cap_inr"`
+}
+
+// CampaignUpdateRequest represents changes to a campaign; the reward rule cannot change
+type CampaignUpdateRequest struct {
+	Name          *string          `json:"name"`
+	StartsAt      *time.Time       `json:"starts_at"`
+	EndsAt        *time.Time       `json:"ends_at"`
+	BudgetINR     *decimal.Decimal `json:"budget_inr"`
+	PerUserCapINR *decimal.Decimal `json:"per_user_cap_inr"`
+	Active        *bool            `json:"active"`
+}
+
+// NewCampaignService creates a new campaign service
+func NewCampaignService(
+	campaignRepo repository.CampaignRepository,
+	eventTypeRepo repository.EventTypeRepository,
+	log *logrus.Logger,
+) *CampaignService {
+	return &CampaignService{
+		campaignRepo:  campaignRepo,
+		eventTypeRepo: eventTypeRepo,
+		log:           log,
+	}
+}
+
+// CreateCampaign validates and stores a new campaign
+func (cs *CampaignService) CreateCampaign(ctx context.Context, req *CampaignRequest) (*models.Campaign, error) {
+	campaign := &models.Campaign{
+		Name:          req.Name,
+		EventType:     req.EventType,
+		RuleType:      req.RuleType,
+		Basket:        req.Basket,
+		StartsAt:      req.StartsAt,
+		EndsAt:        req.EndsAt,
+		BudgetINR:     req.BudgetINR,
+		PerUserCapINR: req.PerUserCapINR,
+		Active:        true,
+	}
+	if campaign.EventType == "" {
+		campaign.EventType = "REWARD"
+	}
+	if campaign.StartsAt.IsZero() {
+		campaign.StartsAt = time.Now()
+	}
+	if req.StockSymbol != "" {
+		symbol := strings.ToUpper(req.StockSymbol)
+		campaign.StockSymbol = &symbol
+	}
+	for i, symbol := range campaign.Basket {
+		campaign.Basket[i] = strings.ToUpper(symbol)
+	}
+	if !req.Quantity.IsZero() {
+		campaign.Quantity = &req.Quantity
+	}
+	if !req.AmountINR.IsZero() {
+		campaign.AmountINR = &req.AmountINR
+	}
+
+	if err := validateCampaign(campaign); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCampaign, err)
+	}
+	if _, err := activeEventType(ctx, cs.eventTypeRepo, campaign.EventType); err != nil {
+		return nil, err
+	}
+
+	if err := cs.campaignRepo.Create(ctx, campaign); err != nil {
+		return nil, fmt.Errorf("failed to create campaign: %w", err)
+	}
+
+	cs.log.Infof("Created campaign %d (%s)", campaign.ID, campaign.Name)
+	return campaign, nil
+}
+
+// GetCampaign retrieves a campaign by ID
+func (cs *CampaignService) GetCampaign(ctx context.Context, id int) (*models.Campaign, error) {
+	campaign, err := cs.campaignRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// ListCampaigns lists campaigns, newest first
+func (cs *CampaignService) ListCampaigns(ctx context.Context, activeOnly bool, limit, offset int) ([]*models.Campaign, error) {
+	return cs.campaignRepo.List(ctx, activeOnly, limit, offset)
+}
+
+// UpdateCampaign applies changes to a campaign's name, dates, budget, cap or active flag
+func (cs *CampaignService) UpdateCampaign(ctx context.Context, id int, req *CampaignUpdateRequest) (*models.Campaign, error) {
+	campaign, err := cs.GetCampaign(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		campaign.Name = *req.Name
+	}
+	if req.StartsAt != nil {
+		campaign.StartsAt = *req.StartsAt
+	}
+	if req.EndsAt != nil {
+		campaign.EndsAt = req.EndsAt
+	}
+	if req.BudgetINR != nil {
+		campaign.BudgetINR = *req.BudgetINR
+	}
+	if req.PerUserCapINR != nil {
+		campaign.PerUserCapINR = req.PerUserCapINR
+	}
+	if req.Active != nil {
+		campaign.Active = *req.Active
+	}
+
+	if err := validateCampaign(campaign); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCampaign, err)
+	}
+	if campaign.BudgetINR.LessThan(campaign.SpentINR) {
+		return nil, fmt.Errorf("%w: budget_inr %s is below the %s INR already spent",
+			ErrInvalidCampaign, campaign.BudgetINR, campaign.SpentINR)
+	}
+
+	if err := cs.campaignRepo.Update(ctx, campaign); err != nil {
+		return nil, fmt.Errorf("failed to update campaign: %w", err)
+	}
+
+	cs.log.Infof("Updated campaign %d", campaign.ID)
+	return campaign, nil
+}
+
+// DeactivateCampaign stops a campaign from granting rewards; its history is kept
+func (cs *CampaignService) DeactivateCampaign(ctx context.Context, id int) (*models.Campaign, error) {
+	active := false
+	return cs.UpdateCampaign(ctx, id, &CampaignUpdateRequest{Active: &active})
+}
+
+// validateCampaign checks that a campaign's rule is complete and consistent
+func validateCampaign(campaign *models.Campaign) error {
+	if campaign.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !campaign.BudgetINR.IsPositive() {
+		return fmt.Errorf("budget_inr must be positive")
+	}
+	if err := normalizePlaces("budget_inr", &campaign.BudgetINR, models.AmountScale); err != nil {
+		return err
+	}
+	if campaign.PerUserCapINR != nil {
+		if !campaign.PerUserCapINR.IsPositive() {
+			return fmt.Errorf("per_user_cap_inr must be positive")
+		}
+		if err := normalizePlaces("per_user_cap_inr", campaign.PerUserCapINR, models.AmountScale); err != nil {
+			return err
+		}
+	}
+	if campaign.EndsAt != nil && !campaign.EndsAt.After(campaign.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	if campaign.Quantity != nil {
+		if !campaign.Quantity.IsPositive() {
+			return fmt.Errorf("quantity must be positive")
+		}
+		if err := normalizePlaces("quantity", campaign.Quantity, models.QuantityScale); err != nil {
+			return err
+		}
+	}
+	if campaign.AmountINR != nil {
+		if !campaign.AmountINR.IsPositive() {
+			return fmt.Errorf("amount_inr must be positive")
+		}
+		if err := normalizePlaces("amount_inr", campaign.AmountINR, models.AmountScale); err != nil {
+			return err
+		}
+	}
+
+	switch campaign.RuleType {
+	case models.CampaignRuleFixedQuantity:
+		if campaign.StockSymbol == nil || campaign.Quantity == nil {
+			return fmt.Errorf("%s requires stock_symbol and quantity", campaign.RuleType)
+		}
+		if campaign.AmountINR != nil || len(campaign.Basket) > 0 {
+			return fmt.Errorf("%s takes only stock_symbol and quantity", campaign.RuleType)
+		}
+	case models.CampaignRuleFixedINR:
+		if campaign.StockSymbol == nil || campaign.AmountINR == nil {
+			return fmt.Errorf("%s requires stock_symbol and amount_inr", campaign.RuleType)
+		}
+		if campaign.Quantity != nil || len(campaign.Basket) > 0 {
+			return fmt.Errorf("%s takes only stock_symbol and amount_inr", campaign.RuleType)
+		}
+	case models.CampaignRuleRandomBasket:
+		if len(campaign.Basket) == 0 {
+			return fmt.Errorf("%s requires a non-empty basket", campaign.RuleType)
+		}
+		if campaign.StockSymbol != nil {
+			return fmt.Errorf("%s takes a basket, not stock_symbol", campaign.RuleType)
+		}
+		if (campaign.Quantity == nil) == (campaign.AmountINR == nil) {
+			return fmt.Errorf("%s requires exactly one of quantity or amount_inr", campaign.RuleType)
+		}
+		for _, symbol := range campaign.Basket {
+			if symbol == "" {
+				return fmt.Errorf("basket symbols must not be empty")
+			}
+		}
+	default:
+		return fmt.Errorf("rule_type must be one of %s, %s, %s",
+			models.CampaignRuleFixedQuantity, models.CampaignRuleFixedINR, models.CampaignRuleRandomBasket)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"testing"
+	"time"
+)
+
+func TestValidateCampaign(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := start.Add(-time.Hour)
+	dec := func(s string) *decimal.Decimal {
+		d := decimal.RequireFromString(s)
+		return &d
+	}
+	str := func(s string) *string { return &s }
+	// campaign is a valid fixed-quantity campaign changed by edit
+	campaign := func(edit func(c *models.Campaign)) *models.Campaign {
+		c := &models.Campaign{
+			Name:        "Diwali",
+			EventType:   "REFERRAL",
+			RuleType:    models.CampaignRuleFixedQuantity,
+			StockSymbol: str("TCS"),
+			Quantity:    dec("1"),
+			StartsAt:    start,
+			BudgetINR:   decimal.RequireFromString("10000"),
+		}
+		edit(c)
+		return c
+	}
+
+	valid := []struct {
+		name     string
+		campaign *models.Campaign
+	}{
+		{"fixed quantity", campaign(func(c *models.Campaign) {})},
+		{"fixed INR", campaign(func(c *models.Campaign) {
+			c.RuleType, c.Quantity, c.AmountINR = models.CampaignRuleFixedINR, nil, dec("500")
+		})},
+		{"basket by quantity", campaign(func(c *models.Campaign) {
+			c.RuleType, c.StockSymbol, c.Basket = models.CampaignRuleRandomBasket, nil, []string{"TCS", "INFY"}
+		})},
+		{"basket by amount", campaign(func(c *models.Campaign) {
+			c.RuleType, c.StockSymbol, c.Quantity, c.AmountINR = models.CampaignRuleRandomBasket, nil, nil, dec("250.5")
+			c.Basket = []string{"TCS"}
+		})},
+		{"capped and ending", campaign(func(c *models.Campaign) {
+			end := start.Add(time.Hour)
+			c.PerUserCapINR, c.EndsAt = dec("1000"), &end
+		})},
+	}
+	for _, tt := range valid {
+		if err := validateCampaign(tt.campaign); err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+
+	// Valid amounts are padded to their column's places
+	c := campaign(func(c *models.Campaign) { c.PerUserCapINR = dec("1000.5") })
+	if err := validateCampaign(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.BudgetINR.String() != "10000.00" || c.PerUserCapINR.String() != "1000.50" || c.Quantity.String() != "1.000000" {
+		t.Errorf("normalized to budget %s, cap %s, quantity %s", c.BudgetINR, c.PerUserCapINR, c.Quantity)
+	}
+
+	invalid := []struct {
+		name     string
+		campaign *models.Campaign
+	}{
+		{"no name", campaign(func(c *models.Campaign) { c.Name = "" })},
+		{"no budget", campaign(func(c *models.Campaign) { c.BudgetINR = decimal.Zero })},
+		{"budget below paise", campaign(func(c *models.Campaign) { c.BudgetINR = decimal.RequireFromString("100.001") })},
+		{"negative cap", campaign(func(c *models.Campaign) { c.PerUserCapINR = dec("-1") })},
+		{"ends before it starts", campaign(func(c *models.Campaign) { c.EndsAt = &before })},
+		{"ends as it starts", campaign(func(c *models.Campaign) { c.EndsAt = &start })},
+		{"zero quantity", campaign(func(c *models.Campaign) { c.Quantity = dec("0") })},
+		{"quantity too precise", campaign(func(c *models.Campaign) { c.Quantity = dec("0.0000001") })},
+		{"negative amount", campaign(func(c *models.Campaign) {
+			c.RuleType, c.Quantity, c.AmountINR = models.CampaignRuleFixedINR, nil, dec("-5")
+		})},
+		{"fixed quantity without symbol", campaign(func(c *models.Campaign) { c.StockSymbol = nil })},
+		{"fixed quantity with amount", campaign(func(c *models.Campaign) { c.AmountINR = dec("500") })},
+		{"fixed quantity with basket", campaign(func(c *models.Campaign) { c.Basket = []string{"INFY"} })},
+		{"fixed INR without amount", campaign(func(c *models.Campaign) { c.RuleType = models.CampaignRuleFixedINR })},
+		{"empty basket", campaign(func(c *models.Campaign) {
+			c.RuleType, c.StockSymbol = models.CampaignRuleRandomBasket, nil
+		})},
+		{"basket with symbol", campaign(func(c *models.Campaign) {
+			c.RuleType, c.Basket = models.CampaignRuleRandomBasket, []string{"TCS"}
+		})},
+		{"basket with both", campaign(func(c *models.Campaign) {
+			c.RuleType, c.StockSymbol, c.AmountINR = models.CampaignRuleRandomBasket, nil, dec("500")
+			c.Basket = []string{"TCS"}
+		})},
+		{"basket with neither", campaign(func(c *models.Campaign) {
+			c.RuleType, c.StockSymbol, c.Quantity = models.CampaignRuleRandomBasket, nil, nil
+			c.Basket = []string{"TCS"}
+		})},
+		{"blank basket symbol", campaign(func(c *models.Campaign) {
+			c.RuleType, c.StockSymbol, c.Basket = models.CampaignRuleRandomBasket, nil, []string{"TCS", ""}
+		})},
+		{"unknown rule", campaign(func(c *models.Campaign) { c.RuleType = "LOTTERY" })},
+	}
+	for _, tt := range invalid {
+		if err := validateCampaign(tt.campaign); err == nil {
+			t.Errorf("%s: validateCampaign should fail", tt.name)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"os"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+)
+
+// Trade sides a charges plan tells apart; stamp duty is only due on purchases
+const (
+	TradeSideBuy  = "BUY"
+	TradeSideSell = "SELL"
+)
+
+// Charge codes itemized on rewards
+const (
+	ChargeBrokerage      = "BROKERAGE"
+	ChargeTransactionFee = "TRANSACTION_FEE"
+	ChargeSTT            = "STT"
+	ChargeExchange       = "EXCHANGE_CHARGE"
+	ChargeSEBIFee        = "SEBI_FEE"
+	ChargeStampDuty      = "STAMP_DUTY"
+	ChargeGST            = "GST"
+)
+
+// Ledger accounts charges are expensed to, one per charge
+const (
+	AccountBrokerageExpense = "BROKERAGE_EXPENSE"
+	AccountFeeExpense       = "FEE_EXPENSE"
+	AccountSTTExpense       = "STT_EXPENSE"
+	AccountExchangeExpense  = "EXCHANGE_CHARGE_EXPENSE"
+	AccountSEBIFeeExpense   = "SEBI_FEE_EXPENSE"
+	AccountStampDutyExpense = "STAMP_DUTY_EXPENSE"
+	AccountGSTExpense       = "GST_EXPENSE"
+)
+
+// chargeAccounts is the account each known charge is posted to unless a fee
+// plan names another
+var chargeAccounts = map[string]string{
+	ChargeBrokerage:      AccountBrokerageExpense,
+	ChargeTransactionFee: AccountFeeExpense,
+	ChargeSTT:            AccountSTTExpense,
+	ChargeExchange:       AccountExchangeExpense,
+	ChargeSEBIFee:        AccountSEBIFeeExpense,
+	ChargeStampDuty:      AccountStampDutyExpense,
+	ChargeGST:            AccountGSTExpense,
+}
+
+// Charges plans selected by CHARGES_PLAN
+const (
+	ChargesPlanPercent     = "percent"
+	ChargesPlanIndiaEquity = "india_equity"
+)
+
+// chargeDescriptions names each charge in its ledger entries
+var chargeDescriptions = map[string]string{
+	ChargeBrokerage:      "Brokerage fee",
+	ChargeTransactionFee: "Transaction fee",
+	ChargeSTT:            "Securities transaction tax",
+	ChargeExchange:       "Exchange transaction charge",
+	ChargeSEBIFee:        "SEBI turnover fee",
+	ChargeStampDuty:      "Stamp duty",
+	ChargeGST:            "GST",
+}
+
+// ChargesCalculator itemizes the charges on buying or selling stock. Rewards
+// and redemptions store what it returns and post each charge to its account.
+type ChargesCalculator interface {
+	// Charges itemizes the charges on a trade worth value, each rounded
+	// half-up to paise; charges that come to zero are left out
+	Charges(side string, value decimal.Decimal) []*models.RewardCharge
+	// Percent is the charges' combined percent of value, used to size a
+	// purchase whose charges are paid out of an INR amount
+	Percent(side string) decimal.Decimal
+}
+
+// percentCharges is the default plan: brokerage and one transaction fee,
+// each a percent of the trade value
+type percentCharges struct {
+	brokeragePercent decimal.Decimal
+	feePercent       decimal.Decimal
+}
+
+func (p *percentCharges) Charges(side string, value decimal.Decimal) []*models.RewardCharge {
+	return nonZeroCharges(
+		&models.RewardCharge{Code: ChargeBrokerage, Account: AccountBrokerageExpense, Amount: feeFor(value, p.brokeragePercent)},
+		&models.RewardCharge{Code: ChargeTransactionFee, Account: AccountFeeExpense, Amount: feeFor(value, p.feePercent)},
+	)
+}
+
+func (p *percentCharges) Percent(side string) decimal.Decimal {
+	return p.brokeragePercent.Add(p.feePercent)
+}
+
+// indiaEquityCharges itemizes a delivery trade on an Indian exchange:
+// brokerage, STT on both sides, the exchange transaction charge, the SEBI
+// turnover fee, stamp duty on purchases, and GST on brokerage plus the
+// exchange charge
+type indiaEquityCharges struct {
+	brokeragePercent decimal.Decimal
+	sttPercent       decimal.Decimal
+	exchangePercent  decimal.Decimal
+	sebiPercent      decimal.Decimal
+	stampDutyPercent decimal.Decimal
+	gstPercent       decimal.Decimal
+}
+
+func (c *indiaEquityCharges) Charges(side string, value decimal.Decimal) []*models.RewardCharge {
+	brokerage := feeFor(value, c.brokeragePercent)
+	exchange := feeFor(value, c.exchangePercent)
+	stampDuty := decimal.Zero
+	if side == TradeSideBuy {
+		stampDuty = feeFor(value, c.stampDutyPercent)
+	}
+	// GST is levied on the rounded charges, as on a contract note
+	gst := feeFor(brokerage.Add(exchange), c.gstPercent)
+
+	return nonZeroCharges(
+		&models.RewardCharge{Code: ChargeBrokerage, Account: AccountBrokerageExpense, Amount: brokerage},
+		&models.RewardCharge{Code: ChargeSTT, Account: AccountSTTExpense, Amount: feeFor(value, c.sttPercent)},
+		&models.RewardCharge{Code: ChargeExchange, Account: AccountExchangeExpense, Amount: exchange},
+		&models.RewardCharge{Code: ChargeSEBIFee, Account: AccountSEBIFeeExpense, Amount: feeFor(value, c.sebiPercent)},
+		&models.RewardCharge{Code: ChargeStampDuty, Account: AccountStampDutyExpense, Amount: stampDuty},
+		&models.RewardCharge{Code: ChargeGST, Account: AccountGSTExpense, Amount: gst},
+	)
+}
+
+func (c *indiaEquityCharges) Percent(side string) decimal.Decimal {
+	taxed := c.brokeragePercent.Add(c.exchangePercent)
+	percent := taxed.Add(c.sttPercent).Add(c.sebiPercent).Add(percentOf(taxed, c.gstPercent))
+	if side == TradeSideBuy {
+		percent = percent.Add(c.stampDutyPercent)
+	}
+	return percent
+}
+
+// feePlanCharges computes the charges of a fee plan stored in the database
+type feePlanCharges struct {
+	charges []models.FeePlanCharge
+}
+
+func (f *feePlanCharges) Charges(side string, value decimal.Decimal) []*models.RewardCharge {
+	amounts := make(map[string]decimal.Decimal, len(f.charges))
+	charges := make([]*models.RewardCharge, 0, len(f.charges))
+	for _, charge := range f.charges {
+		if charge.Side != "" && charge.Side != side {
+			continue
+		}
+		base := value.Abs()
+		if len(charge.LeviedOn) > 0 {
+			base = decimal.Zero
+			for _, code := range charge.LeviedOn {
+				base = base.Add(amounts[code])
+			}
+		}
+		amount := percentOf(base, charge.Percent).Add(charge.FlatINR)
+		if charge.MinINR != nil {
+			amount = decimal.Max(amount, *charge.MinINR)
+		}
+		if charge.MaxINR != nil {
+			amount = decimal.Min(amount, *charge.MaxINR)
+		}
+		amount = roundINR(amount)
+
+		amounts[charge.Code] = amount
+		charges = append(charges, &models.RewardCharge{Code: charge.Code, Account: charge.Account, Amount: amount})
+	}
+	return nonZeroCharges(charges...)
+}
+
+// Percent leaves out flat amounts and caps; quantityForAmount corrects for them
+func (f *feePlanCharges) Percent(side string) decimal.Decimal {
+	percents := make(map[string]decimal.Decimal, len(f.charges))
+	total := decimal.Zero
+	for _, charge := range f.charges {
+		if charge.Side != "" && charge.Side != side {
+			continue
+		}
+		percent := charge.Percent
+		if len(charge.LeviedOn) > 0 {
+			base := decimal.Zero
+			for _, code := range charge.LeviedOn {
+				base = base.Add(percents[code])
+			}
+			percent = percentOf(base, charge.Percent)
+		}
+		percents[charge.Code] = percent
+		total = total.Add(percent)
+	}
+	return total
+}
+
+// chargesForPlan returns the calculator of a fee plan, or fallback when no
+// plan is in force
+func chargesForPlan(plan *models.FeePlan, fallback ChargesCalculator) ChargesCalculator {
+	if plan == nil {
+		return fallback
+	}
+	return &feePlanCharges{charges: plan.Charges}
+}
+
+// chargesFromEnv builds the plan named by CHARGES_PLAN. Both plans take
+// brokerage from BROKERAGE_PERCENT; the percent plan adds
+// TRANSACTION_FEE_PERCENT and india_equity the statutory rates.
+func chargesFromEnv() ChargesCalculator {
+	brokeragePercent := percentFromEnv("BROKERAGE_PERCENT", "0.1")
+	if os.Getenv("CHARGES_PLAN") != ChargesPlanIndiaEquity {
+		return &percentCharges{
+			brokeragePercent: brokeragePercent,
+			feePercent:       percentFromEnv("TRANSACTION_FEE_PERCENT", "0.05"),
+		}
+	}
+	return &indiaEquityCharges{
+		brokeragePercent: brokeragePercent,
+		sttPercent:       percentFromEnv("STT_PERCENT", "0.1"),
+		exchangePercent:  percentFromEnv("EXCHANGE_CHARGE_PERCENT", "0.00297"),
+		sebiPercent:      percentFromEnv("SEBI_FEE_PERCENT", "0.0001"),
+		stampDutyPercent: percentFromEnv("STAMP_DUTY_PERCENT", "0.015"),
+		gstPercent:       percentFromEnv("GST_PERCENT", "18"),
+	}
+}
+
+// percentFromEnv reads a non-negative percent, falling back to fallback
+func percentFromEnv(name, fallback string) decimal.Decimal {
+	if v := os.Getenv(name); v != "" {
+		if val, err := decimal.NewFromString(v); err == nil && !val.IsNegative() {
+			return val
+		}
+	}
+	return decimal.RequireFromString(fallback)
+}
+
+// feeFor is percent of the absolute value, in INR rounded half-up
+func feeFor(value, percent decimal.Decimal) decimal.Decimal {
+	return value.Abs().Mul(percent).Div(hundred, models.AmountScale, decimal.RoundHalfUp)
+}
+
+// percentOf is percent of value, exactly
+func percentOf(value, percent decimal.Decimal) decimal.Decimal {
+	product := value.Mul(percent)
+	return product.Div(hundred, product.Scale()+2, decimal.RoundHalfUp)
+}
+
+// nonZeroCharges drops charges that come to zero
+func nonZeroCharges(charges ...*models.RewardCharge) []*models.RewardCharge {
+	kept := make([]*models.RewardCharge, 0, len(charges))
+	for _, charge := range charges {
+		if !charge.Amount.IsZero() {
+			kept = append(kept, charge)
+		}
+	}
+	return kept
+}
+
+// chargeTotals splits charges the way rewards store them: the brokerage, and
+// the total of every other charge as the transaction fee, both to paise
+func chargeTotals(charges []*models.RewardCharge) (brokerage, transactionFee decimal.Decimal) {
+	brokerage, transactionFee = decimal.Zero, decimal.Zero
+	for _, charge := range charges {
+		if charge.Code == ChargeBrokerage {
+			brokerage = brokerage.Add(charge.Amount)
+		} else {
+			transactionFee = transactionFee.Add(charge.Amount)
+		}
+	}
+	return brokerage.Round(models.AmountScale, decimal.RoundDown), transactionFee.Round(models.AmountScale, decimal.RoundDown)
+}
+
+// chargesForRewards gives each reward's charges its ID
+func chargesForRewards(rewards ...*models.Reward) []*models.RewardCharge {
+	var charges []*models.RewardCharge
+	for _, reward := range rewards {
+		for _, charge := range reward.Charges {
+			charge.RewardID = reward.ID
+			charges = append(charges, charge)
+		}
+	}
+	return charges
+}
+
+// chargeDescription is the ledger description of a charge on an event
+func chargeDescription(code, eventID string) string {
+	name, ok := chargeDescriptions[code]
+	if !ok {
+		name = code
+	}
+	return name + " for " + eventID
+}
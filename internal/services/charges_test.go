@@ -0,0 +1,119 @@
+package services
+
+import (
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"strings"
+	"testing"
+)
+
+func TestIndiaEquityCharges(t *testing.T) {
+	t.Setenv("CHARGES_PLAN", ChargesPlanIndiaEquity)
+	for _, name := range []string{"BROKERAGE_PERCENT", "STT_PERCENT", "EXCHANGE_CHARGE_PERCENT", "SEBI_FEE_PERCENT", "STAMP_DUTY_PERCENT", "GST_PERCENT"} {
+		t.Setenv(name, "")
+	}
+	charges := chargesFromEnv()
+
+	tests := []struct {
+		name      string
+		side      string
+		value     string
+		want      string
+		brokerage string
+		fee       string
+	}{
+		// GST is 18% of the rounded brokerage plus exchange charge: 10.30
+		{"buy", TradeSideBuy, "10000.00", "BROKERAGE=10.00 STT=10.00 EXCHANGE_CHARGE=0.30 SEBI_FEE=0.01 STAMP_DUTY=1.50 GST=1.85", "10.00", "13.66"},
+		// Stamp duty is only due on purchases
+		{"sell", TradeSideSell, "10000.00", "BROKERAGE=10.00 STT=10.00 EXCHANGE_CHARGE=0.30 SEBI_FEE=0.01 GST=1.85", "10.00", "12.16"},
+		// Each charge is rounded half-up to paise on its own; the SEBI fee
+		// rounds to nothing and is left out
+		{"rounded per charge", TradeSideBuy, "1842.75", "BROKERAGE=1.84 STT=1.84 EXCHANGE_CHARGE=0.05 STAMP_DUTY=0.28 GST=0.34", "1.84", "2.51"},
+		{"at half a paisa", TradeSideBuy, "4875.00", "BROKERAGE=4.88 STT=4.88 EXCHANGE_CHARGE=0.14 STAMP_DUTY=0.73 GST=0.90", "4.88", "6.65"},
+		{"negative value", TradeSideSell, "-10000.00", "BROKERAGE=10.00 STT=10.00 EXCHANGE_CHARGE=0.30 SEBI_FEE=0.01 GST=1.85", "10.00", "12.16"},
+		{"zero value", TradeSideBuy, "0.00", "", "0.00", "0.00"},
+	}
+	for _, tt := range tests {
+		itemized := charges.Charges(tt.side, decimal.RequireFromString(tt.value))
+		parts := make([]string, len(itemized))
+		for i, charge := range itemized {
+			if charge.Account != chargeAccounts[charge.Code] {
+				t.Errorf("%s: %s posted to %s, want %s", tt.name, charge.Code, charge.Account, chargeAccounts[charge.Code])
+			}
+			parts[i] = charge.Code + "=" + charge.Amount.String()
+		}
+		if got := strings.Join(parts, " "); got != tt.want {
+			t.Errorf("%s: charges = %s, want %s", tt.name, got, tt.want)
+		}
+
+		brokerage, fee := chargeTotals(itemized)
+		if brokerage.String() != tt.brokerage || fee.String() != tt.fee {
+			t.Errorf("%s: totals = %s brokerage, %s fee, want %s, %s", tt.name, brokerage, fee, tt.brokerage, tt.fee)
+		}
+	}
+
+	// The percent sizing an amount_inr purchase includes GST on brokerage
+	// plus exchange charge, and stamp duty only when buying
+	if got := charges.Percent(TradeSideBuy).String(); got != "0.2366046" {
+		t.Errorf("Percent(BUY) = %s, want 0.2366046", got)
+	}
+	if got := charges.Percent(TradeSideSell).String(); got != "0.2216046" {
+		t.Errorf("Percent(SELL) = %s, want 0.2216046", got)
+	}
+}
+
+func TestFeePlanCharges(t *testing.T) {
+	minBrokerage, maxBrokerage := decimal.RequireFromString("20"), decimal.RequireFromString("500")
+	plan := &feePlanCharges{charges: []models.FeePlanCharge{
+		{Code: ChargeBrokerage, Account: AccountBrokerageExpense, Percent: decimal.RequireFromString("0.1"), MinINR: &minBrokerage, MaxINR: &maxBrokerage},
+		{Code: ChargeExchange, Account: AccountExchangeExpense, Percent: decimal.RequireFromString("0.00297")},
+		{Code: ChargeStampDuty, Account: AccountStampDutyExpense, Side: TradeSideBuy, Percent: decimal.RequireFromString("0.015")},
+		{Code: "DP_CHARGE", Account: "DP_CHARGE_EXPENSE", Side: TradeSideSell, FlatINR: decimal.RequireFromString("15.93")},
+		{Code: ChargeGST, Account: AccountGSTExpense, Percent: decimal.RequireFromString("18"), LeviedOn: []string{ChargeBrokerage, ChargeExchange}},
+	}}
+
+	tests := []struct {
+		name  string
+		side  string
+		value string
+		want  string
+	}{
+		// Brokerage 10.00 is raised to its minimum; GST is levied on the
+		// rounded brokerage and exchange charge
+		{"minimum", TradeSideBuy, "10000.00", "BROKERAGE=20.00 EXCHANGE_CHARGE=0.30 STAMP_DUTY=1.50 GST=3.65"},
+		{"sell side", TradeSideSell, "10000.00", "BROKERAGE=20.00 EXCHANGE_CHARGE=0.30 DP_CHARGE=15.93 GST=3.65"},
+		{"maximum", TradeSideBuy, "1000000.00", "BROKERAGE=500.00 EXCHANGE_CHARGE=29.70 STAMP_DUTY=150.00 GST=95.35"},
+		{"negative value", TradeSideSell, "-10000.00", "BROKERAGE=20.00 EXCHANGE_CHARGE=0.30 DP_CHARGE=15.93 GST=3.65"},
+		// Charges that round to zero are left out
+		{"tiny trade", TradeSideBuy, "1.00", "BROKERAGE=20.00 GST=3.60"},
+	}
+	for _, tt := range tests {
+		charges := plan.Charges(tt.side, decimal.RequireFromString(tt.value))
+		parts := make([]string, len(charges))
+		for i, charge := range charges {
+			parts[i] = charge.Code + "=" + charge.Amount.String()
+		}
+		if got := strings.Join(parts, " "); got != tt.want {
+			t.Errorf("%s: charges = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if got := plan.Percent(TradeSideBuy).String(); got != "0.1365046" {
+		t.Errorf("Percent(BUY) = %s, want 0.1365046", got)
+	}
+}
+
+func TestChargesForPlan(t *testing.T) {
+	fallback := &percentCharges{brokeragePercent: decimal.RequireFromString("0.1"), feePercent: decimal.RequireFromString("0.05")}
+	if got := chargesForPlan(nil, fallback); got != fallback {
+		t.Errorf("chargesForPlan(nil) = %v, want the fallback", got)
+	}
+
+	plan := &models.FeePlan{Charges: []models.FeePlanCharge{
+		{Code: ChargeBrokerage, Account: "REFERRAL_BROKERAGE", FlatINR: decimal.RequireFromString("5")},
+	}}
+	charges := chargesForPlan(plan, fallback).Charges(TradeSideBuy, decimal.RequireFromString("1000.00"))
+	if len(charges) != 1 || charges[0].Account != "REFERRAL_BROKERAGE" || charges[0].Amount.String() != "5.00" {
+		t.Errorf("chargesForPlan(plan) charged %v, want 5.00 to REFERRAL_BROKERAGE", charges)
+	}
+}
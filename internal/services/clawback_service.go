@@ -0,0 +1,508 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// EventTypeClawback marks the negative rewards a clawback books
+const EventTypeClawback = "CLAWBACK"
+
+// maxClawbackIDLength leaves room within rewards.event_id for the -SYMBOL
+// suffix of the longest stock symbol, VARCHAR(20); clawbacks.clawback_id
+// matches it
+const maxClawbackIDLength = maxEventIDLength - 1 - 20
+
+var (
+	// ErrClawbackNotFound is returned when no clawback exists for a clawback ID
+	ErrClawbackNotFound = newError(KindNotFound, "CLAWBACK_NOT_FOUND", "clawback not found")
+	// ErrClawbackIDInUse is returned when a clawback ID was used for another user
+	ErrClawbackIDInUse = newError(KindConflict, "CLAWBACK_ID_IN_USE", "clawback_id already used by another clawback")
+	// ErrInvalidClawback is returned when a clawback request is invalid
+	ErrInvalidClawback = newError(KindValidation, "INVALID_CLAWBACK", "invalid clawback")
+)
+
+// ClawbackService reclaims stock granted to a user, for example when the
+// account closes or a referral turns out to be fraudulent
+type ClawbackService struct {
+	rewardRepo     repository.RewardRepository
+	ledgerRepo     repository.LedgerRepository
+	vestingRepo    repository.VestingRepository
+	clawbackRepo   repository.ClawbackRepository
+	transferRepo   repository.TransferRepository
+	redemptionRepo repository.RedemptionRepository
+	userRepo       repository.UserRepository
+	eventTypeRepo  repository.EventTypeRepository
+	log            *logrus.Logger
+}
+
+// ClawbackRequest selects the grants to reclaim: those of the last
+// window_days days, optionally only of some event types. dry_run reports what
+// would be recovered without booking anything.
+type ClawbackRequest struct {
+	ClawbackID string   `json:"clawback_id"`
+	WindowDays int      `json:"window_days" binding:"required"`
+	EventTypes []string `json:"event_types"`
+	Reason     string   `json:"reason"`
+	DryRun     bool     `json:"dry_run"`
+}
+
+// clawbackGrants are the grants of one symbol a clawback covers
+type clawbackGrants struct {
+	rewards  []*models.Reward
+	quantity decimal.Decimal
+	valueINR decimal.Decimal
+}
+
+// NewClawbackService creates a new clawback service
+func NewClawbackService(
+	rewardRepo repository.RewardRepository,
+	ledgerRepo repository.LedgerRepository,
+	vestingRepo repository.VestingRepository,
+	clawbackRepo repository.ClawbackRepository,
+	transferRepo repository.TransferRepository,
+	redemptionRepo repository.RedemptionRepository,
+	userRepo repository.UserRepository,
+	eventTypeRepo repository.EventTypeRepository,
+	log *logrus.Logger,
+) *ClawbackService {
+	return &ClawbackService{
+		rewardRepo:     rewardRepo,
+		ledgerRepo:     ledgerRepo,
+		vestingRepo:    vestingRepo,
+		clawbackRepo:   clawbackRepo,
+		transferRepo:   transferRepo,
+		redemptionRepo: redemptionRepo,
+		userRepo:       userRepo,
+		eventTypeRepo:  eventTypeRepo,
+		log:            log,
+	}
+}
+
+// ClawbackUser reclaims the user's settled grants in the window. For each
+// symbol it recovers what the user still holds, up to the quantity granted,
+// as a negative CLAWBACK reward with balanced ledger entries. Whatever was
+// granted but is no longer held, or is held for a requested redemption, is
+// reported as unrecoverable, split into what was transferred and what was
+// redeemed. Unvested
+// tranches of the covered grants are cancelled, and a grant is never covered
+// by two clawbacks. Repeating a call with the same clawback ID returns the
+// stored result.
+func (cs *ClawbackService) ClawbackUser(ctx context.Context, userID string, req *ClawbackRequest) (*models.Clawback, error) {
+	if err := validateClawback(req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClawback, err)
+	}
+
+	exists, err := cs.userRepo.Exists(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	clawback := &models.Clawback{
+		ClawbackID: req.ClawbackID,
+		UserID:     userID,
+		WindowDays: req.WindowDays,
+		EventTypes: req.EventTypes,
+	}
+	if req.Reason != "" {
+		clawback.Reason = &req.Reason
+	}
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -req.WindowDays)
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		if !req.DryRun {
+			claimed, err := cs.clawbackRepo.Claim(ctx, clawback)
+			if err != nil {
+				return fmt.Errorf("failed to record clawback: %w", err)
+			}
+			if !claimed {
+				existing, err := cs.clawbackRepo.GetByClawbackID(ctx, req.ClawbackID)
+				if err != nil {
+					return fmt.Errorf("failed to load clawback: %w", err)
+				}
+				if existing.UserID != userID {
+					return ErrClawbackIDInUse
+				}
+				if existing.WindowDays != req.WindowDays || !sameEventTypes(existing.EventTypes, req.EventTypes) {
+					return fmt.Errorf("%w: clawback %s was already used with a different window or event types",
+						ErrIdempotencyConflict, req.ClawbackID)
+				}
+				clawback = existing
+				return nil
+			}
+		}
+
+		grants, err := cs.rewardRepo.LockGrantedSince(ctx, userID, since, req.EventTypes)
+		if err != nil {
+			return fmt.Errorf("failed to load granted rewards: %w", err)
+		}
+
+		bySymbol := make(map[string]*clawbackGrants)
+		for _, grant := range grants {
+			group, ok := bySymbol[grant.StockSymbol]
+			if !ok {
+				group = &clawbackGrants{}
+				bySymbol[grant.StockSymbol] = group
+			}
+			group.rewards = append(group.rewards, grant)
+			group.quantity = group.quantity.Add(grant.Quantity)
+			group.valueINR = group.valueINR.Add(grant.TotalValueINR)
+		}
+		symbols := make([]string, 0, len(bySymbol))
+		for symbol := range bySymbol {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+
+		// Holdings are locked in symbol order, like batches, so a clawback and
+		// a negative reward for the same position cannot interleave
+		for _, symbol := range symbols {
+			if err := cs.rewardRepo.LockHolding(ctx, userID, symbol); err != nil {
+				return err
+			}
+		}
+		unvested, err := cs.vestingRepo.GetUnvestedQuantities(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to load unvested quantities: %w", err)
+		}
+
+		clawback.Items = make([]models.ClawbackItem, 0, len(symbols))
+		for _, symbol := range symbols {
+			item, err := cs.clawbackSymbol(ctx, clawback, symbol, bySymbol[symbol], unvested[symbol], req.DryRun, since, now)
+			if err != nil {
+				return err
+			}
+			clawback.Items = append(clawback.Items, *item)
+		}
+
+		if req.DryRun {
+			return nil
+		}
+		ids := make([]int, 0, len(grants))
+		for _, grant := range grants {
+			ids = append(ids, grant.ID)
+		}
+		if err := cs.rewardRepo.MarkClawedBack(ctx, ids, now); err != nil {
+			return fmt.Errorf("failed to mark rewards clawed back: %w", err)
+		}
+		if err := cs.clawbackRepo.SetItems(ctx, clawback.ID, clawback.Items); err != nil {
+			return fmt.Errorf("failed to store clawback items: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.DryRun {
+		cs.log.Infof("Clawback %s for user %s covered %d symbols", clawback.ClawbackID, userID, len(clawback.Items))
+	}
+	return clawback, nil
+}
+
+// clawbackSymbol works out and, unless dryRun, books the recovery of one
+// symbol's grants. unvestedTotal is the user's unvested quantity of the
+// symbol across all rewards; unvested stock of grants outside the clawback
+// and stock held for requested redemptions are never taken. since is the
+// start of the clawback's window.
+func (cs *ClawbackService) clawbackSymbol(
+	ctx context.Context,
+	clawback *models.Clawback,
+	symbol string,
+	grants *clawbackGrants,
+	unvestedTotal decimal.Decimal,
+	dryRun bool,
+	since, now time.Time,
+) (*models.ClawbackItem, error) {
+	held, err := cs.rewardRepo.GetNetQuantity(ctx, clawback.UserID, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holding of %s: %w", symbol, err)
+	}
+	requested, err := cs.redemptionRepo.GetRequestedQuantity(ctx, clawback.UserID, symbol, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load requested redemptions: %w", err)
+	}
+
+	item := &models.ClawbackItem{
+		StockSymbol:     symbol,
+		GrantedQuantity: grants.quantity,
+		RewardIDs:       make([]int, 0, len(grants.rewards)),
+	}
+
+	var grantUnvested, grantUnvestedValue decimal.Decimal
+	for _, grant := range grants.rewards {
+		item.RewardIDs = append(item.RewardIDs, grant.ID)
+		if grant.Vesting == nil {
+			continue
+		}
+		tranches, err := cs.vestingRepo.GetByRewardID(ctx, grant.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load vesting tranches: %w", err)
+		}
+		for _, tranche := range tranches {
+			if tranche.Status == models.VestingStatusUnvested {
+				grantUnvested = grantUnvested.Add(tranche.Quantity)
+				grantUnvestedValue = grantUnvestedValue.Add(tranche.ValueINR)
+			}
+		}
+	}
+
+	available := decimal.Max(held.Sub(unvestedTotal.Sub(grantUnvested)).Sub(requested), decimal.Zero)
+	item.RecoveredQuantity = decimal.Min(grants.quantity, available)
+	item.UnrecoverableQuantity = grants.quantity.Sub(item.RecoveredQuantity)
+	item.CancelledUnvested = grantUnvested
+	if item.UnrecoverableQuantity.IsPositive() {
+		if err := cs.explainShortfall(ctx, clawback.UserID, symbol, item, requested, since); err != nil {
+			return nil, err
+		}
+	}
+
+	// Recovered stock is valued at what it was booked at: unvested stock at
+	// its tranche values, the rest at the grants' average price. Each part
+	// is rounded half-up to paise.
+	unvestedRecovered := decimal.Min(item.RecoveredQuantity, grantUnvested)
+	unvestedValue := decimal.Zero
+	if grantUnvested.IsPositive() {
+		unvestedValue = grantUnvestedValue.Mul(unvestedRecovered).Div(grantUnvested, models.AmountScale, decimal.RoundHalfUp)
+	}
+	vestedValue := item.RecoveredQuantity.Sub(unvestedRecovered).Mul(grants.valueINR).Div(grants.quantity, models.AmountScale, decimal.RoundHalfUp)
+	item.ValueINR = unvestedValue.Add(vestedValue)
+
+	if item.UnrecoverableQuantity.IsPositive() {
+		cs.log.Warnf("Clawback %s cannot recover %s %s from user %s: %s transferred, %s redeemed",
+			clawback.ClawbackID, item.UnrecoverableQuantity, symbol, clawback.UserID,
+			item.TransferredQuantity, item.RedeemedQuantity)
+	}
+	if dryRun {
+		return item, nil
+	}
+
+	for _, grant := range grants.rewards {
+		if grant.Vesting == nil {
+			continue
+		}
+		if _, err := cs.vestingRepo.CancelByRewardID(ctx, grant.ID); err != nil {
+			return nil, fmt.Errorf("failed to cancel vesting tranches: %w", err)
+		}
+	}
+	if item.RecoveredQuantity.IsZero() {
+		return item, nil
+	}
+
+	notes := fmt.Sprintf("Clawback %s", clawback.ClawbackID)
+	if clawback.Reason != nil {
+		notes = fmt.Sprintf("%s: %s", notes, *clawback.Reason)
+	}
+	reward, err := cs.rewardRepo.Create(ctx, &models.Reward{
+		UserID:         clawback.UserID,
+		StockSymbol:    symbol,
+		Quantity:       item.RecoveredQuantity.Neg(),
+		EventType:      EventTypeClawback,
+		EventID:        fmt.Sprintf("%s-%s", clawback.ClawbackID, symbol),
+		EventTimestamp: now,
+		StockPrice:     item.ValueINR.Div(item.RecoveredQuantity, models.PriceScale, decimal.RoundHalfUp),
+		TotalValueINR:  item.ValueINR.Neg(),
+		NetValueINR:    item.ValueINR.Neg(),
+		Status:         models.RewardStatusCompleted,
+		Notes:          &notes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clawback reward: %w", err)
+	}
+	item.EventID = reward.EventID
+
+	income, err := cs.clawbackIncome(ctx, grants, item.ValueINR)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.ledgerRepo.BulkCreate(ctx, clawbackLedgerEntries(reward, unvestedValue, income)); err != nil {
+		return nil, fmt.Errorf("failed to create clawback ledger entries: %w", err)
+	}
+	return item, nil
+}
+
+// explainShortfall splits an item's unrecoverable quantity into stock sent in
+// transfers and stock redeemed since the window started. Requested
+// redemptions are held back from the recovery, so they count first; whatever
+// neither explains was adjusted away.
+func (cs *ClawbackService) explainShortfall(ctx context.Context, userID, symbol string, item *models.ClawbackItem, requested decimal.Decimal, since time.Time) error {
+	sent, err := cs.transferRepo.GetSentQuantitySince(ctx, userID, symbol, since)
+	if err != nil {
+		return fmt.Errorf("failed to load transferred quantity: %w", err)
+	}
+	executed, err := cs.redemptionRepo.GetExecutedQuantitySince(ctx, userID, symbol, since)
+	if err != nil {
+		return fmt.Errorf("failed to load redeemed quantity: %w", err)
+	}
+
+	shortfall := item.UnrecoverableQuantity
+	pending := decimal.Min(shortfall, requested)
+	shortfall = shortfall.Sub(pending)
+	item.TransferredQuantity = decimal.Min(shortfall, sent)
+	shortfall = shortfall.Sub(item.TransferredQuantity)
+	item.RedeemedQuantity = pending.Add(decimal.Min(shortfall, executed))
+	return nil
+}
+
+// GetClawback retrieves a clawback by its clawback ID
+func (cs *ClawbackService) GetClawback(ctx context.Context, clawbackID string) (*models.Clawback, error) {
+	clawback, err := cs.clawbackRepo.GetByClawbackID(ctx, clawbackID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrClawbackNotFound
+		}
+		return nil, err
+	}
+	return clawback, nil
+}
+
+// GetUserClawbacks lists a user's clawbacks, newest first
+func (cs *ClawbackService) GetUserClawbacks(ctx context.Context, userID string, limit, offset int) ([]*models.Clawback, error) {
+	return cs.clawbackRepo.GetByUserID(ctx, userID, limit, offset)
+}
+
+// validateClawback checks a clawback request and upper-cases its event types
+func validateClawback(req *ClawbackRequest) error {
+	if req.ClawbackID == "" && !req.DryRun {
+		return fmt.Errorf("clawback_id is required")
+	}
+	if len(req.ClawbackID) > maxClawbackIDLength {
+		return fmt.Errorf("clawback_id must be at most %d characters", maxClawbackIDLength)
+	}
+	if req.WindowDays <= 0 {
+		return fmt.Errorf("window_days must be positive")
+	}
+	for i, eventType := range req.EventTypes {
+		if eventType == "" {
+			return fmt.Errorf("event_types must not contain empty values")
+		}
+		req.EventTypes[i] = strings.ToUpper(eventType)
+	}
+	return nil
+}
+
+// sameEventTypes reports whether two event type filters select the same
+// types, in any order
+func sameEventTypes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// clawbackDebit is the part of a clawback's value reversed out of one account
+type clawbackDebit struct {
+	account string
+	amount  decimal.Decimal
+}
+
+// clawbackIncome splits the value recovered from a symbol's grants across the
+// accounts their event types credited, in proportion to the value each
+// account was credited. Shares are rounded half-up to paise and the last
+// account in name order takes the remainder, so they sum to value exactly.
+func (cs *ClawbackService) clawbackIncome(ctx context.Context, grants *clawbackGrants, value decimal.Decimal) ([]clawbackDebit, error) {
+	eventTypes := make(map[string]*models.EventType)
+	granted := make(map[string]decimal.Decimal)
+	for _, grant := range grants.rewards {
+		eventType, ok := eventTypes[grant.EventType]
+		if !ok {
+			var err error
+			if eventType, err = ledgerEventType(ctx, cs.eventTypeRepo, grant.EventType); err != nil {
+				return nil, err
+			}
+			eventTypes[grant.EventType] = eventType
+		}
+		granted[eventType.CreditAccount] = granted[eventType.CreditAccount].Add(grant.TotalValueINR)
+	}
+	return splitClawbackValue(granted, grants.valueINR, value), nil
+}
+
+// splitClawbackValue shares value across accounts in proportion to what was
+// granted against each of them out of total
+func splitClawbackValue(granted map[string]decimal.Decimal, total, value decimal.Decimal) []clawbackDebit {
+	accounts := make([]string, 0, len(granted))
+	for account := range granted {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	debits := make([]clawbackDebit, 0, len(accounts))
+	remaining := value
+	for i, account := range accounts {
+		amount := remaining
+		if i < len(accounts)-1 {
+			amount = value.Mul(granted[account]).Div(total, models.AmountScale, decimal.RoundHalfUp)
+			remaining = remaining.Sub(amount)
+		}
+		debits = append(debits, clawbackDebit{account: account, amount: amount})
+	}
+	return debits
+}
+
+// clawbackLedgerEntries takes recovered stock out of the asset accounts it
+// sits in and reverses the income its grants were credited against
+func clawbackLedgerEntries(reward *models.Reward, unvestedValue decimal.Decimal, income []clawbackDebit) []*models.LedgerEntry {
+	value := reward.TotalValueINR.Abs()
+	stockDesc := fmt.Sprintf("Clawback: %s x %s", reward.StockSymbol, reward.Quantity.Abs())
+	incomeDesc := fmt.Sprintf("Reward income clawed back for event %s", reward.EventID)
+
+	entries := make([]*models.LedgerEntry, 0, 2+len(income))
+	credit := func(account string, amount decimal.Decimal) {
+		if !amount.IsPositive() {
+			return
+		}
+		entries = append(entries, &models.LedgerEntry{
+			RewardID:    reward.ID,
+			UserID:      reward.UserID,
+			EntryType:   "CREDIT",
+			AccountType: account,
+			Amount:      amount,
+			Currency:    "INR",
+			Description: &stockDesc,
+			ReferenceID: &reward.EventID,
+		})
+	}
+	credit(AccountUnvestedStockAsset, unvestedValue)
+	credit(AccountStockAsset, value.Sub(unvestedValue))
+
+	for _, debit := range income {
+		if !debit.amount.IsPositive() {
+			continue
+		}
+		entries = append(entries, &models.LedgerEntry{
+			RewardID:    reward.ID,
+			UserID:      reward.UserID,
+			EntryType:   "DEBIT",
+			AccountType: debit.account,
+			Amount:      debit.amount,
+			Currency:    "INR",
+			Description: &incomeDesc,
+			ReferenceID: &reward.EventID,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrorKind classifies a domain error so callers can decide how to react
+// without matching on messages
+type ErrorKind string
+
+// Error kinds
+const (
+	// KindValidation means the request is malformed or breaks a business
+	// rule; it will fail again unless it is changed
+	KindValidation ErrorKind = "VALIDATION"
+	// KindNotFound means a resource the request names does not exist
+	KindNotFound ErrorKind = "NOT_FOUND"
+	// KindConflict means the request clashes with the current state, such as
+	// a reused idempotency key or a status that does not allow the change
+	KindConflict ErrorKind = "CONFLICT"
+	// KindUnavailable means something the request needs is not available
+	// right now; the same request may succeed later
+	KindUnavailable ErrorKind = "UNAVAILABLE"
+	// KindInternal is an unexpected failure
+	KindInternal ErrorKind = "INTERNAL"
+)
+
+// Error is a domain error with a kind and a stable, machine-readable code.
+// Services return the sentinels below, usually wrapped with detail, and
+// callers match them with errors.Is or read them with AsError.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	// RetryAfter hints when an UNAVAILABLE request is worth retrying
+	RetryAfter time.Duration
+	// Status, when set, is the HTTP status to report instead of the kind's;
+	// it keeps the statuses endpoints returned before errors had kinds
+	Status int
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// newError creates a domain error sentinel
+func newError(kind ErrorKind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// withStatus sets the HTTP status a sentinel is reported with
+func (e *Error) withStatus(status int) *Error {
+	e.Status = status
+	return e
+}
+
+var (
+	// ErrValidation is returned when a request fails validation that has no
+	// more specific error of its own
+	ErrValidation = newError(KindValidation, "VALIDATION_FAILED", "validation failed")
+	// ErrUserNotFound is returned when an operation names an unknown user
+	ErrUserNotFound = newError(KindNotFound, "USER_NOT_FOUND", "user not found")
+	// ErrUserExists is returned when creating a user whose user_id is taken
+	ErrUserExists = newError(KindConflict, "USER_EXISTS", "user already exists")
+	// ErrInternal classifies errors that carry no domain error
+	ErrInternal = newError(KindInternal, "INTERNAL_ERROR", "internal error")
+)
+
+// AsError returns the first domain error in err's chain, or ErrInternal when
+// there is none
+func AsError(err error) *Error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+	return ErrInternal
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrEventTypeNotFound is returned when an event type is not registered
+	ErrEventTypeNotFound = newError(KindNotFound, "EVENT_TYPE_NOT_FOUND", "event type not found")
+	// ErrEventTypeInactive is returned when a reward names a deactivated event type
+	ErrEventTypeInactive = newError(KindConflict, "EVENT_TYPE_INACTIVE", "event type is not active")
+	// ErrEventTypeExists is returned when registering a code that is already taken
+	ErrEventTypeExists = newError(KindConflict, "EVENT_TYPE_EXISTS", "event type already exists")
+	// ErrInvalidEventType is returned when an event type's code, limits or accounts are invalid
+	ErrInvalidEventType = newError(KindValidation, "INVALID_EVENT_TYPE", "invalid event type")
+	// ErrEventTypeRule is returned when a reward breaks a rule of its event type
+	ErrEventTypeRule = newError(KindValidation, "EVENT_TYPE_RULE_VIOLATED", "reward breaks the rules of its event type")
+)
+
+// defaultEventType is the event type of a reward that names none
+const defaultEventType = "REWARD"
+
+// Accounts a reward is posted to unless its event type says otherwise
+const (
+	AccountRewardIncome      = "REWARD_INCOME"
+	AccountAdjustmentExpense = "ADJUSTMENT_EXPENSE"
+)
+
+// maxEventTypeCodeLength matches event_types.code and rewards.event_type VARCHAR(50)
+const maxEventTypeCodeLength = 50
+
+// codePattern is the shape of event type codes and ledger account names
+var codePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// systemEventTypes are booked by the services themselves and cannot be registered
+var systemEventTypes = map[string]bool{
+	EventTypeReversal:    true,
+	EventTypeClawback:    true,
+	EventTypeTransferOut: true,
+	EventTypeTransferIn:  true,
+	EventTypeRedemption:  true,
+}
+
+// EventTypeService manages the event type registry
+type EventTypeService struct {
+	eventTypeRepo repository.EventTypeRepository
+	log           *logrus.Logger
+}
+
+// EventTypeRequest represents an event type to register
+type EventTypeRequest struct {
+	Code          string           `json:"code" binding:"required"`
+	Description   string           `json:"description"`
+	AllowNegative bool             `json:"allow_negative"`
+	MaxQuantity   *decimal.Decimal `json:"max_quantity"`
+	MaxValueINR   *decimal.Decimal `json:"max_value_inr"`
+	NotesRequired bool             `json:"notes_required"`
+	CreditAccount string           `json:"credit_account"`
+	DebitAccount  string           `json:"debit_account"`
+}
+
+// EventTypeUpdateRequest represents changes to an event type's rules; a limit
+// of 0 removes it. The code cannot change.
+type EventTypeUpdateRequest struct {
+	Description   *string          `json:"description"`
+	AllowNegative *bool            `json:"allow_negative"`
+	MaxQuantity   *decimal.Decimal `json:"max_quantity"`
+	MaxValueINR   *decimal.Decimal `json:"max_value_inr"`
+	NotesRequired *bool            `json:"notes_required"`
+	CreditAccount *string          `json:"credit_account"`
+	DebitAccount  *string          `json:"debit_account"`
+	Active        *bool            `json:"active"`
+}
+
+// NewEventTypeService creates a new event type service
+func NewEventTypeService(eventTypeRepo repository.EventTypeRepository, log *logrus.Logger) *EventTypeService {
+	return &EventTypeService{
+		eventTypeRepo: eventTypeRepo,
+		log:           log,
+	}
+}
+
+// CreateEventType validates and registers a new event type
+func (es *EventTypeService) CreateEventType(ctx context.Context, req *EventTypeRequest) (*models.EventType, error) {
+	eventType := &models.EventType{
+		Code:          strings.ToUpper(strings.TrimSpace(req.Code)),
+		AllowNegative: req.AllowNegative,
+		MaxQuantity:   req.MaxQuantity,
+		MaxValueINR:   req.MaxValueINR,
+		NotesRequired: req.NotesRequired,
+		CreditAccount: strings.ToUpper(req.CreditAccount),
+		DebitAccount:  strings.ToUpper(req.DebitAccount),
+		Active:        true,
+	}
+	if req.Description != "" {
+		eventType.Description = &req.Description
+	}
+	if eventType.CreditAccount == "" {
+		eventType.CreditAccount = AccountRewardIncome
+	}
+	if eventType.DebitAccount == "" {
+		eventType.DebitAccount = AccountAdjustmentExpense
+	}
+
+	if err := validateEventTypeCode(eventType.Code); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEventType, err)
+	}
+	if err := validateEventType(eventType); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEventType, err)
+	}
+
+	created, err := es.eventTypeRepo.Create(ctx, eventType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event type: %w", err)
+	}
+	if !created {
+		return nil, fmt.Errorf("%w: %s", ErrEventTypeExists, eventType.Code)
+	}
+
+	es.log.Infof("Registered event type %s", eventType.Code)
+	return eventType, nil
+}
+
+// GetEventType retrieves an event type by code
+func (es *EventTypeService) GetEventType(ctx context.Context, code string) (*models.EventType, error) {
+	eventType, err := es.eventTypeRepo.GetByCode(ctx, strings.ToUpper(code))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrEventTypeNotFound, code)
+		}
+		return nil, err
+	}
+	return eventType, nil
+}
+
+// ListEventTypes lists the registered event types by code
+func (es *EventTypeService) ListEventTypes(ctx context.Context, activeOnly bool) ([]*models.EventType, error) {
+	return es.eventTypeRepo.List(ctx, activeOnly)
+}
+
+// UpdateEventType changes an event type's rules, accounts or active flag.
+// Rewards already booked keep their ledger entries.
+func (es *EventTypeService) UpdateEventType(ctx context.Context, code string, req *EventTypeUpdateRequest) (*models.EventType, error) {
+	eventType, err := es.GetEventType(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Description != nil {
+		eventType.Description = req.Description
+	}
+	if req.AllowNegative != nil {
+		eventType.AllowNegative = *req.AllowNegative
+	}
+	if req.MaxQuantity != nil {
+		eventType.MaxQuantity = req.MaxQuantity
+		if req.MaxQuantity.IsZero() {
+			eventType.MaxQuantity = nil
+		}
+	}
+	if req.MaxValueINR != nil {
+		eventType.MaxValueINR = req.MaxValueINR
+		if req.MaxValueINR.IsZero() {
+			eventType.MaxValueINR = nil
+		}
+	}
+	if req.NotesRequired != nil {
+		eventType.NotesRequired = *req.NotesRequired
+	}
+	if req.CreditAccount != nil {
+		eventType.CreditAccount = strings.ToUpper(*req.CreditAccount)
+	}
+	if req.DebitAccount != nil {
+		eventType.DebitAccount = strings.ToUpper(*req.DebitAccount)
+	}
+	if req.Active != nil {
+		eventType.Active = *req.Active
+	}
+
+	if err := validateEventType(eventType); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEventType, err)
+	}
+
+	if err := es.eventTypeRepo.Update(ctx, eventType); err != nil {
+		return nil, fmt.Errorf("failed to update event type: %w", err)
+	}
+
+	es.log.Infof("Updated event type %s", eventType.Code)
+	return eventType, nil
+}
+
+// validateEventTypeCode checks the code of a new event type. Codes
+// registered from existing rewards may not follow it, so it is not rechecked
+// on update.
+func validateEventTypeCode(code string) error {
+	if !codePattern.MatchString(code) || len(code) > maxEventTypeCodeLength {
+		return fmt.Errorf("code must be at most %d letters, digits or underscores, starting with a letter", maxEventTypeCodeLength)
+	}
+	if systemEventTypes[code] {
+		return fmt.Errorf("%s events are booked by the service itself", code)
+	}
+	return nil
+}
+
+// validateEventType checks an event type's limits and accounts
+func validateEventType(eventType *models.EventType) error {
+	if eventType.MaxQuantity != nil {
+		if !eventType.MaxQuantity.IsPositive() {
+			return fmt.Errorf("max_quantity must be positive")
+		}
+		if err := normalizePlaces("max_quantity", eventType.MaxQuantity, models.QuantityScale); err != nil {
+			return err
+		}
+	}
+	if eventType.MaxValueINR != nil {
+		if !eventType.MaxValueINR.IsPositive() {
+			return fmt.Errorf("max_value_inr must be positive")
+		}
+		if err := normalizePlaces("max_value_inr", eventType.MaxValueINR, models.AmountScale); err != nil {
+			return err
+		}
+	}
+	if err := validateAccount("credit_account", eventType.CreditAccount); err != nil {
+		return err
+	}
+	return validateAccount("debit_account", eventType.DebitAccount)
+}
+
+// validateAccount checks a ledger account an event type posts to
+func validateAccount(field, account string) error {
+	if !codePattern.MatchString(account) || len(account) > maxEventTypeCodeLength {
+		return fmt.Errorf("%s must be an account name like %s", field, AccountRewardIncome)
+	}
+	// The reward itself posts the stock; the event type only names its counterpart
+	if account == AccountStockAsset || account == AccountUnvestedStockAsset {
+		return fmt.Errorf("%s cannot be %s", field, account)
+	}
+	return nil
+}
+
+// activeEventType loads the active registered event type code names; an
+// empty code names the default type
+func activeEventType(ctx context.Context, eventTypeRepo repository.EventTypeRepository, code string) (*models.EventType, error) {
+	code = eventTypeCode(code)
+	eventType, err := eventTypeRepo.GetByCode(ctx, code)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to load event type: %w", err)
+	}
+	return usableEventType(code, eventType)
+}
+
+// usableEventType checks that eventType, as loaded for code, may be used by
+// a new reward; a nil eventType means code is not registered
+func usableEventType(code string, eventType *models.EventType) (*models.EventType, error) {
+	if eventType == nil {
+		return nil, fmt.Errorf("%w: %s", ErrEventTypeNotFound, code)
+	}
+	if !eventType.Active {
+		return nil, fmt.Errorf("%w: %s", ErrEventTypeInactive, code)
+	}
+	return eventType, nil
+}
+
+// eventTypeCode returns the event type a reward is booked under
+func eventTypeCode(code string) string {
+	if code == "" {
+		return defaultEventType
+	}
+	return code
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrFeePlanNotFound is returned when no fee plan exists for an ID
+	ErrFeePlanNotFound = newError(KindNotFound, "FEE_PLAN_NOT_FOUND", "fee plan not found")
+	// ErrInvalidFeePlan is returned when a fee plan's scope, dates or charges are invalid
+	ErrInvalidFeePlan = newError(KindValidation, "INVALID_FEE_PLAN", "invalid fee plan")
+	// ErrFeePlanOverlap is returned when a plan would be in force alongside
+	// another plan of the same symbol and event type
+	ErrFeePlanOverlap = newError(KindConflict, "FEE_PLAN_OVERLAP", "fee plan overlaps another plan")
+	// ErrFeePlanEnded is returned when changing a plan that is no longer in force
+	ErrFeePlanEnded = newError(KindConflict, "FEE_PLAN_ENDED", "fee plan has ended")
+)
+
+const (
+	// maxChargeCodeLength matches reward_charges.code VARCHAR(30)
+	maxChargeCodeLength = 30
+	// maxFeePlanSymbolLength matches fee_plans.stock_symbol VARCHAR(20)
+	maxFeePlanSymbolLength = 20
+	// maxFeePlanNameLength matches fee_plans.name VARCHAR(200)
+	maxFeePlanNameLength = 200
+)
+
+// FeePlanService manages the effective-dated fee plans rewards are charged by
+type FeePlanService struct {
+	feePlanRepo repository.FeePlanRepository
+	log         *logrus.Logger
+}
+
+// FeePlanRequest represents a fee plan to create. Without effective_from the
+// plan starts now.
+type FeePlanRequest struct {
+	Name          string                 `json:"name" binding:"required"`
+	StockSymbol   string                 `json:"stock_symbol"`
+	EventType     string                 `json:"event_type"`
+	EffectiveFrom *time.Time             `json:"effective_from"`
+	EffectiveTo   *time.Time             `json:"effective_to"`
+	Charges       []models.FeePlanCharge `json:"charges" binding:"required"`
+}
+
+// FeePlanUpdateRequest renames a plan or moves its end. The scope, start and
+// charges of a plan cannot change; a new version is a new plan.
+type FeePlanUpdateRequest struct {
+	Name        *string    `json:"name"`
+	EffectiveTo *time.Time `json:"effective_to"`
+}
+
+// NewFeePlanService creates a new fee plan service
+func NewFeePlanService(feePlanRepo repository.FeePlanRepository, log *logrus.Logger) *FeePlanService {
+	return &FeePlanService{
+		feePlanRepo: feePlanRepo,
+		log:         log,
+	}
+}
+
+// CreateFeePlan validates and stores a fee plan. An open-ended plan of the
+// same symbol and event type that started earlier is ended where an
+// open-ended new plan starts, making the new plan its next version; any other
+// overlap is rejected.
+func (fs *FeePlanService) CreateFeePlan(ctx context.Context, req *FeePlanRequest) (*models.FeePlan, error) {
+	now := time.Now()
+	plan := &models.FeePlan{
+		Name:          strings.TrimSpace(req.Name),
+		EffectiveFrom: now,
+		EffectiveTo:   req.EffectiveTo,
+		Charges:       req.Charges,
+	}
+	if req.StockSymbol != "" {
+		symbol := strings.ToUpper(strings.TrimSpace(req.StockSymbol))
+		plan.StockSymbol = &symbol
+	}
+	if req.EventType != "" {
+		eventType := strings.ToUpper(strings.TrimSpace(req.EventType))
+		plan.EventType = &eventType
+	}
+	if req.EffectiveFrom != nil {
+		// Rewards already booked keep the plan they were charged by
+		if req.EffectiveFrom.Before(now) {
+			return nil, fmt.Errorf("%w: effective_from cannot be in the past; omit it to start now", ErrInvalidFeePlan)
+		}
+		plan.EffectiveFrom = *req.EffectiveFrom
+	}
+
+	if err := validateFeePlan(plan); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFeePlan, err)
+	}
+
+	var superseded *models.FeePlan
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := fs.feePlanRepo.LockScope(ctx, plan.StockSymbol, plan.EventType); err != nil {
+			return err
+		}
+		others, err := fs.scopePlans(ctx, plan)
+		if err != nil {
+			return err
+		}
+		for _, other := range others {
+			if !plansOverlap(other, plan) {
+				continue
+			}
+			if other.EffectiveTo == nil && plan.EffectiveTo == nil && other.EffectiveFrom.Before(plan.EffectiveFrom) {
+				other.EffectiveTo = &plan.EffectiveFrom
+				if err := fs.feePlanRepo.Update(ctx, other); err != nil {
+					return fmt.Errorf("failed to end fee plan %d: %w", other.ID, err)
+				}
+				superseded = other
+				continue
+			}
+			return fmt.Errorf("%w: plan %d (%s) is in force from %s",
+				ErrFeePlanOverlap, other.ID, other.Name, other.EffectiveFrom.Format(time.RFC3339))
+		}
+
+		if err := fs.feePlanRepo.Create(ctx, plan); err != nil {
+			return fmt.Errorf("failed to create fee plan: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if superseded != nil {
+		fs.log.Infof("Fee plan %d ends at %s, superseded by fee plan %d",
+			superseded.ID, superseded.EffectiveTo.Format(time.RFC3339), plan.ID)
+	}
+	fs.log.Infof("Created fee plan %d (%s) from %s", plan.ID, plan.Name, plan.EffectiveFrom.Format(time.RFC3339))
+	return plan, nil
+}
+
+// GetFeePlan retrieves a fee plan by ID
+func (fs *FeePlanService) GetFeePlan(ctx context.Context, id int) (*models.FeePlan, error) {
+	plan, err := fs.feePlanRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %d", ErrFeePlanNotFound, id)
+		}
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ListFeePlans lists the plans that apply to a symbol and event type, latest
+// first; with at, only those in force then
+func (fs *FeePlanService) ListFeePlans(ctx context.Context, stockSymbol, eventType string, at *time.Time) ([]*models.FeePlan, error) {
+	return fs.feePlanRepo.List(ctx, strings.ToUpper(stockSymbol), strings.ToUpper(eventType), at)
+}
+
+// UpdateFeePlan renames a plan or moves its end. The end cannot move into
+// the past, and a plan that has ended cannot change, so rewards already
+// booked keep the plan they were charged by.
+func (fs *FeePlanService) UpdateFeePlan(ctx context.Context, id int, req *FeePlanUpdateRequest) (*models.FeePlan, error) {
+	plan, err := fs.GetFeePlan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := fs.feePlanRepo.LockScope(ctx, plan.StockSymbol, plan.EventType); err != nil {
+			return err
+		}
+		// Read the plan again now that no other change to its scope can run
+		if plan, err = fs.GetFeePlan(ctx, id); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if plan.EffectiveTo != nil && !plan.EffectiveTo.After(now) {
+			return fmt.Errorf("%w: plan %d ended at %s", ErrFeePlanEnded, plan.ID, plan.EffectiveTo.Format(time.RFC3339))
+		}
+		if req.Name != nil {
+			plan.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.EffectiveTo != nil {
+			if req.EffectiveTo.Before(now) {
+				return fmt.Errorf("%w: effective_to cannot be in the past", ErrInvalidFeePlan)
+			}
+			plan.EffectiveTo = req.EffectiveTo
+		}
+		if err := validateFeePlan(plan); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFeePlan, err)
+		}
+
+		if req.EffectiveTo != nil {
+			others, err := fs.scopePlans(ctx, plan)
+			if err != nil {
+				return err
+			}
+			for _, other := range others {
+				if other.ID != plan.ID && plansOverlap(other, plan) {
+					return fmt.Errorf("%w: plan %d (%s) is in force from %s",
+						ErrFeePlanOverlap, other.ID, other.Name, other.EffectiveFrom.Format(time.RFC3339))
+				}
+			}
+		}
+
+		if err := fs.feePlanRepo.Update(ctx, plan); err != nil {
+			return fmt.Errorf("failed to update fee plan: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fs.log.Infof("Updated fee plan %d (%s)", plan.ID, plan.Name)
+	return plan, nil
+}
+
+// scopePlans loads the plans limited to exactly the symbol and event type of plan
+func (fs *FeePlanService) scopePlans(ctx context.Context, plan *models.FeePlan) ([]*models.FeePlan, error) {
+	var stockSymbol, eventType string
+	if plan.StockSymbol != nil {
+		stockSymbol = *plan.StockSymbol
+	}
+	if plan.EventType != nil {
+		eventType = *plan.EventType
+	}
+	plans, err := fs.feePlanRepo.List(ctx, stockSymbol, eventType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fee plans: %w", err)
+	}
+
+	scoped := make([]*models.FeePlan, 0, len(plans))
+	for _, other := range plans {
+		if sameValue(other.StockSymbol, plan.StockSymbol) && sameValue(other.EventType, plan.EventType) {
+			scoped = append(scoped, other)
+		}
+	}
+	return scoped, nil
+}
+
+// validateFeePlan checks a plan's name, scope and dates and validates its
+// charges in order, upper-casing codes and filling in known accounts
+func validateFeePlan(plan *models.FeePlan) error {
+	if plan.Name == "" || len(plan.Name) > maxFeePlanNameLength {
+		return fmt.Errorf("name is required, at most %d characters", maxFeePlanNameLength)
+	}
+	if plan.StockSymbol != nil && len(*plan.StockSymbol) > maxFeePlanSymbolLength {
+		return fmt.Errorf("stock_symbol must be at most %d characters", maxFeePlanSymbolLength)
+	}
+	if plan.EventType != nil && (!codePattern.MatchString(*plan.EventType) || len(*plan.EventType) > maxEventTypeCodeLength) {
+		return fmt.Errorf("event_type must be an event type code")
+	}
+	if plan.EffectiveTo != nil && !plan.EffectiveTo.After(plan.EffectiveFrom) {
+		return fmt.Errorf("effective_to must be after effective_from")
+	}
+	if len(plan.Charges) == 0 {
+		return fmt.Errorf("at least one charge is required")
+	}
+
+	seen := make(map[string]bool, len(plan.Charges))
+	for i := range plan.Charges {
+		charge := &plan.Charges[i]
+		if err := validateFeePlanCharge(charge, seen); err != nil {
+			return fmt.Errorf("charges[%d]: %w", i, err)
+		}
+		seen[charge.Code] = true
+	}
+	return nil
+}
+
+// validateFeePlanCharge checks one charge of a plan; seen holds the codes of
+// the charges before it, the only ones it can be levied on
+func validateFeePlanCharge(charge *models.FeePlanCharge, seen map[string]bool) error {
+	charge.Code = strings.ToUpper(strings.TrimSpace(charge.Code))
+	if !codePattern.MatchString(charge.Code) || len(charge.Code) > maxChargeCodeLength {
+		return fmt.Errorf("code must be at most %d letters, digits or underscores, starting with a letter", maxChargeCodeLength)
+	}
+	if seen[charge.Code] {
+		return fmt.Errorf("code %s is repeated", charge.Code)
+	}
+
+	charge.Account = strings.ToUpper(strings.TrimSpace(charge.Account))
+	if charge.Account == "" {
+		charge.Account = chargeAccounts[charge.Code]
+	}
+	if err := validateAccount("account", charge.Account); err != nil {
+		return err
+	}
+	if charge.Account == "CASH" {
+		return fmt.Errorf("account cannot be CASH, which pays the charge")
+	}
+
+	charge.Side = strings.ToUpper(charge.Side)
+	if charge.Side != "" && charge.Side != TradeSideBuy && charge.Side != TradeSideSell {
+		return fmt.Errorf("side must be %s, %s or empty for both", TradeSideBuy, TradeSideSell)
+	}
+
+	if charge.Percent.IsNegative() || charge.FlatINR.IsNegative() {
+		return fmt.Errorf("percent and flat_inr cannot be negative")
+	}
+	if err := normalizePlaces("flat_inr", &charge.FlatINR, models.AmountScale); err != nil {
+		return err
+	}
+	if charge.MinINR != nil {
+		if charge.MinINR.IsNegative() {
+			return fmt.Errorf("min_inr cannot be negative")
+		}
+		if err := normalizePlaces("min_inr", charge.MinINR, models.AmountScale); err != nil {
+			return err
+		}
+	}
+	if charge.MaxINR != nil {
+		if charge.MaxINR.IsNegative() {
+			return fmt.Errorf("max_inr cannot be negative")
+		}
+		if err := normalizePlaces("max_inr", charge.MaxINR, models.AmountScale); err != nil {
+			return err
+		}
+		if charge.MinINR != nil && charge.MinINR.GreaterThan(*charge.MaxINR) {
+			return fmt.Errorf("min_inr cannot exceed max_inr")
+		}
+	}
+
+	for i, code := range charge.LeviedOn {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !seen[code] {
+			return fmt.Errorf("levied_on names %s, which is not an earlier charge of the plan", code)
+		}
+		charge.LeviedOn[i] = code
+	}
+	return nil
+}
+
+// feePlanAt loads the fee plan in force for a reward of stockSymbol and
+// eventType at the given time; nil when none is and CHARGES_PLAN applies
+func feePlanAt(ctx context.Context, feePlanRepo repository.FeePlanRepository, stockSymbol, eventType string, at time.Time) (*models.FeePlan, error) {
+	plans, err := feePlanRepo.List(ctx, stockSymbol, eventType, &at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fee plans: %w", err)
+	}
+	return resolveFeePlan(plans, stockSymbol, eventType, at), nil
+}
+
+// resolveFeePlan picks the most specific of plans in force at the given
+// time: one limited to both the symbol and the event type, then to the
+// symbol, then to the event type, then one for every reward. Plans of the
+// same scope never overlap, so at most one of each is in force.
+func resolveFeePlan(plans []*models.FeePlan, stockSymbol, eventType string, at time.Time) *models.FeePlan {
+	var resolved *models.FeePlan
+	best := -1
+	for _, plan := range plans {
+		if plan.EffectiveFrom.After(at) || (plan.EffectiveTo != nil && !plan.EffectiveTo.After(at)) {
+			continue
+		}
+		rank := 0
+		if plan.StockSymbol != nil {
+			if *plan.StockSymbol != stockSymbol {
+				continue
+			}
+			rank += 2
+		}
+		if plan.EventType != nil {
+			if *plan.EventType != eventType {
+				continue
+			}
+			rank++
+		}
+		if rank > best {
+			resolved, best = plan, rank
+		}
+	}
+	return resolved
+}
+
+// plansOverlap reports whether two plans are in force at a common time
+func plansOverlap(a, b *models.FeePlan) bool {
+	aEndsFirst := a.EffectiveTo != nil && !a.EffectiveTo.After(b.EffectiveFrom)
+	bEndsFirst := b.EffectiveTo != nil && !b.EffectiveTo.After(a.EffectiveFrom)
+	return !aEndsFirst && !bEndsFirst
+}
+
+// sameValue reports whether two optional values are both unset or equal
+func sameValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
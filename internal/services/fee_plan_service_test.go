@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"stockBackend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestResolveFeePlan(t *testing.T) {
+	symbol, eventType, other := "TCS", "REFERRAL", "INFY"
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jul := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	general := &models.FeePlan{ID: 1, EffectiveFrom: jan}
+	byEvent := &models.FeePlan{ID: 2, EventType: &eventType, EffectiveFrom: jan}
+	bySymbol := &models.FeePlan{ID: 3, StockSymbol: &symbol, EffectiveFrom: jan}
+	both := &models.FeePlan{ID: 4, StockSymbol: &symbol, EventType: &eventType, EffectiveFrom: jan, EffectiveTo: &jul}
+	otherSymbol := &models.FeePlan{ID: 5, StockSymbol: &other, EventType: &eventType, EffectiveFrom: jan}
+	later := &models.FeePlan{ID: 6, StockSymbol: &symbol, EventType: &eventType, EffectiveFrom: jul}
+	plans := []*models.FeePlan{general, byEvent, bySymbol, both, otherSymbol, later}
+
+	tests := []struct {
+		name      string
+		plans     []*models.FeePlan
+		symbol    string
+		eventType string
+		at        time.Time
+		want      *models.FeePlan
+	}{
+		{"symbol and event type", plans, symbol, eventType, jan.AddDate(0, 1, 0), both},
+		{"end is exclusive", plans, symbol, eventType, jul, later},
+		{"symbol over event type", plans, symbol, "BONUS", jan.AddDate(0, 1, 0), bySymbol},
+		{"event type", plans, "WIPRO", eventType, jan.AddDate(0, 1, 0), byEvent},
+		{"every reward", plans, "WIPRO", "BONUS", jan.AddDate(0, 1, 0), general},
+		{"start is inclusive", plans, "WIPRO", "BONUS", jan, general},
+		{"none in force yet", plans, symbol, eventType, jan.AddDate(0, 0, -1), nil},
+		{"only other scopes", []*models.FeePlan{otherSymbol, both}, "WIPRO", eventType, jan.AddDate(0, 1, 0), nil},
+		{"no plans", nil, symbol, eventType, jan, nil},
+	}
+	for _, tt := range tests {
+		got := resolveFeePlan(tt.plans, tt.symbol, tt.eventType, tt.at)
+		if got != tt.want {
+			t.Errorf("%s: resolved %s, want %s", tt.name, planName(got), planName(tt.want))
+		}
+	}
+}
+
+// planName names a plan in failure messages
+func planName(plan *models.FeePlan) string {
+	if plan == nil {
+		return "no plan"
+	}
+	return fmt.Sprintf("plan %d", plan.ID)
+}
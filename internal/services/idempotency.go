@@ -0,0 +1,60 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"stockBackend/internal/models"
+	"time"
+)
+
+// canonicalRewardRequest holds the fields that decide what a reward request does.
+// Defaults are applied and timestamps normalized so that equivalent payloads
+// produce the same fingerprint. Quantity and AmountINR stay floats so that
+// fingerprints stored before they became decimals still match; at 6 and 2
+// places a float64 tells any two valid values apart.
+type canonicalRewardRequest struct {
+	UserID         string  `json:"user_id"`
+	StockSymbol    string  `json:"stock_symbol"`
+	Quantity       float64 `json:"quantity"`
+	EventType      string  `json:"event_type"`
+	EventTimestamp string  `json:"event_timestamp"`
+	Notes          string  `json:"notes"`
+	// Omitted when unset so quantity-based fingerprints are unchanged
+	AmountINR     float64 `json:"amount_inr,omitempty"`
+	DeductFees    bool    `json:"deduct_fees,omitempty"`
+	AllowNegative bool    `json:"allow_negative,omitempty"`
+	CampaignID    *int    `json:"campaign_id,omitempty"`
+	// Vesting dates are normalized to UTC by validateRequest
+	Vesting  *models.VestingSchedule `json:"vesting,omitempty"`
+	QuoteID  string                  `json:"quote_id,omitempty"`
+	BasketID *int                    `json:"basket_id,omitempty"`
+}
+
+// fingerprintRequest returns the SHA-256 of the canonical form of a request
+func fingerprintRequest(req *RewardRequest) string {
+	canonical := canonicalRewardRequest{
+		UserID:        req.UserID,
+		StockSymbol:   req.StockSymbol,
+		Quantity:      req.Quantity.Float64(),
+		EventType:     req.EventType,
+		Notes:         req.Notes,
+		AmountINR:     req.AmountINR.Float64(),
+		DeductFees:    req.DeductFees,
+		AllowNegative: req.AllowNegative,
+		CampaignID:    req.CampaignID,
+		Vesting:       req.Vesting,
+		QuoteID:       req.QuoteID,
+		BasketID:      req.BasketID,
+	}
+	if canonical.EventType == "" {
+		canonical.EventType = "REWARD"
+	}
+	if !req.EventTimestamp.IsZero() {
+		canonical.EventTimestamp = req.EventTimestamp.UTC().Format(time.RFC3339Nano)
+	}
+
+	payload, _ := json.Marshal(canonical)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
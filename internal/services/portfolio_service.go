@@ -12,21 +12,27 @@ import (
 
 // PortfolioService handles portfolio and analytics operations
 type PortfolioService struct {
-	portfolioRepo repository.PortfolioRepository
-	rewardRepo    repository.RewardRepository
-	log           *logrus.Logger
+	portfolioRepo  repository.PortfolioRepository
+	rewardRepo     repository.RewardRepository
+	vestingRepo    repository.VestingRepository
+	redemptionRepo repository.RedemptionRepository
+	log            *logrus.Logger
 }
 
 // NewPortfolioService creates a new portfolio service
 func NewPortfolioService(
 	portfolioRepo repository.PortfolioRepository,
 	rewardRepo repository.RewardRepository,
+	vestingRepo repository.VestingRepository,
+	redemptionRepo repository.RedemptionRepository,
 	log *logrus.Logger,
 ) *PortfolioService {
 	return &PortfolioService{
-		portfolioRepo: portfolioRepo,
-		rewardRepo:    rewardRepo,
-		log:           log,
+		portfolioRepo:  portfolioRepo,
+		rewardRepo:     rewardRepo,
+		vestingRepo:    vestingRepo,
+		redemptionRepo: redemptionRepo,
+		log:            log,
 	}
 }
 
@@ -71,6 +77,23 @@ func (ps *PortfolioService) GetUserStats(ctx context.Context, userID string) (*m
 		return nil, fmt.Errorf("failed to get user stats: %w", err)
 	}
 
+	unvested, err := ps.vestingRepo.GetUnvestedQuantities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unvested quantities: %w", err)
+	}
+	for _, quantity := range unvested {
+		stats.UnvestedQuantity = stats.UnvestedQuantity.Add(quantity)
+	}
+	stats.VestedQuantity = stats.TotalStocksQuantity.Sub(stats.UnvestedQuantity)
+
+	redemptions, err := ps.redemptionRepo.GetUserTotals(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redemption totals: %w", err)
+	}
+	stats.RedemptionPayoutINR = redemptions.PayoutINR
+	stats.PendingPayoutINR = redemptions.PendingPayoutINR
+	stats.RealizedPnLINR = redemptions.RealizedPnLINR
+
 	return stats, nil
 }
 
@@ -83,6 +106,15 @@ func (ps *PortfolioService) GetUserPortfolio(ctx context.Context, userID string)
 		return nil, fmt.Errorf("failed to get portfolio: %w", err)
 	}
 
+	unvested, err := ps.vestingRepo.GetUnvestedQuantities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unvested quantities: %w", err)
+	}
+	for _, holding := range portfolio {
+		holding.UnvestedQuantity = unvested[holding.StockSymbol]
+		holding.VestedQuantity = holding.TotalQuantity.Sub(holding.UnvestedQuantity)
+	}
+
 	return portfolio, nil
 }
 
@@ -2,32 +2,45 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"os"
 	"stockBackend/internal/models"
 	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
 	"strconv"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 )
 
+// ErrPriceUnavailable is returned when no price was recorded close enough to
+// an event; retrying the same event will not find one
+var ErrPriceUnavailable = newError(KindValidation, "PRICE_UNAVAILABLE", "no stock price available at event time").
+	withStatus(http.StatusUnprocessableEntity)
+
 // PriceService handles stock price updates
 type PriceService struct {
-	priceRepo repository.StockPriceRepository
-	log       *logrus.Logger
-	cron      *cron.Cron
-	minPrice  float64
-	maxPrice  float64
-	stocks    []string
+	priceRepo      repository.StockPriceRepository
+	log            *logrus.Logger
+	cron           *cron.Cron
+	cronEntry      cron.EntryID
+	minPrice       float64
+	maxPrice       float64
+	priceTolerance time.Duration
+	stocks         []string
 }
 
-// NewPriceService creates a new price service
-func NewPriceService(priceRepo repository.StockPriceRepository, log *logrus.Logger) *PriceService {
+// NewPriceService creates a new price service whose updates run on scheduler
+func NewPriceService(priceRepo repository.StockPriceRepository, scheduler *cron.Cron, log *logrus.Logger) *PriceService {
 	minPrice := 100.0
 	maxPrice := 5000.0
+	// Prices are refreshed hourly by default, so allow a missed update
+	priceTolerance := 120 * time.Minute
 
 	if min := os.Getenv("MOCK_PRICE_MIN"); min != "" {
 		if val, err := strconv.ParseFloat(min, 64); err == nil {
@@ -39,13 +52,19 @@ func NewPriceService(priceRepo repository.StockPriceRepository, log *logrus.Logg
 			maxPrice = val
 		}
 	}
+	if tolerance := os.Getenv("PRICE_AT_TOLERANCE_MINUTES"); tolerance != "" {
+		if val, err := strconv.Atoi(tolerance); err == nil && val >= 0 {
+			priceTolerance = time.Duration(val) * time.Minute
+		}
+	}
 
 	return &PriceService{
-		priceRepo: priceRepo,
-		log:       log,
-		cron:      cron.New(),
-		minPrice:  minPrice,
-		maxPrice:  maxPrice,
+		priceRepo:      priceRepo,
+		log:            log,
+		cron:           scheduler,
+		minPrice:       minPrice,
+		maxPrice:       maxPrice,
+		priceTolerance: priceTolerance,
 		stocks: []string{
 			"AAPL", "GOOGL", "MSFT", "TSLA", "AMZN",
 			"META", "NVDA", "NFLX", "AMD", "INTC",
@@ -53,7 +72,7 @@ func NewPriceService(priceRepo repository.StockPriceRepository, log *logrus.Logg
 	}
 }
 
-// Start begins the scheduled price updates
+// Start adds the price updates to the scheduler and runs a first update
 func (s *PriceService) Start() error {
 	// Get interval from environment (default 1 hour)
 	interval := "1h"
@@ -69,7 +88,7 @@ func (s *PriceService) Start() error {
 		cronExpr = fmt.Sprintf("@every %s", interval)
 	}
 
-	_, err := s.cron.AddFunc(cronExpr, func() {
+	entry, err := s.cron.AddFunc(cronExpr, func() {
 		ctx := context.Background()
 		if err := s.UpdatePrices(ctx); err != nil {
 			s.log.Errorf("Failed to update prices: %v", err)
@@ -79,8 +98,8 @@ func (s *PriceService) Start() error {
 	if err != nil {
 		return fmt.Errorf("failed to schedule price updates: %w", err)
 	}
+	s.cronEntry = entry
 
-	s.cron.Start()
 	s.log.Infof("Price service started with interval: %s", interval)
 
 	// Run initial update
@@ -94,10 +113,10 @@ func (s *PriceService) Start() error {
 	return nil
 }
 
-// Stop stops the price service
+// Stop removes the price updates from the scheduler
 func (s *PriceService) Stop() {
 	if s.cron != nil {
-		s.cron.Stop()
+		s.cron.Remove(s.cronEntry)
 		s.log.Info("Price service stopped")
 	}
 }
@@ -147,7 +166,7 @@ func (s *PriceService) UpdateSinglePrice(ctx context.Context, symbol string) (*m
 		return nil, fmt.Errorf("failed to save price: %w", err)
 	}
 
-	s.log.Infof("Updated price for %s: %.2f INR", symbol, price.Price)
+	s.log.Infof("Updated price for %s: %s INR", symbol, price.Price)
 	return price, nil
 }
 
@@ -181,13 +200,79 @@ func (s *PriceService) GetLatestPrices(ctx context.Context, symbols []string) (m
 	return prices, nil
 }
 
+// GetPriceAt retrieves the price a reward for an event at the given time is valued at:
+// the latest price recorded at or before that time, if it is no older than the
+// configured tolerance. A zero time means "now". Recent events with no usable
+// price fall back to GetLatestPrice, which generates one if needed.
+func (s *PriceService) GetPriceAt(ctx context.Context, symbol string, at time.Time) (*models.StockPrice, error) {
+	if at.IsZero() {
+		return s.GetLatestPrice(ctx, symbol)
+	}
+
+	price, err := s.priceRepo.GetAtOrBefore(ctx, symbol, at)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil && s.withinTolerance(price, at) {
+		return price, nil
+	}
+	if s.isRecent(at) {
+		return s.GetLatestPrice(ctx, symbol)
+	}
+	return nil, fmt.Errorf("%w: %s at %s", ErrPriceUnavailable, symbol, at.Format(time.RFC3339))
+}
+
+// GetPricesAt is the batch form of GetPriceAt. The result is aligned with the
+// input; a pair with no usable price gets nil.
+func (s *PriceService) GetPricesAt(ctx context.Context, symbols []string, ats []time.Time) ([]*models.StockPrice, error) {
+	prices, err := s.priceRepo.GetAtOrBeforeBatch(ctx, symbols, ats)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, price := range prices {
+		if price != nil && s.withinTolerance(price, ats[i]) {
+			continue
+		}
+		prices[i] = nil
+		if s.isRecent(ats[i]) {
+			if latest, err := s.GetLatestPrice(ctx, symbols[i]); err == nil {
+				prices[i] = latest
+			}
+		}
+	}
+
+	return prices, nil
+}
+
+// withinTolerance reports whether price is recent enough to value an event at
+func (s *PriceService) withinTolerance(price *models.StockPrice, at time.Time) bool {
+	return at.Sub(price.Timestamp) <= s.priceTolerance
+}
+
+// isRecent reports whether an event is close enough to now, before or after,
+// to use the latest price
+func (s *PriceService) isRecent(at time.Time) bool {
+	age := time.Since(at)
+	return age <= s.priceTolerance && age >= -s.priceTolerance
+}
+
+// checkEventTime rejects an event more than the price tolerance in the
+// future, which no recorded price can value; a zero time means "now"
+func (s *PriceService) checkEventTime(at time.Time) error {
+	if !at.IsZero() && time.Until(at) > s.priceTolerance {
+		return fmt.Errorf("event_timestamp must not be more than %s in the future", s.priceTolerance)
+	}
+	return nil
+}
+
 // GetPriceHistory retrieves price history for a stock
 func (s *PriceService) GetPriceHistory(ctx context.Context, symbol string, limit int) ([]*models.StockPrice, error) {
 	return s.priceRepo.GetHistory(ctx, symbol, limit)
 }
 
 // generateMockPrice generates a random price with some volatility
-func (s *PriceService) generateMockPrice(symbol string) float64 {
+func (s *PriceService) generateMockPrice(symbol string) decimal.Decimal {
 	// Use symbol as seed for some consistency
 	seed := int64(0)
 	for _, c := range symbol {
@@ -202,10 +287,8 @@ func (s *PriceService) generateMockPrice(symbol string) float64 {
 	// Generate price in range with 2 decimal precision
 	price := s.minPrice + r.Float64()*(s.maxPrice-s.minPrice)
 	
-	// Round to 2 decimal places
-	price = float64(int(price*100)) / 100
-	
-	return price
+	// Round down to 2 decimal places, written with the 4 a stored price has
+	return decimal.NewFromFloat(price).Round(2, decimal.RoundDown).Round(models.PriceScale, decimal.RoundDown)
 }
 
 // GetSupportedStocks returns list of supported stock symbols
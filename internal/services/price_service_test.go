@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventTimeTolerance(t *testing.T) {
+	s := &PriceService{priceTolerance: 2 * time.Hour}
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		recent bool
+		valid  bool
+	}{
+		{"now", now, true, true},
+		{"an hour ago", now.Add(-time.Hour), true, true},
+		{"a day ago", now.AddDate(0, 0, -1), false, true},
+		{"in an hour", now.Add(time.Hour), true, true},
+		// A future event cannot be valued at today's price
+		{"next year", now.AddDate(1, 0, 0), false, false},
+		{"omitted", time.Time{}, false, true},
+	}
+	for _, tt := range tests {
+		if tt.at.IsZero() {
+			if err := s.checkEventTime(tt.at); err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if got := s.isRecent(tt.at); got != tt.recent {
+			t.Errorf("%s: isRecent = %t, want %t", tt.name, got, tt.recent)
+		}
+		if err := s.checkEventTime(tt.at); (err == nil) != tt.valid {
+			t.Errorf("%s: checkEventTime error %v, want valid %t", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// Recovery decisions, also used as metric keys
+const (
+	RecoveryFinalized = "finalized"
+	RecoveryRetried   = "retried"
+	RecoveryFailed    = "failed"
+	RecoverySkipped   = "skipped"
+	RecoveryErrors    = "errors"
+)
+
+// Recovery actions for requests that never produced a reward
+const (
+	RecoveryActionRetry = "retry"
+	RecoveryActionFail  = "fail"
+)
+
+// recoveryMetrics counts recovery decisions; published at /debug/vars
+var recoveryMetrics = expvar.NewMap("reward_recovery")
+
+// RecoveryService finalizes reward requests left in PROCESSING by a crash
+type RecoveryService struct {
+	rewardService     *RewardService
+	rewardRequestRepo repository.RewardRequestRepository
+	rewardRepo        repository.RewardRepository
+	ledgerRepo        repository.LedgerRepository
+	log               *logrus.Logger
+	cron              *cron.Cron
+	cronEntry         cron.EntryID
+	interval          time.Duration
+	minAge            time.Duration
+	batchSize         int
+	action            string
+}
+
+// NewRecoveryService creates a new recovery service whose runs are added to scheduler
+func NewRecoveryService(
+	rewardService *RewardService,
+	rewardRequestRepo repository.RewardRequestRepository,
+	rewardRepo repository.RewardRepository,
+	ledgerRepo repository.LedgerRepository,
+	scheduler *cron.Cron,
+	log *logrus.Logger,
+) *RecoveryService {
+	interval := 5 * time.Minute
+	minAge := 15 * time.Minute
+	batchSize := 100
+	action := RecoveryActionRetry
+
+	if v := os.Getenv("REWARD_RECOVERY_INTERVAL_MINUTES"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val > 0 {
+			interval = time.Duration(val) * time.Minute
+		}
+	}
+	if v := os.Getenv("REWARD_RECOVERY_MIN_AGE_MINUTES"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val > 0 {
+			minAge = time.Duration(val) * time.Minute
+		}
+	}
+	if v := os.Getenv("REWARD_RECOVERY_BATCH_SIZE"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val > 0 {
+			batchSize = val
+		}
+	}
+	if v := os.Getenv("REWARD_RECOVERY_ACTION"); v == RecoveryActionFail {
+		action = v
+	}
+
+	return &RecoveryService{
+		rewardService:     rewardService,
+		rewardRequestRepo: rewardRequestRepo,
+		rewardRepo:        rewardRepo,
+		ledgerRepo:        ledgerRepo,
+		log:               log,
+		cron:              scheduler,
+		interval:          interval,
+		minAge:            minAge,
+		batchSize:         batchSize,
+		action:            action,
+	}
+}
+
+// Start adds the recovery runs to the scheduler
+func (s *RecoveryService) Start() error {
+	entry, err := s.cron.AddFunc(fmt.Sprintf("@every %s", s.interval), func() {
+		ctx := context.Background()
+		if err := s.RecoverStuckRequests(ctx); err != nil {
+			s.log.Errorf("Failed to recover stuck reward requests: %v", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule reward recovery: %w", err)
+	}
+	s.cronEntry = entry
+
+	s.log.Infof("Reward recovery started with interval %s, min age %s, action %s", s.interval, s.minAge, s.action)
+	return nil
+}
+
+// Stop removes the recovery runs from the scheduler
+func (s *RecoveryService) Stop() {
+	if s.cron != nil {
+		s.cron.Remove(s.cronEntry)
+		s.log.Info("Reward recovery stopped")
+	}
+}
+
+// RecoverStuckRequests resolves every PROCESSING request older than the
+// configured age. A request whose reward exists is finalized from that
+// reward; otherwise it is re-run or failed depending on the configured action.
+func (s *RecoveryService) RecoverStuckRequests(ctx context.Context) error {
+	pending, err := s.rewardRequestRepo.GetPending(ctx, s.batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to list pending requests: %w", err)
+	}
+
+	cutoff := time.Now().Add(-s.minAge)
+	for _, request := range pending {
+		// GetPending is ordered oldest first, so the rest are too young
+		if request.CreatedAt.After(cutoff) {
+			break
+		}
+
+		decision, err := s.recoverRequest(ctx, request)
+		entry := s.log.WithFields(logrus.Fields{
+			"event_id":   request.EventID,
+			"user_id":    request.UserID,
+			"created_at": request.CreatedAt,
+			"decision":   decision,
+		})
+		if err != nil {
+			recoveryMetrics.Add(RecoveryErrors, 1)
+			entry.Errorf("Reward request recovery failed: %v", err)
+			continue
+		}
+		recoveryMetrics.Add(decision, 1)
+		entry.Info("Reward request recovered")
+	}
+
+	return nil
+}
+
+// recoverRequest resolves one stuck request and returns the decision taken
+func (s *RecoveryService) recoverRequest(ctx context.Context, request *models.RewardRequest) (string, error) {
+	// The stored payload never changes, so it can be read before the record
+	// is locked; an unreadable one is marked failed below
+	var req RewardRequest
+	payloadErr := json.Unmarshal([]byte(request.RequestPayload), &req)
+
+	decision := RecoverySkipped
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		// A re-run takes the same locks as the original request, in the
+		// same order: before the idempotency record
+		if s.action == RecoveryActionRetry && payloadErr == nil {
+			if err := s.rewardService.lockRequest(ctx, &req); err != nil {
+				return err
+			}
+		}
+
+		// Another worker or a late original request may own it already
+		locked, err := s.rewardRequestRepo.LockPending(ctx, request.EventID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		// A request claimed again since it was listed was not covered by
+		// the locks taken above; leave it to its new owner
+		if locked.RequestPayload != request.RequestPayload {
+			return nil
+		}
+
+		reward, err := s.rewardRepo.GetByEventID(ctx, locked.EventID)
+		if err == nil {
+			decision = RecoveryFinalized
+			return s.finalize(ctx, reward)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to look up reward: %w", err)
+		}
+
+		if s.action == RecoveryActionFail {
+			decision = RecoveryFailed
+			return s.rewardRequestRepo.MarkFailed(ctx, locked.EventID, FailureCodeStuck, "abandoned while processing")
+		}
+
+		if payloadErr != nil {
+			decision = RecoveryFailed
+			return s.rewardRequestRepo.MarkFailed(ctx, locked.EventID, FailureCodeInvalidPayload, fmt.Sprintf("unreadable request payload: %v", payloadErr))
+		}
+
+		decision = RecoveryRetried
+		_, err = s.rewardService.executeRewardTx(ctx, &req)
+		return err
+	})
+
+	// A failed re-run must not stay PROCESSING, or it would be retried forever
+	if err != nil && decision == RecoveryRetried {
+		if markErr := s.rewardRequestRepo.MarkFailed(ctx, request.EventID, failureCode(err), err.Error()); markErr != nil {
+			return decision, fmt.Errorf("retry failed: %v; marking failed: %w", err, markErr)
+		}
+		return RecoveryFailed, nil
+	}
+	return decision, err
+}
+
+// finalize completes a request whose reward was written but never marked
+// processed, adding ledger entries if the reward is missing them
+func (s *RecoveryService) finalize(ctx context.Context, reward *models.Reward) error {
+	entries, err := s.ledgerRepo.GetByRewardID(ctx, reward.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load ledger entries: %w", err)
+	}
+	// A reward awaiting approval is posted to the ledger when approved
+	if len(entries) == 0 && models.IsSettledReward(reward.Status) {
+		if err := s.rewardService.createLedgerEntries(ctx, reward); err != nil {
+			return fmt.Errorf("failed to create ledger entries: %w", err)
+		}
+	}
+
+	response := newRewardResponse(reward, processedMessage(reward))
+	payload, _ := json.Marshal(response)
+	return s.rewardRequestRepo.MarkProcessed(ctx, reward.EventID, string(payload))
+}
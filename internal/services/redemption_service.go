@@ -0,0 +1,496 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// EventTypeRedemption is the event type of the negative reward an executed
+// redemption books
+const EventTypeRedemption = "REDEMPTION"
+
+// redemptionEventSuffix turns a redemption ID into the event ID of its reward
+const redemptionEventSuffix = "-REDEEM"
+
+// Ledger accounts a redemption posts to besides the stock asset and fees
+const (
+	// AccountRedemptionReceivable holds the INR owed to the user between
+	// execution and payment
+	AccountRedemptionReceivable = "REDEMPTION_RECEIVABLE"
+	// AccountRealizedPnL takes the difference between sale value and cost basis
+	AccountRealizedPnL = "REALIZED_PNL"
+)
+
+var (
+	// ErrRedemptionNotFound is returned when no redemption exists for a redemption ID
+	ErrRedemptionNotFound = newError(KindNotFound, "REDEMPTION_NOT_FOUND", "redemption not found")
+	// ErrRedemptionIDInUse is returned when a redemption ID was used for a different redemption
+	ErrRedemptionIDInUse = newError(KindConflict, "REDEMPTION_ID_IN_USE", "redemption_id already used by another redemption")
+	// ErrInvalidRedemption is returned when a redemption request is invalid
+	ErrInvalidRedemption = newError(KindValidation, "INVALID_REDEMPTION", "invalid redemption")
+	// ErrRedemptionStatus is returned when a redemption cannot move to the requested status
+	ErrRedemptionStatus = newError(KindConflict, "INVALID_REDEMPTION_STATUS", "invalid redemption status transition")
+)
+
+// RedemptionService sells users' rewarded stock back for INR
+type RedemptionService struct {
+	rewardRepo     repository.RewardRepository
+	ledgerRepo     repository.LedgerRepository
+	vestingRepo    repository.VestingRepository
+	chargeRepo     repository.RewardChargeRepository
+	redemptionRepo repository.RedemptionRepository
+	userRepo       repository.UserRepository
+	feePlanRepo    repository.FeePlanRepository
+	priceService   *PriceService
+	log            *logrus.Logger
+	charges        ChargesCalculator
+}
+
+// RedemptionRequest represents a user's request to sell a quantity of a symbol
+type RedemptionRequest struct {
+	RedemptionID string          `json:"redemption_id" binding:"required"`
+	UserID       string          `json:"user_id" binding:"required"`
+	StockSymbol  string          `json:"stock_symbol" binding:"required"`
+	Quantity     decimal.Decimal `json:"quantity"`
+}
+
+// RedemptionPaymentRequest records how an executed redemption was paid
+type RedemptionPaymentRequest struct {
+	PaymentReference string `json:"payment_reference" binding:"required"`
+}
+
+// NewRedemptionService creates a new redemption service. Sales are charged
+// by the fee plan in force for REDEMPTION events, or by CHARGES_PLAN like
+// rewards.
+func NewRedemptionService(
+	rewardRepo repository.RewardRepository,
+	ledgerRepo repository.LedgerRepository,
+	vestingRepo repository.VestingRepository,
+	chargeRepo repository.RewardChargeRepository,
+	redemptionRepo repository.RedemptionRepository,
+	userRepo repository.UserRepository,
+	feePlanRepo repository.FeePlanRepository,
+	priceService *PriceService,
+	log *logrus.Logger,
+) *RedemptionService {
+	return &RedemptionService{
+		rewardRepo:     rewardRepo,
+		ledgerRepo:     ledgerRepo,
+		vestingRepo:    vestingRepo,
+		chargeRepo:     chargeRepo,
+		redemptionRepo: redemptionRepo,
+		userRepo:       userRepo,
+		feePlanRepo:    feePlanRepo,
+		priceService:   priceService,
+		log:            log,
+		charges:        chargesFromEnv(),
+	}
+}
+
+// RequestRedemption records a REQUESTED redemption after checking that the
+// quantity is available: held, vested and not already requested by another
+// redemption. Repeating a call with the same redemption ID returns the stored
+// redemption.
+func (rs *RedemptionService) RequestRedemption(ctx context.Context, req *RedemptionRequest) (*models.Redemption, error) {
+	req.StockSymbol = strings.ToUpper(req.StockSymbol)
+	if err := validateRedemption(req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRedemption, err)
+	}
+
+	exists, err := rs.userRepo.Exists(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, req.UserID)
+	}
+
+	redemption := &models.Redemption{
+		RedemptionID: req.RedemptionID,
+		UserID:       req.UserID,
+		StockSymbol:  req.StockSymbol,
+		Quantity:     req.Quantity,
+		Status:       models.RedemptionStatusRequested,
+	}
+
+	replayed := false
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := rs.rewardRepo.LockHolding(ctx, req.UserID, req.StockSymbol); err != nil {
+			return err
+		}
+
+		claimed, err := rs.redemptionRepo.Claim(ctx, redemption)
+		if err != nil {
+			return fmt.Errorf("failed to record redemption: %w", err)
+		}
+		if !claimed {
+			existing, err := rs.redemptionRepo.GetByRedemptionID(ctx, req.RedemptionID)
+			if err != nil {
+				return fmt.Errorf("failed to load redemption: %w", err)
+			}
+			if existing.UserID != req.UserID || existing.StockSymbol != req.StockSymbol ||
+				!existing.Quantity.Equal(req.Quantity) {
+				return ErrRedemptionIDInUse
+			}
+			redemption = existing
+			replayed = true
+			return nil
+		}
+
+		return rs.checkAvailable(ctx, redemption)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !replayed {
+		rs.log.Infof("Redemption %s requested for %s %s by %s",
+			redemption.RedemptionID, redemption.Quantity, redemption.StockSymbol, redemption.UserID)
+	}
+	return redemption, nil
+}
+
+// ExecuteRedemption sells a REQUESTED redemption at the current price. The
+// negative REDEMPTION reward, its ledger entries and the EXECUTED status
+// commit together; the INR stays receivable until the redemption is paid.
+func (rs *RedemptionService) ExecuteRedemption(ctx context.Context, redemptionID string) (*models.Redemption, error) {
+	redemption, err := rs.GetRedemption(ctx, redemptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		// The holding is locked before the redemption row, the order
+		// requesting a redemption takes them in
+		if err := rs.rewardRepo.LockHolding(ctx, redemption.UserID, redemption.StockSymbol); err != nil {
+			return err
+		}
+		locked, err := rs.redemptionRepo.LockByRedemptionID(ctx, redemptionID)
+		if err != nil {
+			return fmt.Errorf("failed to lock redemption: %w", err)
+		}
+		redemption = locked
+
+		if !models.CanTransitionRedemption(redemption.Status, models.RedemptionStatusExecuted) {
+			return fmt.Errorf("%w: %s to %s", ErrRedemptionStatus, redemption.Status, models.RedemptionStatusExecuted)
+		}
+		if err := rs.checkAvailable(ctx, redemption); err != nil {
+			return err
+		}
+		return rs.bookRedemption(ctx, redemption)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rs.log.Infof("Redemption %s executed: %s %s for %s INR net (realized P&L %s INR)",
+		redemption.RedemptionID, redemption.Quantity, redemption.StockSymbol,
+		*redemption.NetPayoutINR, *redemption.RealizedPnLINR)
+	return redemption, nil
+}
+
+// PayRedemption marks an EXECUTED redemption as PAID and moves its net payout
+// from the receivable to cash
+func (rs *RedemptionService) PayRedemption(ctx context.Context, redemptionID string, req *RedemptionPaymentRequest) (*models.Redemption, error) {
+	if len(req.PaymentReference) > 100 {
+		return nil, fmt.Errorf("%w: payment_reference must be at most 100 characters", ErrInvalidRedemption)
+	}
+
+	var redemption *models.Redemption
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		locked, err := rs.lockRedemption(ctx, redemptionID)
+		if err != nil {
+			return err
+		}
+		redemption = locked
+
+		if !models.CanTransitionRedemption(redemption.Status, models.RedemptionStatusPaid) {
+			return fmt.Errorf("%w: %s to %s", ErrRedemptionStatus, redemption.Status, models.RedemptionStatusPaid)
+		}
+
+		now := time.Now()
+		redemption.Status = models.RedemptionStatusPaid
+		redemption.PaidAt = &now
+		redemption.PaymentReference = &req.PaymentReference
+		if err := rs.ledgerRepo.BulkCreate(ctx, redemptionPaymentEntries(redemption)); err != nil {
+			return fmt.Errorf("failed to create payment ledger entries: %w", err)
+		}
+		if err := rs.redemptionRepo.Update(ctx, redemption); err != nil {
+			return fmt.Errorf("failed to update redemption: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rs.log.Infof("Redemption %s paid: %s INR to %s", redemption.RedemptionID, *redemption.NetPayoutINR, redemption.UserID)
+	return redemption, nil
+}
+
+// CancelRedemption cancels a REQUESTED redemption, releasing its quantity
+func (rs *RedemptionService) CancelRedemption(ctx context.Context, redemptionID string) (*models.Redemption, error) {
+	var redemption *models.Redemption
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		locked, err := rs.lockRedemption(ctx, redemptionID)
+		if err != nil {
+			return err
+		}
+		redemption = locked
+
+		if !models.CanTransitionRedemption(redemption.Status, models.RedemptionStatusCancelled) {
+			return fmt.Errorf("%w: %s to %s", ErrRedemptionStatus, redemption.Status, models.RedemptionStatusCancelled)
+		}
+
+		now := time.Now()
+		redemption.Status = models.RedemptionStatusCancelled
+		redemption.CancelledAt = &now
+		if err := rs.redemptionRepo.Update(ctx, redemption); err != nil {
+			return fmt.Errorf("failed to update redemption: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rs.log.Infof("Redemption %s cancelled", redemption.RedemptionID)
+	return redemption, nil
+}
+
+// GetRedemption retrieves a redemption by its redemption ID, with the
+// charges of an executed one
+func (rs *RedemptionService) GetRedemption(ctx context.Context, redemptionID string) (*models.Redemption, error) {
+	redemption, err := rs.redemptionRepo.GetByRedemptionID(ctx, redemptionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRedemptionNotFound
+		}
+		return nil, err
+	}
+	if redemption.RewardID != nil {
+		if redemption.Charges, err = rs.chargeRepo.GetByRewardID(ctx, *redemption.RewardID); err != nil {
+			return nil, fmt.Errorf("failed to load redemption charges: %w", err)
+		}
+	}
+	return redemption, nil
+}
+
+// GetUserRedemptions lists a user's redemptions, newest first, optionally filtered by status
+func (rs *RedemptionService) GetUserRedemptions(ctx context.Context, userID, status string, limit, offset int) ([]*models.Redemption, error) {
+	return rs.redemptionRepo.GetByUserID(ctx, userID, strings.ToUpper(status), limit, offset)
+}
+
+// lockRedemption locks a redemption row for a status change; ctx must carry a transaction
+func (rs *RedemptionService) lockRedemption(ctx context.Context, redemptionID string) (*models.Redemption, error) {
+	redemption, err := rs.redemptionRepo.LockByRedemptionID(ctx, redemptionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRedemptionNotFound
+		}
+		return nil, fmt.Errorf("failed to lock redemption: %w", err)
+	}
+	return redemption, nil
+}
+
+// checkAvailable rejects a redemption larger than the user's holding net of
+// unvested stock and of other requested redemptions; ctx must carry the
+// transaction holding the user's lock on the symbol
+func (rs *RedemptionService) checkAvailable(ctx context.Context, redemption *models.Redemption) error {
+	held, err := rs.rewardRepo.GetNetQuantity(ctx, redemption.UserID, redemption.StockSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to get current holding: %w", err)
+	}
+	unvested, err := rs.vestingRepo.GetUnvestedQuantities(ctx, redemption.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load unvested quantities: %w", err)
+	}
+	requested, err := rs.redemptionRepo.GetRequestedQuantity(ctx, redemption.UserID, redemption.StockSymbol, redemption.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load requested redemptions: %w", err)
+	}
+
+	available := held.Sub(unvested[redemption.StockSymbol]).Sub(requested)
+	if redemption.Quantity.GreaterThan(available) {
+		return fmt.Errorf("%w: user %s has %s %s available to redeem (%s unvested, %s already requested), requested %s",
+			ErrInsufficientHoldings, redemption.UserID, decimal.Max(available, decimal.Zero), redemption.StockSymbol,
+			unvested[redemption.StockSymbol], requested, redemption.Quantity)
+	}
+	return nil
+}
+
+// bookRedemption prices a redemption at the current price, books its
+// negative reward and ledger entries and marks it EXECUTED; ctx must carry a
+// transaction
+func (rs *RedemptionService) bookRedemption(ctx context.Context, redemption *models.Redemption) error {
+	stockPrice, err := rs.priceService.GetPriceAt(ctx, redemption.StockSymbol, time.Time{})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPriceUnavailable, err)
+	}
+
+	executedAt := time.Now()
+	plan, err := feePlanAt(ctx, rs.feePlanRepo, redemption.StockSymbol, EventTypeRedemption, executedAt)
+	if err != nil {
+		return err
+	}
+	var feePlanID *int
+	if plan != nil {
+		feePlanID = &plan.ID
+	}
+
+	gross := roundINR(redemption.Quantity.Mul(stockPrice.Price))
+	charges := chargesForPlan(plan, rs.charges).Charges(TradeSideSell, gross)
+	brokerage, fee := chargeTotals(charges)
+	net := gross.Sub(brokerage).Sub(fee)
+	if !net.IsPositive() {
+		return fmt.Errorf("%w: %s %s is worth less than its fees", ErrInvalidRedemption, redemption.Quantity, redemption.StockSymbol)
+	}
+
+	// Cost basis is the average cost of everything that added to the holding
+	acquiredQuantity, acquiredValue, err := rs.rewardRepo.GetAcquisitionCost(ctx, redemption.UserID, redemption.StockSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to get cost basis: %w", err)
+	}
+	costBasis := decimal.Zero
+	if acquiredQuantity.IsPositive() {
+		costBasis = redemption.Quantity.Mul(acquiredValue).Div(acquiredQuantity, models.AmountScale, decimal.RoundHalfUp)
+	}
+	realizedPnL := net.Sub(costBasis)
+
+	notes := fmt.Sprintf("Redemption %s", redemption.RedemptionID)
+	reward := &models.Reward{
+		UserID:         redemption.UserID,
+		StockSymbol:    redemption.StockSymbol,
+		Quantity:       redemption.Quantity.Neg(),
+		EventType:      EventTypeRedemption,
+		EventID:        redemption.RedemptionID + redemptionEventSuffix,
+		EventTimestamp: executedAt,
+		StockPrice:     stockPrice.Price,
+		StockPriceID:   &stockPrice.ID,
+		TotalValueINR:  gross.Neg(),
+		BrokerageFee:   brokerage,
+		TransactionFee: fee,
+		NetValueINR:    net.Neg(),
+		Charges:        charges,
+		FeePlanID:      feePlanID,
+		DeductFees:     true,
+		Status:         models.RewardStatusCompleted,
+		Notes:          &notes,
+	}
+	if _, err := rs.rewardRepo.Create(ctx, reward); err != nil {
+		return fmt.Errorf("failed to create redemption reward: %w", err)
+	}
+	if err := rs.chargeRepo.BulkCreate(ctx, chargesForRewards(reward)); err != nil {
+		return fmt.Errorf("failed to create redemption charges: %w", err)
+	}
+
+	redemption.Status = models.RedemptionStatusExecuted
+	redemption.StockPrice = &stockPrice.Price
+	redemption.StockPriceID = &stockPrice.ID
+	redemption.GrossValueINR = &gross
+	redemption.BrokerageFee = &brokerage
+	redemption.TransactionFee = &fee
+	redemption.NetPayoutINR = &net
+	redemption.CostBasisINR = &costBasis
+	redemption.RealizedPnLINR = &realizedPnL
+	redemption.Charges = charges
+	redemption.RewardID = &reward.ID
+	redemption.ExecutedAt = &executedAt
+
+	if err := rs.ledgerRepo.BulkCreate(ctx, redemptionLedgerEntries(redemption, reward)); err != nil {
+		return fmt.Errorf("failed to create redemption ledger entries: %w", err)
+	}
+	if err := rs.redemptionRepo.Update(ctx, redemption); err != nil {
+		return fmt.Errorf("failed to update redemption: %w", err)
+	}
+	return nil
+}
+
+// validateRedemption checks a redemption request
+func validateRedemption(req *RedemptionRequest) error {
+	if req.RedemptionID == "" {
+		return fmt.Errorf("redemption_id is required")
+	}
+	// Leaves room for the -REDEEM suffix within rewards.event_id
+	if len(req.RedemptionID) > maxEventIDLength-len(redemptionEventSuffix) {
+		return fmt.Errorf("redemption_id must be at most %d characters", maxEventIDLength-len(redemptionEventSuffix))
+	}
+	if req.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	if req.StockSymbol == "" {
+		return fmt.Errorf("stock_symbol is required")
+	}
+	if !req.Quantity.IsPositive() {
+		return fmt.Errorf("quantity must be positive")
+	}
+	return normalizePlaces("quantity", &req.Quantity, models.QuantityScale)
+}
+
+// redemptionLedgerEntries books a sale: the stock leaves STOCK_ASSET at cost,
+// the gain or loss against the gross sale value goes to REALIZED_PNL, each
+// charge is expensed to its account and the net payout becomes receivable
+func redemptionLedgerEntries(redemption *models.Redemption, reward *models.Reward) []*models.LedgerEntry {
+	desc := fmt.Sprintf("Redemption %s: %s x %s @ %s INR",
+		redemption.RedemptionID, redemption.StockSymbol, redemption.Quantity, *redemption.StockPrice)
+
+	entries := make([]*models.LedgerEntry, 0, 4+len(reward.Charges))
+	add := func(entryType, account string, amount decimal.Decimal) {
+		if !amount.IsPositive() {
+			return
+		}
+		entries = append(entries, &models.LedgerEntry{
+			RewardID:    reward.ID,
+			UserID:      reward.UserID,
+			EntryType:   entryType,
+			AccountType: account,
+			Amount:      amount,
+			Currency:    "INR",
+			Description: &desc,
+			ReferenceID: &reward.EventID,
+		})
+	}
+
+	gain := redemption.GrossValueINR.Sub(*redemption.CostBasisINR)
+	add("CREDIT", AccountStockAsset, *redemption.CostBasisINR)
+	add("CREDIT", AccountRealizedPnL, gain)
+	add("DEBIT", AccountRealizedPnL, gain.Neg())
+	for _, charge := range reward.Charges {
+		add("DEBIT", charge.Account, charge.Amount)
+	}
+	add("DEBIT", AccountRedemptionReceivable, *redemption.NetPayoutINR)
+	return entries
+}
+
+// redemptionPaymentEntries settles a redemption's receivable in cash
+func redemptionPaymentEntries(redemption *models.Redemption) []*models.LedgerEntry {
+	desc := fmt.Sprintf("Redemption %s paid", redemption.RedemptionID)
+	eventID := redemption.RedemptionID + redemptionEventSuffix
+
+	entry := func(entryType, account string) *models.LedgerEntry {
+		return &models.LedgerEntry{
+			RewardID:    *redemption.RewardID,
+			UserID:      redemption.UserID,
+			EntryType:   entryType,
+			AccountType: account,
+			Amount:      *redemption.NetPayoutINR,
+			Currency:    "INR",
+			Description: &desc,
+			ReferenceID: &eventID,
+		}
+	}
+	return []*models.LedgerEntry{
+		entry("DEBIT", "CASH"),
+		entry("CREDIT", AccountRedemptionReceivable),
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+)
+
+// Rounding policies for quantities derived from an INR amount
+const (
+	QuantityRoundDown    = "down"
+	QuantityRoundNearest = "nearest"
+	QuantityRoundUp      = "up"
+)
+
+// quantityRoundingModes maps QUANTITY_ROUNDING to the rounding it applies;
+// nearest rounds ties away from zero
+var quantityRoundingModes = map[string]decimal.RoundingMode{
+	QuantityRoundDown:    decimal.RoundDown,
+	QuantityRoundNearest: decimal.RoundHalfUp,
+	QuantityRoundUp:      decimal.RoundUp,
+}
+
+// maxQuantityPrecision matches the scale of rewards.quantity DECIMAL(15,6)
+const maxQuantityPrecision = models.QuantityScale
+
+// hundred turns percentages into fractions
+var hundred = decimal.NewFromInt(100)
+
+// ErrAmountTooSmall is returned when an INR amount buys less than the smallest quantity step
+var ErrAmountTooSmall = newError(KindValidation, "AMOUNT_TOO_SMALL", "amount_inr is too small to buy any stock").
+	withStatus(http.StatusUnprocessableEntity)
+
+// maxChargeFitSteps bounds how often quantityForAmount steps a quantity down
+// to fit flat charges and caps within the amount
+const maxChargeFitSteps = 3
+
+// quantityForAmount derives the quantity an INR amount buys at price.
+// With deductFees the amount covers stock value plus the charges computed by
+// charges; otherwise it is the stock value alone and fees are paid on top.
+func (rs *RewardService) quantityForAmount(amountINR, price decimal.Decimal, deductFees bool, charges ChargesCalculator) (decimal.Decimal, error) {
+	if !price.IsPositive() {
+		return decimal.Zero, fmt.Errorf("cannot value amount_inr at price %s", price)
+	}
+
+	// With fees deducted the amount is quantity × price × (100 + charge
+	// percent) / 100; one division keeps the quantity exact until rounded
+	stockValue, divisor := amountINR, price
+	if deductFees {
+		stockValue = amountINR.Mul(hundred)
+		divisor = price.Mul(hundred.Add(charges.Percent(TradeSideBuy)))
+	}
+
+	quantity := stockValue.Div(divisor, int32(rs.quantityPrecision), quantityRoundingModes[rs.quantityRounding])
+
+	// Flat charges, caps, rounding to paise and rounding the quantity up or
+	// to nearest can all overshoot; step the quantity down by the excess
+	// until stock value plus charges fits within the amount
+	if deductFees {
+		for i := 0; i < maxChargeFitSteps && quantity.IsPositive(); i++ {
+			value := roundINR(quantity.Mul(price))
+			brokerage, fee := chargeTotals(charges.Charges(TradeSideBuy, value))
+			excess := value.Add(brokerage).Add(fee).Sub(amountINR)
+			if !excess.IsPositive() {
+				break
+			}
+			quantity = quantity.Sub(excess.Div(price, int32(rs.quantityPrecision), decimal.RoundUp))
+		}
+	}
+
+	if !quantity.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w: %s INR at %s INR per share", ErrAmountTooSmall, amountINR, price)
+	}
+	return quantity.Round(models.QuantityScale, decimal.RoundDown), nil
+}
+
+// executedAmountINR is the INR spent against a requested amount: stock value
+// plus fees when fees were deducted from the amount, stock value otherwise
+func executedAmountINR(totalValueINR, brokerageFee, transactionFee decimal.Decimal, deductFees bool) decimal.Decimal {
+	if deductFees {
+		return totalValueINR.Add(brokerageFee).Add(transactionFee)
+	}
+	return totalValueINR
+}
+
+// roundINR rounds an INR value to paise, half-up
+func roundINR(value decimal.Decimal) decimal.Decimal {
+	return value.Round(models.AmountScale, decimal.RoundHalfUp)
+}
+
+// normalizePlaces rejects a value with more digits after the point than its
+// column keeps, which the database would otherwise round away, and pads it to
+// exactly that many so it renders the same before and after it is stored
+func normalizePlaces(field string, value *decimal.Decimal, places int32) error {
+	padded := value.Round(places, decimal.RoundDown)
+	if !padded.Equal(*value) {
+		return fmt.Errorf("%s allows at most %d decimals", field, places)
+	}
+	*value = padded
+	return nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"testing"
+)
+
+func TestQuantityForAmount(t *testing.T) {
+	percent := &percentCharges{
+		brokeragePercent: decimal.RequireFromString("0.1"),
+		feePercent:       decimal.RequireFromString("0.05"),
+	}
+	// A flat fee the charge percent cannot see, so only the fit loop
+	// keeps the purchase within the amount
+	flat := &feePlanCharges{charges: []models.FeePlanCharge{
+		{Code: ChargeBrokerage, Account: AccountBrokerageExpense, FlatINR: decimal.RequireFromString("20")},
+	}}
+
+	tests := []struct {
+		name       string
+		precision  int
+		rounding   string
+		amount     string
+		price      string
+		deductFees bool
+		charges    ChargesCalculator
+		want       string
+	}{
+		{"fees on top, down", 6, QuantityRoundDown, "500", "123.4500", false, percent, "4.050222"},
+		{"fees on top, up", 6, QuantityRoundUp, "500", "123.4500", false, percent, "4.050223"},
+		{"fees on top, nearest", 6, QuantityRoundNearest, "1", "3", false, percent, "0.333333"},
+		{"whole shares", 0, QuantityRoundDown, "500", "123.4500", false, percent, "4.000000"},
+		{"fees deducted", 6, QuantityRoundDown, "1000", "100", true, percent, "9.985022"},
+		{"flat fee, down", 6, QuantityRoundDown, "1000", "100", true, flat, "9.800000"},
+		{"flat fee, up", 6, QuantityRoundUp, "1000", "100", true, flat, "9.800000"},
+		{"flat fee, nearest", 6, QuantityRoundNearest, "1000", "100", true, flat, "9.800000"},
+		{"flat fee, whole shares", 0, QuantityRoundUp, "1000", "100", true, flat, "9.000000"},
+	}
+	for _, tt := range tests {
+		rs := &RewardService{quantityPrecision: tt.precision, quantityRounding: tt.rounding}
+		amount, price := decimal.RequireFromString(tt.amount), decimal.RequireFromString(tt.price)
+		got, err := rs.quantityForAmount(amount, price, tt.deductFees, tt.charges)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s: quantity = %s, want %s", tt.name, got, tt.want)
+		}
+
+		// With fees deducted, stock value plus charges never exceeds the amount
+		if tt.deductFees {
+			value := roundINR(got.Mul(price))
+			brokerage, fee := chargeTotals(tt.charges.Charges(TradeSideBuy, value))
+			if spent := value.Add(brokerage).Add(fee); spent.GreaterThan(amount) {
+				t.Errorf("%s: %s shares cost %s INR with charges, more than %s", tt.name, got, spent, amount)
+			}
+		}
+	}
+}
+
+func TestQuantityForAmountErrors(t *testing.T) {
+	rs := &RewardService{quantityPrecision: 0, quantityRounding: QuantityRoundDown}
+	charges := &percentCharges{brokeragePercent: decimal.Zero, feePercent: decimal.Zero}
+
+	if _, err := rs.quantityForAmount(decimal.RequireFromString("50"), decimal.RequireFromString("100"), false, charges); !errors.Is(err, ErrAmountTooSmall) {
+		t.Errorf("amount below one share: error %v, want ErrAmountTooSmall", err)
+	}
+	flat := &feePlanCharges{charges: []models.FeePlanCharge{{Code: ChargeBrokerage, FlatINR: decimal.RequireFromString("100")}}}
+	if _, err := rs.quantityForAmount(decimal.RequireFromString("100"), decimal.RequireFromString("10"), true, flat); !errors.Is(err, ErrAmountTooSmall) {
+		t.Errorf("amount eaten by charges: error %v, want ErrAmountTooSmall", err)
+	}
+	if _, err := rs.quantityForAmount(decimal.RequireFromString("100"), decimal.Zero, false, charges); err == nil {
+		t.Error("zero price should fail")
+	}
+}
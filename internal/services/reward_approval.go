@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrInvalidStatusTransition is returned when a reward cannot move to the requested status
+	ErrInvalidStatusTransition = newError(KindConflict, "INVALID_STATUS_TRANSITION", "invalid reward status transition")
+	// ErrSelfApproval is returned when the reviewer of a reward is also its creator
+	ErrSelfApproval = newError(KindValidation, "SELF_APPROVAL", "reviewer must differ from the reward creator").
+			withStatus(http.StatusForbidden)
+	// ErrCreatorRequired is returned when a reward needing approval has no creator identity
+	ErrCreatorRequired = newError(KindValidation, "CREATOR_REQUIRED", "created_by is required for rewards above the approval threshold")
+)
+
+// ReviewRequest represents an approve or reject decision on a pending reward
+type ReviewRequest struct {
+	ReviewerID string `json:"reviewer_id" binding:"required"`
+	Note       string `json:"note"`
+}
+
+// requiresApproval reports whether a reward is above the approval threshold.
+// Only rewards that grant stock need approval; adjustments are booked directly.
+func (rs *RewardService) requiresApproval(totalValueINR decimal.Decimal) bool {
+	return rs.approvalThresholdINR.IsPositive() && totalValueINR.GreaterThan(rs.approvalThresholdINR)
+}
+
+// ApproveReward approves a PENDING_APPROVAL reward and posts its ledger entries
+func (rs *RewardService) ApproveReward(ctx context.Context, eventID string, req *ReviewRequest) (*RewardResponse, error) {
+	return rs.reviewReward(ctx, eventID, req, models.RewardStatusApproved)
+}
+
+// RejectReward rejects a PENDING_APPROVAL reward; nothing is posted to the ledger
+func (rs *RewardService) RejectReward(ctx context.Context, eventID string, req *ReviewRequest) (*RewardResponse, error) {
+	return rs.reviewReward(ctx, eventID, req, models.RewardStatusRejected)
+}
+
+// reviewReward moves a pending reward to APPROVED or REJECTED
+func (rs *RewardService) reviewReward(ctx context.Context, eventID string, req *ReviewRequest, status string) (*RewardResponse, error) {
+	rs.log.Infof("Reviewing reward %s: %s by %s", eventID, status, req.ReviewerID)
+
+	if req.ReviewerID == "" {
+		return nil, fmt.Errorf("%w: reviewer_id is required", ErrValidation)
+	}
+
+	var response *RewardResponse
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		// Lock the reward so concurrent reviews are serialized
+		reward, err := rs.rewardRepo.LockByEventID(ctx, eventID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRewardNotFound
+			}
+			return fmt.Errorf("failed to load reward: %w", err)
+		}
+
+		if !models.CanTransitionReward(reward.Status, status) {
+			return fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, reward.Status, status)
+		}
+		// Rewards flagged by a risk rule have no maker and any reviewer may decide them
+		if reward.CreatedBy != nil && *reward.CreatedBy == req.ReviewerID {
+			return ErrSelfApproval
+		}
+
+		now := time.Now()
+		reward.Status = status
+		reward.ReviewedBy = &req.ReviewerID
+		reward.ReviewedAt = &now
+		if req.Note != "" {
+			reward.ReviewNote = &req.Note
+		}
+		if err := rs.rewardRepo.Review(ctx, reward); err != nil {
+			return fmt.Errorf("failed to record review: %w", err)
+		}
+
+		message := "Reward rejected"
+		if status == models.RewardStatusRejected {
+			if err := rs.releaseCampaignSpend(ctx, reward); err != nil {
+				return fmt.Errorf("failed to release campaign spend: %w", err)
+			}
+			if _, err := rs.vestingRepo.CancelByRewardID(ctx, reward.ID); err != nil {
+				return fmt.Errorf("failed to cancel vesting tranches: %w", err)
+			}
+		}
+		if status == models.RewardStatusApproved {
+			if err := rs.createLedgerEntries(ctx, reward); err != nil {
+				return fmt.Errorf("failed to create ledger entries: %w", err)
+			}
+			message = "Reward approved"
+		}
+
+		response = newRewardResponse(reward, message)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rs.log.Infof("Reward %s %s by %s", eventID, status, req.ReviewerID)
+	return response, nil
+}
+
+// ListRewardsByStatus lists rewards in the given status, oldest first
+func (rs *RewardService) ListRewardsByStatus(ctx context.Context, status string, limit, offset int) ([]*models.Reward, error) {
+	return rs.rewardRepo.ListByStatus(ctx, status, limit, offset)
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// maxEventIDLength matches rewards.event_id VARCHAR(100)
+const maxEventIDLength = 100
+
+// basketLegPrefix starts the event ID of every basket leg; requests may not
+// use it, so a leg's event ID never collides with a standalone event
+const basketLegPrefix = "BASKET:"
+
+// applyBasket checks that the request's basket exists and is active before
+// the event is claimed
+func (rs *RewardService) applyBasket(ctx context.Context, req *RewardRequest) error {
+	if req.BasketID == nil {
+		return nil
+	}
+	_, err := rs.basketForRequest(ctx, req)
+	return err
+}
+
+// basketForRequest loads the request's basket and checks that every leg's
+// event ID fits
+func (rs *RewardService) basketForRequest(ctx context.Context, req *RewardRequest) (*models.Basket, error) {
+	basket, err := rs.basketRepo.GetByID(ctx, *req.BasketID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %d", ErrBasketNotFound, *req.BasketID)
+		}
+		return nil, fmt.Errorf("failed to load basket: %w", err)
+	}
+	if !basket.Active {
+		return nil, fmt.Errorf("%w: %d", ErrBasketInactive, basket.ID)
+	}
+	for _, leg := range basket.Legs {
+		if len(basketLegEventID(req.EventID, leg.StockSymbol)) > maxEventIDLength {
+			return nil, fmt.Errorf("%w: event_id is too long for the legs of basket %d", ErrValidation, basket.ID)
+		}
+	}
+	return basket, nil
+}
+
+// validateBasketRequest checks the fields a basket reward takes: an INR
+// amount to split, and no symbol or quantity of its own
+func validateBasketRequest(req *RewardRequest) error {
+	if req.CampaignID != nil || req.QuoteID != "" {
+		return fmt.Errorf("basket_id cannot be combined with campaign_id or quote_id")
+	}
+	if req.StockSymbol != "" {
+		return fmt.Errorf("a basket reward takes its symbols from the basket, not stock_symbol")
+	}
+	if !req.Quantity.IsZero() || !req.AmountINR.IsPositive() {
+		return fmt.Errorf("a basket reward requires amount_inr and no quantity")
+	}
+	return nil
+}
+
+// executeBasketTx books one reward per leg of the request's basket under the
+// request's event; ctx must carry a transaction so the legs commit or roll
+// back together with the idempotency record
+func (rs *RewardService) executeBasketTx(ctx context.Context, req *RewardRequest, eventType *models.EventType) (*RewardResponse, error) {
+	basket, err := rs.basketForRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Steps 5-7: Price and value every leg before anything is written
+	amounts := basketLegAmounts(req.AmountINR, basket.Legs)
+	legs := make([]*models.Reward, 0, len(basket.Legs))
+	totalValueINR := decimal.Zero
+	for i, leg := range basket.Legs {
+		stockPrice, err := rs.priceService.GetPriceAt(ctx, leg.StockSymbol, req.EventTimestamp)
+		if err != nil {
+			rs.log.Errorf("Failed to get price for %s: %v", leg.StockSymbol, err)
+			return nil, failWith(FailureCodePriceUnavailable, fmt.Errorf("failed to get stock price for %s: %w", leg.StockSymbol, err))
+		}
+
+		legReq := *req
+		legReq.StockSymbol = leg.StockSymbol
+		legReq.AmountINR = amounts[i]
+		legReq.EventID = basketLegEventID(req.EventID, leg.StockSymbol)
+		legReq.BasketID = nil
+		plan, err := feePlanAt(ctx, rs.feePlanRepo, leg.StockSymbol, eventType.Code, rewardEventTime(req))
+		if err != nil {
+			return nil, err
+		}
+		reward, err := rs.buildReward(&legReq, stockPrice, plan)
+		if err != nil {
+			return nil, fmt.Errorf("basket leg %s: %w", leg.StockSymbol, err)
+		}
+		if err := checkEventTypeValue(eventType, reward); err != nil {
+			return nil, fmt.Errorf("basket leg %s: %w", leg.StockSymbol, err)
+		}
+		reward.BasketID = req.BasketID
+		reward.ParentEventID = &req.EventID
+		legs = append(legs, reward)
+		totalValueINR = totalValueINR.Add(reward.TotalValueINR)
+	}
+
+	// Approval is decided for the basket as a whole: all legs wait or none do
+	pending := rs.requiresApproval(totalValueINR)
+	if pending && req.CreatedBy == "" {
+		return nil, ErrCreatorRequired
+	}
+
+	var activity *models.UserRewardActivity
+	if rs.risk.tracksUsers() {
+		activity, err = rs.rewardRepo.GetUserActivity(ctx, req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user reward activity: %w", err)
+		}
+	}
+	riskRule := ""
+	for _, leg := range legs {
+		rule, err := rs.applyRiskRules(leg, activity)
+		if err != nil {
+			return nil, err
+		}
+		if riskRule == "" {
+			riskRule = rule
+		}
+		// Each leg counts towards the velocity rules, as it will once booked
+		if activity != nil {
+			activity = &models.UserRewardActivity{
+				RewardsToday:      activity.RewardsToday + 1,
+				ValueINRThisMonth: activity.ValueINRThisMonth.Add(leg.TotalValueINR),
+			}
+		}
+	}
+	if riskRule != "" {
+		pending = true
+	}
+	if pending {
+		for _, leg := range legs {
+			leg.Status = models.RewardStatusPendingApproval
+		}
+	}
+
+	if err := rs.rewardRepo.BulkCreate(ctx, legs); err != nil {
+		return nil, failWith(FailureCodeRewardWrite, fmt.Errorf("failed to create basket rewards: %w", err))
+	}
+	if err := rs.createVestingTranches(ctx, legs...); err != nil {
+		return nil, failWith(FailureCodeRewardWrite, fmt.Errorf("failed to create vesting tranches: %w", err))
+	}
+	if err := rs.chargeRepo.BulkCreate(ctx, chargesForRewards(legs...)); err != nil {
+		return nil, failWith(FailureCodeRewardWrite, fmt.Errorf("failed to create reward charges: %w", err))
+	}
+	if riskRule != "" {
+		if err := rs.rewardRequestRepo.FlagRisk(ctx, req.EventID, riskRule); err != nil {
+			return nil, fmt.Errorf("failed to record risk rule: %w", err)
+		}
+	}
+
+	// Step 8: Post every leg to the ledger unless the basket awaits approval
+	if !pending {
+		entries := make([]*models.LedgerEntry, 0)
+		for _, leg := range legs {
+			entries = append(entries, ledgerEntriesFor(leg, eventType)...)
+		}
+		if err := rs.ledgerRepo.BulkCreate(ctx, entries); err != nil {
+			return nil, failWith(FailureCodeLedgerWrite, fmt.Errorf("failed to create ledger entries: %w", err))
+		}
+	}
+
+	// Step 9: Mark the basket request as completed
+	response := newBasketResponse(req, legs)
+	response.RiskRule = riskRule
+
+	responsePayload, _ := json.Marshal(response)
+	if err := rs.rewardRequestRepo.MarkProcessed(ctx, req.EventID, string(responsePayload)); err != nil {
+		return nil, failWith(FailureCodeInternal, fmt.Errorf("failed to mark request as processed: %w", err))
+	}
+
+	rs.log.Infof("Booked basket %d for event %s as %d legs worth %s INR",
+		basket.ID, req.EventID, len(legs), response.TotalValueINR)
+	return response, nil
+}
+
+// GetBasketRewards returns the legs booked for a basket reward event
+func (rs *RewardService) GetBasketRewards(ctx context.Context, eventID string) ([]*models.Reward, error) {
+	legs, err := rs.rewardRepo.GetByParentEventID(ctx, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load basket rewards: %w", err)
+	}
+	if len(legs) == 0 {
+		return nil, fmt.Errorf("%w: no basket reward for event %s", ErrRewardNotFound, eventID)
+	}
+	return legs, nil
+}
+
+// basketLegAmounts splits amountINR across the legs by weight, rounding each
+// share half-up to the paisa. The last leg takes the remainder so the legs
+// add up to the amount exactly.
+func basketLegAmounts(amountINR decimal.Decimal, legs []models.BasketLeg) []decimal.Decimal {
+	totalWeight := decimal.Zero
+	for _, leg := range legs {
+		totalWeight = totalWeight.Add(leg.Weight)
+	}
+
+	amounts := make([]decimal.Decimal, len(legs))
+	remaining := amountINR
+	for i, leg := range legs {
+		if i == len(legs)-1 {
+			amounts[i] = remaining
+			break
+		}
+		amounts[i] = amountINR.Mul(leg.Weight).Div(totalWeight, models.AmountScale, decimal.RoundHalfUp)
+		remaining = remaining.Sub(amounts[i])
+	}
+	return amounts
+}
+
+// basketLegEventID is the event ID of one leg of a basket reward
+func basketLegEventID(parentEventID, stockSymbol string) string {
+	return basketLegPrefix + parentEventID + ":" + stockSymbol
+}
+
+// newBasketResponse builds the API response for a basket reward; values
+// and fees are totals over its legs
+func newBasketResponse(req *RewardRequest, legs []*models.Reward) *RewardResponse {
+	response := &RewardResponse{
+		UserID:             req.UserID,
+		BasketID:           req.BasketID,
+		RequestedAmountINR: &req.AmountINR,
+		DeductFees:         req.DeductFees,
+		EventID:            req.EventID,
+		Status:             "SUCCESS",
+		Message:            "Basket reward processed successfully",
+		Timestamp:          time.Now(),
+		Vesting:            req.Vesting,
+	}
+
+	executed := decimal.Zero
+	for _, leg := range legs {
+		legResponse := newRewardResponse(leg, processedMessage(leg))
+		response.Legs = append(response.Legs, legResponse)
+		response.TotalValueINR = response.TotalValueINR.Add(leg.TotalValueINR)
+		response.BrokerageFee = response.BrokerageFee.Add(leg.BrokerageFee)
+		response.TransactionFee = response.TransactionFee.Add(leg.TransactionFee)
+		response.NetValueINR = response.NetValueINR.Add(leg.NetValueINR)
+		executed = executed.Add(*legResponse.ExecutedAmountINR)
+	}
+	response.ExecutedAmountINR = &executed
+
+	if len(legs) > 0 && legs[0].Status == models.RewardStatusPendingApproval {
+		response.Status = models.RewardStatusPendingApproval
+		response.Message = "Basket reward created and awaiting approval"
+	}
+	return response
+}
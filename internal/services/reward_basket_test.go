@@ -0,0 +1,76 @@
+package services
+
+import (
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"testing"
+)
+
+func TestBasketLegAmounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		weights []string
+		want    []string
+	}{
+		{
+			name:    "relative weights",
+			amount:  "1000.00",
+			weights: []string{"30", "25", "20", "15", "10"},
+			want:    []string{"300.00", "250.00", "200.00", "150.00", "100.00"},
+		},
+		{
+			name:    "last leg takes the remainder",
+			amount:  "100.00",
+			weights: []string{"1", "1", "1"},
+			want:    []string{"33.33", "33.33", "33.34"},
+		},
+		{
+			name:    "shares round half-up",
+			amount:  "10.00",
+			weights: []string{"2", "1"},
+			want:    []string{"6.67", "3.33"},
+		},
+		{
+			name:    "fractional weights",
+			amount:  "1.00",
+			weights: []string{"0.1", "0.2"},
+			want:    []string{"0.33", "0.67"},
+		},
+		{
+			name:    "single leg",
+			amount:  "99.99",
+			weights: []string{"7"},
+			want:    []string{"99.99"},
+		},
+	}
+	for _, tt := range tests {
+		legs := make([]models.BasketLeg, len(tt.weights))
+		for i, weight := range tt.weights {
+			legs[i] = models.BasketLeg{StockSymbol: "SYM", Weight: decimal.RequireFromString(weight)}
+		}
+		amount := decimal.RequireFromString(tt.amount)
+		amounts := basketLegAmounts(amount, legs)
+		if len(amounts) != len(tt.want) {
+			t.Errorf("%s: got %d legs, want %d", tt.name, len(amounts), len(tt.want))
+			continue
+		}
+
+		total := decimal.Zero
+		for i, got := range amounts {
+			if got.String() != tt.want[i] {
+				t.Errorf("%s: leg %d = %s, want %s", tt.name, i, got, tt.want[i])
+			}
+			total = total.Add(got)
+		}
+		if !total.Equal(amount) {
+			t.Errorf("%s: legs sum to %s, want %s", tt.name, total, amount)
+		}
+	}
+}
+
+func TestBasketLegEventID(t *testing.T) {
+	if got := basketLegEventID("EVT-DIWALI-551", "TCS"); got != "BASKET:EVT-DIWALI-551:TCS" {
+		t.Errorf("basketLegEventID = %s, want BASKET:EVT-DIWALI-551:TCS", got)
+	}
+}
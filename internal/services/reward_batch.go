@@ -0,0 +1,542 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"strconv"
+	"time"
+)
+
+// Batch item outcomes
+const (
+	BatchItemCreated   = "CREATED"
+	BatchItemDuplicate = "DUPLICATE"
+	BatchItemFailed    = "FAILED"
+)
+
+// defaultMaxBatchSize caps a single batch unless REWARD_BATCH_MAX_SIZE overrides it
+const defaultMaxBatchSize = 50000
+
+// BatchRewardResult is the outcome of one item in a reward batch
+type BatchRewardResult struct {
+	Index   int             `json:"index"`
+	EventID string          `json:"event_id"`
+	Status  string          `json:"status"`
+	Reason  string          `json:"reason,omitempty"`
+	Reward  *RewardResponse `json:"reward,omitempty"`
+}
+
+// MaxBatchSize returns the largest batch ProcessRewardBatch accepts
+func (rs *RewardService) MaxBatchSize() int {
+	if v := os.Getenv("REWARD_BATCH_MAX_SIZE"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val > 0 {
+			return val
+		}
+	}
+	return defaultMaxBatchSize
+}
+
+// ProcessRewardBatch processes many reward requests with the same event_id
+// idempotency semantics as ProcessReward. Prices are fetched once per symbol
+// and all new rewards are written in one transaction. Each item gets its own
+// result; items that fail validation never block the rest of the batch.
+func (rs *RewardService) ProcessRewardBatch(ctx context.Context, reqs []*RewardRequest) ([]*BatchRewardResult, error) {
+	rs.log.Infof("Processing reward batch of %d items", len(reqs))
+
+	if len(reqs) > rs.MaxBatchSize() {
+		return nil, fmt.Errorf("%w: batch of %d exceeds maximum of %d", ErrValidation, len(reqs), rs.MaxBatchSize())
+	}
+
+	results := make([]*BatchRewardResult, len(reqs))
+	fail := func(i int, reason string) {
+		results[i].Status = BatchItemFailed
+		results[i].Reason = reason
+	}
+
+	// Step 1: Validate each item against its event type and reject event IDs
+	// repeated inside the batch
+	eventTypes, err := rs.loadEventTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(reqs))
+	var eventIDs, userIDs []string
+	for i, req := range reqs {
+		results[i] = &BatchRewardResult{Index: i}
+		if req == nil {
+			fail(i, "validation failed: empty item")
+			continue
+		}
+		results[i].EventID = req.EventID
+		// Fingerprinted as received, like a single request
+		normalizeVesting(req.Vesting)
+		req.fingerprint = fingerprintRequest(req)
+		if err := rs.validateRequest(req); err != nil {
+			fail(i, fmt.Sprintf("validation failed: %v", err))
+			continue
+		}
+		if req.CampaignID != nil {
+			fail(i, "validation failed: campaign rewards cannot be batched")
+			continue
+		}
+		if req.QuoteID != "" {
+			fail(i, "validation failed: quoted rewards cannot be batched")
+			continue
+		}
+		if req.BasketID != nil {
+			fail(i, "validation failed: basket rewards cannot be batched")
+			continue
+		}
+		code := eventTypeCode(req.EventType)
+		eventType, err := usableEventType(code, eventTypes[code])
+		if err == nil {
+			err = checkEventType(eventType, req)
+		}
+		if err != nil {
+			fail(i, err.Error())
+			continue
+		}
+		if seen[req.EventID] {
+			fail(i, "duplicate event_id within batch")
+			continue
+		}
+		seen[req.EventID] = true
+		eventIDs = append(eventIDs, req.EventID)
+		userIDs = append(userIDs, req.UserID)
+	}
+
+	// Step 2: Check idempotency for every event in one query
+	existing, err := rs.rewardRequestRepo.GetByEventIDs(ctx, eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check idempotency: %w", err)
+	}
+
+	// Step 3: Ensure users exist
+	users, err := rs.userRepo.ExistsBatch(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+
+	pending := make([]int, 0, len(eventIDs))
+	symbolSet := make(map[string]bool)
+	for i, req := range reqs {
+		if results[i].Status != "" {
+			continue
+		}
+		if record, ok := existing[req.EventID]; ok && record.Status == models.RequestStatusFailed {
+			// A FAILED event is attempted again; BulkCreate reclaims its record
+			if err := checkFingerprint(record, req.fingerprint); err != nil {
+				fail(i, err.Error())
+				continue
+			}
+		} else if ok {
+			response, err := rs.replayResponse(ctx, record, req.fingerprint)
+			if err != nil {
+				fail(i, err.Error())
+				continue
+			}
+			results[i].Status = BatchItemDuplicate
+			results[i].Reward = response
+			continue
+		}
+		if !users[req.UserID] {
+			fail(i, fmt.Sprintf("user %s does not exist", req.UserID))
+			continue
+		}
+		pending = append(pending, i)
+		if req.EventTimestamp.IsZero() {
+			symbolSet[req.StockSymbol] = true
+		}
+	}
+
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	// Step 4: Fetch prices - once per symbol for undated items, and at the
+	// event time in one query for items carrying an event_timestamp
+	symbols := make([]string, 0, len(symbolSet))
+	for symbol := range symbolSet {
+		symbols = append(symbols, symbol)
+	}
+	latest := make(map[string]*models.StockPrice)
+	if len(symbols) > 0 {
+		latest, err = rs.priceService.GetLatestPrices(ctx, symbols)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stock prices: %w", err)
+		}
+	}
+
+	var datedIdx []int
+	var datedSymbols []string
+	var datedAts []time.Time
+	for _, i := range pending {
+		if !reqs[i].EventTimestamp.IsZero() {
+			datedIdx = append(datedIdx, i)
+			datedSymbols = append(datedSymbols, reqs[i].StockSymbol)
+			datedAts = append(datedAts, reqs[i].EventTimestamp)
+		}
+	}
+	pricesAt := make(map[int]*models.StockPrice, len(datedIdx))
+	if len(datedIdx) > 0 {
+		dated, err := rs.priceService.GetPricesAt(ctx, datedSymbols, datedAts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stock prices: %w", err)
+		}
+		for n, i := range datedIdx {
+			pricesAt[i] = dated[n]
+		}
+	}
+
+	// Step 5: Value every remaining item under the fee plan in force at its
+	// event time; plans are loaded once and resolved per item
+	plans, err := rs.feePlanRepo.List(ctx, "", "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fee plans: %w", err)
+	}
+	toCreate := make([]int, 0, len(pending))
+	rewards := make([]*models.Reward, 0, len(pending))
+	records := make([]*models.RewardRequest, 0, len(pending))
+	var failures []*models.RewardRequest
+	for _, i := range pending {
+		req := reqs[i]
+		price, ok := latest[req.StockSymbol]
+		if !req.EventTimestamp.IsZero() {
+			price = pricesAt[i]
+			ok = price != nil
+		}
+		if !ok {
+			reason := fmt.Sprintf("failed to get stock price for %s", req.StockSymbol)
+			if !req.EventTimestamp.IsZero() {
+				reason = fmt.Sprintf("%v: %s at %s", ErrPriceUnavailable, req.StockSymbol, req.EventTimestamp.Format(time.RFC3339))
+			}
+			fail(i, reason)
+			failures = append(failures, newFailedRequestRecord(req, FailureCodePriceUnavailable, reason))
+			continue
+		}
+		plan := resolveFeePlan(plans, req.StockSymbol, eventTypeCode(req.EventType), rewardEventTime(req))
+		reward, err := rs.buildReward(req, price, plan)
+		if err == nil {
+			err = checkEventTypeValue(eventTypes[reward.EventType], reward)
+		}
+		if err != nil {
+			fail(i, err.Error())
+			continue
+		}
+		toCreate = append(toCreate, i)
+		rewards = append(rewards, reward)
+		records = append(records, newRewardRequestRecord(req))
+	}
+
+	// Step 6: Write idempotency records, rewards and ledger entries together
+	var lost []int
+	var claimedItems map[int]bool
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		// Negative items must not drive a holding below zero
+		rejected, err := rs.checkBatchHoldings(ctx, reqs, toCreate, rewards)
+		if err != nil {
+			return err
+		}
+		// Risk rules see the batch's own earlier items, like holdings do
+		breaches, flagged, err := rs.checkBatchRisk(ctx, toCreate, rewards)
+		if err != nil {
+			return err
+		}
+		for i, breach := range breaches {
+			if rejected == nil {
+				rejected = make(map[int]string)
+			}
+			rejected[i] = breach.Error()
+			record := newFailedRequestRecord(reqs[i], FailureCodeRiskLimit, breach.Error())
+			record.RiskRule = &breach.Rule
+			failures = append(failures, record)
+		}
+		if len(rejected) > 0 {
+			keptIdx, keptRewards, keptRecords := toCreate[:0], rewards[:0], records[:0]
+			for n, i := range toCreate {
+				if reason, ok := rejected[i]; ok {
+					fail(i, reason)
+					continue
+				}
+				keptIdx = append(keptIdx, i)
+				keptRewards = append(keptRewards, rewards[n])
+				keptRecords = append(keptRecords, records[n])
+			}
+			toCreate, rewards, records = keptIdx, keptRewards, keptRecords
+		}
+
+		claimed, err := rs.rewardRequestRepo.BulkCreate(ctx, records)
+		if err != nil {
+			return err
+		}
+
+		// Events claimed concurrently by another request are left to that
+		// request; BulkCreate waited for it, so its result is replayed below
+		kept := rewards[:0]
+		keptIdx := toCreate[:0]
+		for n, i := range toCreate {
+			if !claimed[reqs[i].EventID] {
+				lost = append(lost, i)
+				continue
+			}
+			kept = append(kept, rewards[n])
+			keptIdx = append(keptIdx, i)
+		}
+		rewards, toCreate = kept, keptIdx
+		claimedItems = make(map[int]bool, len(toCreate))
+		for _, i := range toCreate {
+			claimedItems[i] = true
+		}
+
+		for _, i := range toCreate {
+			if rule, ok := flagged[i]; ok {
+				if err := rs.rewardRequestRepo.FlagRisk(ctx, reqs[i].EventID, rule); err != nil {
+					return fmt.Errorf("failed to record risk rule: %w", err)
+				}
+			}
+		}
+
+		if err := rs.rewardRepo.BulkCreate(ctx, rewards); err != nil {
+			return failWith(FailureCodeRewardWrite, err)
+		}
+		if err := rs.createVestingTranches(ctx, rewards...); err != nil {
+			return failWith(FailureCodeRewardWrite, err)
+		}
+		if err := rs.chargeRepo.BulkCreate(ctx, chargesForRewards(rewards...)); err != nil {
+			return failWith(FailureCodeRewardWrite, err)
+		}
+
+		entries := make([]*models.LedgerEntry, 0, len(rewards)*6)
+		for _, reward := range rewards {
+			// Rewards awaiting approval are posted when approved
+			if models.IsSettledReward(reward.Status) {
+				entries = append(entries, ledgerEntriesFor(reward, eventTypes[reward.EventType])...)
+			}
+		}
+		if err := rs.ledgerRepo.BulkCreate(ctx, entries); err != nil {
+			return failWith(FailureCodeLedgerWrite, err)
+		}
+
+		payloads := make(map[string]string, len(rewards))
+		for n, reward := range rewards {
+			response := newRewardResponse(reward, processedMessage(reward))
+			response.RiskRule = flagged[toCreate[n]]
+			payload, _ := json.Marshal(response)
+			payloads[reward.EventID] = string(payload)
+			results[toCreate[n]].Reward = response
+		}
+		return rs.rewardRequestRepo.BulkMarkProcessed(ctx, payloads)
+	})
+	if err != nil {
+		rs.log.Errorf("Failed to write reward batch: %v", err)
+		reason := fmt.Sprintf("failed to persist batch: %v", err)
+		// As with a single request, only events the batch claimed are
+		// recorded as FAILED; the others are left unclaimed so sending them
+		// again processes them as new
+		unclaimed := reason + "; event not claimed"
+		for _, i := range toCreate {
+			results[i].Reward = nil
+			if !claimedItems[i] {
+				fail(i, unclaimed)
+				continue
+			}
+			fail(i, reason)
+			failures = append(failures, newFailedRequestRecord(reqs[i], failureCode(err), reason))
+		}
+		for _, i := range lost {
+			fail(i, unclaimed)
+		}
+		rs.recordBatchFailures(ctx, failures)
+		return results, nil
+	}
+	rs.recordBatchFailures(ctx, failures)
+	rs.replayLostClaims(ctx, reqs, lost, results)
+
+	for _, i := range toCreate {
+		results[i].Status = BatchItemCreated
+	}
+
+	rs.log.Infof("Reward batch processed: %d created out of %d items", len(toCreate), len(reqs))
+	return results, nil
+}
+
+// replayLostClaims reports items whose event_id was claimed by a concurrent
+// request as duplicates of that request's stored result
+func (rs *RewardService) replayLostClaims(ctx context.Context, reqs []*RewardRequest, lost []int, results []*BatchRewardResult) {
+	if len(lost) == 0 {
+		return
+	}
+
+	eventIDs := make([]string, len(lost))
+	for n, i := range lost {
+		eventIDs[n] = reqs[i].EventID
+	}
+	existing, err := rs.rewardRequestRepo.GetByEventIDs(ctx, eventIDs)
+	if err != nil {
+		rs.log.Errorf("Failed to load concurrent requests: %v", err)
+	}
+
+	for _, i := range lost {
+		record, ok := existing[reqs[i].EventID]
+		if !ok {
+			results[i].Status = BatchItemFailed
+			results[i].Reason = "request claimed concurrently and its result is unavailable"
+			continue
+		}
+		response, err := rs.replayResponse(ctx, record, reqs[i].fingerprint)
+		if err != nil {
+			results[i].Status = BatchItemFailed
+			results[i].Reason = err.Error()
+			continue
+		}
+		results[i].Status = BatchItemDuplicate
+		results[i].Reward = response
+	}
+}
+
+// batchHolding identifies one user's position in a symbol
+type batchHolding struct {
+	userID      string
+	stockSymbol string
+}
+
+// checkBatchHoldings locks every holding touched by a negative item and walks
+// the items in batch order, so earlier items count towards later ones. It
+// returns the rejection reason of each item that would go below zero, by index.
+func (rs *RewardService) checkBatchHoldings(ctx context.Context, reqs []*RewardRequest, items []int, rewards []*models.Reward) (map[int]string, error) {
+	checked := make(map[batchHolding]bool)
+	for n, i := range items {
+		if rewards[n].Quantity.IsNegative() && !reqs[i].AllowNegative {
+			checked[batchHolding{reqs[i].UserID, reqs[i].StockSymbol}] = true
+		}
+	}
+	if len(checked) == 0 {
+		return nil, nil
+	}
+
+	// Lock in a fixed order so concurrent batches cannot deadlock
+	keys := make([]batchHolding, 0, len(checked))
+	for key := range checked {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].userID != keys[b].userID {
+			return keys[a].userID < keys[b].userID
+		}
+		return keys[a].stockSymbol < keys[b].stockSymbol
+	})
+
+	held := make(map[batchHolding]decimal.Decimal, len(keys))
+	for _, key := range keys {
+		if err := rs.rewardRepo.LockHolding(ctx, key.userID, key.stockSymbol); err != nil {
+			return nil, err
+		}
+		quantity, err := rs.rewardRepo.GetNetQuantity(ctx, key.userID, key.stockSymbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current holding: %w", err)
+		}
+		held[key] = quantity
+	}
+
+	rejected := make(map[int]string)
+	for n, i := range items {
+		key := batchHolding{reqs[i].UserID, reqs[i].StockSymbol}
+		if !checked[key] {
+			continue
+		}
+		// Rewards awaiting approval do not count until approved
+		if rewards[n].Status == models.RewardStatusPendingApproval {
+			continue
+		}
+		change := rewards[n].Quantity
+		if change.IsNegative() && !reqs[i].AllowNegative && held[key].Add(change).IsNegative() {
+			rejected[i] = fmt.Sprintf("%v: user %s holds %s %s, adjustment of %s would leave %s",
+				ErrInsufficientHoldings, key.userID, held[key], key.stockSymbol, change, held[key].Add(change))
+			continue
+		}
+		held[key] = held[key].Add(change)
+	}
+
+	return rejected, nil
+}
+
+// checkBatchRisk applies the risk rules to the items in batch order, so
+// earlier items count towards later ones. Users are locked in a fixed order
+// after any holdings. It returns the breach of each rejected item and the
+// rule of each item sent to review, by index.
+func (rs *RewardService) checkBatchRisk(ctx context.Context, items []int, rewards []*models.Reward) (map[int]*RiskBreach, map[int]string, error) {
+	activity := make(map[string]*models.UserRewardActivity)
+	if rs.risk.tracksUsers() {
+		for _, reward := range rewards {
+			if reward.Quantity.IsPositive() {
+				activity[reward.UserID] = nil
+			}
+		}
+		userIDs := make([]string, 0, len(activity))
+		for userID := range activity {
+			userIDs = append(userIDs, userID)
+		}
+		sort.Strings(userIDs)
+
+		for _, userID := range userIDs {
+			if err := rs.rewardRepo.LockUser(ctx, userID); err != nil {
+				return nil, nil, err
+			}
+			userActivity, err := rs.rewardRepo.GetUserActivity(ctx, userID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get user reward activity: %w", err)
+			}
+			activity[userID] = userActivity
+		}
+	}
+
+	rejected := make(map[int]*RiskBreach)
+	flagged := make(map[int]string)
+	for n, i := range items {
+		reward := rewards[n]
+		if !reward.Quantity.IsPositive() {
+			continue
+		}
+		rule, err := rs.applyRiskRules(reward, activity[reward.UserID])
+		if err != nil {
+			var breach *RiskBreach
+			if !errors.As(err, &breach) {
+				return nil, nil, err
+			}
+			rejected[i] = breach
+			continue
+		}
+		if rule != "" {
+			flagged[i] = rule
+		}
+		if userActivity := activity[reward.UserID]; userActivity != nil {
+			userActivity.RewardsToday++
+			userActivity.ValueINRThisMonth = userActivity.ValueINRThisMonth.Add(reward.TotalValueINR)
+		}
+	}
+
+	return rejected, flagged, nil
+}
+
+// newFailedRequestRecord builds the FAILED idempotency record for a request
+func newFailedRequestRecord(req *RewardRequest, code, message string) *models.RewardRequest {
+	record := newRewardRequestRecord(req)
+	record.Status = models.RequestStatusFailed
+	record.ErrorCode = &code
+	record.ErrorMessage = &message
+	return record
+}
+
+// recordBatchFailures stores failed batch items so they can be listed and replayed
+func (rs *RewardService) recordBatchFailures(ctx context.Context, failures []*models.RewardRequest) {
+	if err := rs.rewardRequestRepo.RecordFailures(ctx, failures); err != nil {
+		rs.log.Errorf("Failed to record %d batch failures: %v", len(failures), err)
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"stockBackend/internal/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrCampaignMismatch is returned when a campaign reward names a symbol,
+// quantity or event type other than the one its campaign resolves to
+var ErrCampaignMismatch = newError(KindValidation, "CAMPAIGN_MISMATCH", "request does not match campaign rule")
+
+// applyCampaign fills in symbol, quantity or amount and event type from the
+// request's campaign. Fields the caller already set must agree with the rule,
+// so a stored request resolves the same way when it is replayed.
+func (rs *RewardService) applyCampaign(ctx context.Context, req *RewardRequest) error {
+	if req.CampaignID == nil {
+		return nil
+	}
+
+	campaign, err := rs.campaignRepo.GetByID(ctx, *req.CampaignID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrCampaignNotFound, *req.CampaignID)
+		}
+		return fmt.Errorf("failed to load campaign: %w", err)
+	}
+
+	symbol := ""
+	switch campaign.RuleType {
+	case models.CampaignRuleRandomBasket:
+		symbol = pickBasketSymbol(campaign.Basket, req.EventID)
+	default:
+		if campaign.StockSymbol != nil {
+			symbol = *campaign.StockSymbol
+		}
+	}
+	if req.StockSymbol != "" && !strings.EqualFold(req.StockSymbol, symbol) {
+		return fmt.Errorf("%w: campaign %d rewards %s, not %s", ErrCampaignMismatch, campaign.ID, symbol, req.StockSymbol)
+	}
+	req.StockSymbol = symbol
+
+	if campaign.Quantity != nil {
+		if !req.AmountINR.IsZero() || (!req.Quantity.IsZero() && !req.Quantity.Equal(*campaign.Quantity)) {
+			return fmt.Errorf("%w: campaign %d rewards a quantity of %s", ErrCampaignMismatch, campaign.ID, *campaign.Quantity)
+		}
+		req.Quantity = *campaign.Quantity
+	}
+	if campaign.AmountINR != nil {
+		if !req.Quantity.IsZero() || (!req.AmountINR.IsZero() && !req.AmountINR.Equal(*campaign.AmountINR)) {
+			return fmt.Errorf("%w: campaign %d rewards %s INR", ErrCampaignMismatch, campaign.ID, *campaign.AmountINR)
+		}
+		req.AmountINR = *campaign.AmountINR
+	}
+
+	if req.EventType != "" && req.EventType != campaign.EventType {
+		return fmt.Errorf("%w: campaign %d is for %s events", ErrCampaignMismatch, campaign.ID, campaign.EventType)
+	}
+	req.EventType = campaign.EventType
+
+	return nil
+}
+
+// pickBasketSymbol picks a basket symbol at random but keyed on the event ID,
+// so retries and replays of an event always get the same stock
+func pickBasketSymbol(basket []string, eventID string) string {
+	if len(basket) == 0 {
+		return ""
+	}
+	h := fnv.New32a()
+	h.Write([]byte(eventID))
+	return basket[h.Sum32()%uint32(len(basket))]
+}
+
+// reserveCampaignSpend checks the reward's campaign is running at the event
+// time and reserves the reward's INR value against its total budget and the
+// user's cap; ctx must carry a transaction so a failed reward releases it
+func (rs *RewardService) reserveCampaignSpend(ctx context.Context, reward *models.Reward) error {
+	if reward.CampaignID == nil {
+		return nil
+	}
+
+	campaign, err := rs.campaignRepo.GetByID(ctx, *reward.CampaignID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrCampaignNotFound, *reward.CampaignID)
+		}
+		return fmt.Errorf("failed to load campaign: %w", err)
+	}
+
+	at := reward.EventTimestamp
+	if !campaign.Active || at.Before(campaign.StartsAt) || (campaign.EndsAt != nil && !at.Before(*campaign.EndsAt)) {
+		return fmt.Errorf("%w: campaign %d is not running at %s", ErrCampaignInactive, campaign.ID, at.Format(time.RFC3339))
+	}
+
+	amount := reward.TotalValueINR
+	reserved, err := rs.campaignRepo.ReserveBudget(ctx, campaign.ID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to reserve campaign budget: %w", err)
+	}
+	if !reserved {
+		return fmt.Errorf("%w: campaign %d cannot fund %s INR", ErrCampaignBudgetExhausted, campaign.ID, amount)
+	}
+
+	reserved, err = rs.campaignRepo.ReserveUserSpend(ctx, campaign.ID, reward.UserID, amount, campaign.PerUserCapINR)
+	if err != nil {
+		return fmt.Errorf("failed to reserve campaign user spend: %w", err)
+	}
+	if !reserved {
+		return fmt.Errorf("%w: user %s cannot receive %s INR more from campaign %d",
+			ErrCampaignUserCapReached, reward.UserID, amount, campaign.ID)
+	}
+
+	return nil
+}
+
+// releaseCampaignSpend returns a reward's reserved value to its campaign
+func (rs *RewardService) releaseCampaignSpend(ctx context.Context, reward *models.Reward) error {
+	if reward.CampaignID == nil {
+		return nil
+	}
+	return rs.campaignRepo.ReleaseSpend(ctx, *reward.CampaignID, reward.UserID, reward.TotalValueINR)
+}
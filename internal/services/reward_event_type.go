@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultLedgerEventType gives the accounts of rewards whose event type is
+// not registered, such as rewards booked before the registry existed
+var defaultLedgerEventType = &models.EventType{
+	Code:          defaultEventType,
+	CreditAccount: AccountRewardIncome,
+	DebitAccount:  AccountAdjustmentExpense,
+}
+
+// applyEventType checks the request against the rules of its event type
+// before the event is claimed. The type is loaded again inside the
+// transaction, where its value limit and accounts are applied.
+func (rs *RewardService) applyEventType(ctx context.Context, req *RewardRequest) error {
+	eventType, err := activeEventType(ctx, rs.eventTypeRepo, req.EventType)
+	if err != nil {
+		return err
+	}
+	return checkEventType(eventType, req)
+}
+
+// checkEventType applies the rules an event type sets on a request before
+// it is priced
+func checkEventType(eventType *models.EventType, req *RewardRequest) error {
+	if req.Quantity.IsNegative() && !eventType.AllowNegative {
+		return fmt.Errorf("%w: %s rewards cannot be negative", ErrEventTypeRule, eventType.Code)
+	}
+	if eventType.NotesRequired && strings.TrimSpace(req.Notes) == "" {
+		return fmt.Errorf("%w: %s rewards require notes", ErrEventTypeRule, eventType.Code)
+	}
+	if eventType.MaxQuantity != nil && req.Quantity.Abs().GreaterThan(*eventType.MaxQuantity) {
+		return fmt.Errorf("%w: quantity %s exceeds the %s maximum of %s",
+			ErrEventTypeRule, req.Quantity, eventType.Code, *eventType.MaxQuantity)
+	}
+	if eventType.MaxValueINR != nil && req.AmountINR.GreaterThan(*eventType.MaxValueINR) {
+		return fmt.Errorf("%w: amount_inr %s exceeds the %s maximum of %s INR",
+			ErrEventTypeRule, req.AmountINR, eventType.Code, *eventType.MaxValueINR)
+	}
+	return nil
+}
+
+// checkEventTypeValue applies an event type's limits to a priced reward, whose
+// quantity is only known now when it was requested as an INR amount
+func checkEventTypeValue(eventType *models.EventType, reward *models.Reward) error {
+	if eventType.MaxQuantity != nil && reward.Quantity.Abs().GreaterThan(*eventType.MaxQuantity) {
+		return fmt.Errorf("%w: quantity %s exceeds the %s maximum of %s",
+			ErrEventTypeRule, reward.Quantity, eventType.Code, *eventType.MaxQuantity)
+	}
+	if eventType.MaxValueINR != nil && reward.TotalValueINR.Abs().GreaterThan(*eventType.MaxValueINR) {
+		return fmt.Errorf("%w: value %s INR exceeds the %s maximum of %s INR",
+			ErrEventTypeRule, reward.TotalValueINR.Abs(), eventType.Code, *eventType.MaxValueINR)
+	}
+	return nil
+}
+
+// ledgerEventType returns the event type whose accounts a booked reward is
+// posted to. A deactivated type still posts the rewards booked under it.
+func ledgerEventType(ctx context.Context, eventTypeRepo repository.EventTypeRepository, code string) (*models.EventType, error) {
+	eventType, err := eventTypeRepo.GetByCode(ctx, code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return defaultLedgerEventType, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event type: %w", err)
+	}
+	return eventType, nil
+}
+
+// loadEventTypes returns every registered event type by code
+func (rs *RewardService) loadEventTypes(ctx context.Context) (map[string]*models.EventType, error) {
+	eventTypes, err := rs.eventTypeRepo.List(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event types: %w", err)
+	}
+	byCode := make(map[string]*models.EventType, len(eventTypes))
+	for _, eventType := range eventTypes {
+		byCode[eventType.Code] = eventType
+	}
+	return byCode, nil
+}
@@ -0,0 +1,101 @@
+//go:build integration
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"stockBackend/internal/db"
+	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// TestConcurrentDuplicateRewards sends parallel duplicates of reward requests
+// and checks that every duplicate gets the same reward. It needs a migrated
+// database holding the seed data:
+//
+//	DATABASE_URL=postgres://... go test -tags integration -run TestConcurrentDuplicateRewards ./internal/services
+func TestConcurrentDuplicateRewards(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+
+	const (
+		duplicates = 20
+		rounds     = 5
+	)
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer pool.Close()
+	db.InitDB(pool)
+
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+	rewardService := NewRewardService(
+		repository.NewRewardRepository(pool),
+		repository.NewLedgerRepository(pool),
+		repository.NewRewardRequestRepository(pool),
+		repository.NewUserRepository(pool),
+		repository.NewCampaignRepository(pool),
+		repository.NewVestingRepository(pool),
+		repository.NewRewardChargeRepository(pool),
+		repository.NewRewardQuoteRepository(pool),
+		repository.NewBasketRepository(pool),
+		repository.NewEventTypeRepository(pool),
+		repository.NewFeePlanRepository(pool),
+		NewPriceService(repository.NewStockPriceRepository(pool), cron.New(), log),
+		log,
+	)
+
+	for round := 0; round < rounds; round++ {
+		req := RewardRequest{
+			UserID:      "USR001",
+			StockSymbol: "AAPL",
+			Quantity:    decimal.NewFromInt(1),
+			EventID:     fmt.Sprintf("IDEMPOTENCY-CHECK-%d-%d", time.Now().UnixNano(), round),
+		}
+
+		rewardIDs := make([]int, duplicates)
+		errs := make([]error, duplicates)
+		start := make(chan struct{})
+		var done sync.WaitGroup
+		for i := 0; i < duplicates; i++ {
+			done.Add(1)
+			go func(i int) {
+				defer done.Done()
+				<-start
+				// Each duplicate gets its own copy; processing fills it in
+				dup := req
+				resp, err := rewardService.ProcessReward(ctx, &dup)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				rewardIDs[i] = resp.RewardID
+			}(i)
+		}
+		close(start)
+		done.Wait()
+
+		for i := range errs {
+			if errs[i] != nil {
+				t.Errorf("event %s: duplicate %d failed: %v", req.EventID, i, errs[i])
+			} else if rewardIDs[i] == 0 || rewardIDs[i] != rewardIDs[0] {
+				t.Errorf("event %s: duplicate %d got reward %d, duplicate 0 got %d", req.EventID, i, rewardIDs[i], rewardIDs[0])
+			}
+		}
+	}
+}
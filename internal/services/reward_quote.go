@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FailureCodeQuoteUnavailable is recorded when a quote expires or is used
+// between validation and booking
+const FailureCodeQuoteUnavailable = "QUOTE_UNAVAILABLE"
+
+var (
+	// ErrQuoteNotFound is returned when no quote exists for a quote ID
+	ErrQuoteNotFound = newError(KindNotFound, "QUOTE_NOT_FOUND", "reward quote not found")
+	// ErrQuoteExpired is returned when a quote's locked price has expired
+	ErrQuoteExpired = newError(KindConflict, "QUOTE_EXPIRED", "reward quote expired").
+			withStatus(http.StatusGone)
+	// ErrQuoteUsed is returned when a quote was already used by another reward
+	ErrQuoteUsed = newError(KindConflict, "QUOTE_USED", "reward quote already used")
+	// ErrQuoteMismatch is returned when a reward names a user, symbol or amount other than its quote's
+	ErrQuoteMismatch = newError(KindValidation, "QUOTE_MISMATCH", "request does not match reward quote")
+)
+
+// QuoteRequest represents a reward to price without booking it
+type QuoteRequest struct {
+	UserID      string          `json:"user_id" binding:"required"`
+	StockSymbol string          `json:"stock_symbol" binding:"required"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	AmountINR   decimal.Decimal `json:"amount_inr"`
+	DeductFees  bool            `json:"deduct_fees"`
+	// EventType is the type of the reward the quote is for; REWARD by default
+	EventType string `json:"event_type"`
+}
+
+// QuoteReward prices a reward with the same math as ProcessReward without
+// booking it. The price is locked for the quote TTL; a reward that sends the
+// returned quote_id before then is booked at that price.
+func (rs *RewardService) QuoteReward(ctx context.Context, req *QuoteRequest) (*models.RewardQuote, error) {
+	rewardReq := &RewardRequest{
+		UserID:      req.UserID,
+		StockSymbol: strings.ToUpper(req.StockSymbol),
+		Quantity:    req.Quantity,
+		AmountINR:   req.AmountINR,
+		DeductFees:  req.DeductFees,
+		EventType:   eventTypeCode(strings.ToUpper(req.EventType)),
+	}
+	if err := validateQuoteRequest(rewardReq); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if _, err := activeEventType(ctx, rs.eventTypeRepo, rewardReq.EventType); err != nil {
+		return nil, err
+	}
+
+	userExists, err := rs.userRepo.Exists(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+	if !userExists {
+		return nil, ErrUserNotFound
+	}
+
+	stockPrice, err := rs.priceService.GetPriceAt(ctx, rewardReq.StockSymbol, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock price: %w", err)
+	}
+	// The quote is charged like a reward of its event type booked now, and
+	// the reward that uses it is charged by the same plan
+	plan, err := feePlanAt(ctx, rs.feePlanRepo, rewardReq.StockSymbol, rewardReq.EventType, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	reward, err := rs.buildReward(rewardReq, stockPrice, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	quoteID, err := newQuoteID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate quote ID: %w", err)
+	}
+	quote := &models.RewardQuote{
+		QuoteID:        quoteID,
+		UserID:         reward.UserID,
+		StockSymbol:    reward.StockSymbol,
+		Quantity:       reward.Quantity,
+		AmountINR:      reward.RequestedAmountINR,
+		DeductFees:     reward.DeductFees,
+		EventType:      rewardReq.EventType,
+		StockPrice:     stockPrice.Price,
+		StockPriceID:   stockPrice.ID,
+		TotalValueINR:  reward.TotalValueINR,
+		BrokerageFee:   reward.BrokerageFee,
+		TransactionFee: reward.TransactionFee,
+		NetValueINR:    reward.NetValueINR,
+		Charges:        reward.Charges,
+		FeePlanID:      reward.FeePlanID,
+		ExpiresAt:      time.Now().Add(rs.quoteTTL),
+	}
+	if err := rs.quoteRepo.Create(ctx, quote); err != nil {
+		return nil, err
+	}
+
+	rs.log.Infof("Quoted %s %s at %s INR for user %s as %s",
+		quote.Quantity, quote.StockSymbol, quote.StockPrice, quote.UserID, quote.QuoteID)
+	return quote, nil
+}
+
+// applyQuote fills in symbol, event type and quantity or amount from the
+// request's quote.
+// Fields the caller already set must agree with the quote. A quote the same
+// event already used passes, so the request can be replayed after expiry.
+func (rs *RewardService) applyQuote(ctx context.Context, req *RewardRequest) error {
+	if req.QuoteID == "" {
+		return nil
+	}
+	if req.CampaignID != nil {
+		return fmt.Errorf("%w: quote_id cannot be combined with campaign_id", ErrQuoteMismatch)
+	}
+	if !req.EventTimestamp.IsZero() {
+		return fmt.Errorf("%w: a quoted reward is priced at the quote, so event_timestamp cannot be set", ErrQuoteMismatch)
+	}
+
+	quote, err := rs.quoteRepo.GetByQuoteID(ctx, req.QuoteID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrQuoteNotFound, req.QuoteID)
+		}
+		return fmt.Errorf("failed to load reward quote: %w", err)
+	}
+
+	if req.UserID != quote.UserID {
+		return fmt.Errorf("%w: quote %s is for user %s", ErrQuoteMismatch, quote.QuoteID, quote.UserID)
+	}
+	if req.StockSymbol != "" && !strings.EqualFold(req.StockSymbol, quote.StockSymbol) {
+		return fmt.Errorf("%w: quote %s is for %s, not %s", ErrQuoteMismatch, quote.QuoteID, quote.StockSymbol, req.StockSymbol)
+	}
+	req.StockSymbol = quote.StockSymbol
+	if req.EventType != "" && !strings.EqualFold(req.EventType, quote.EventType) {
+		return fmt.Errorf("%w: quote %s is for a %s reward, not %s", ErrQuoteMismatch, quote.QuoteID, quote.EventType, req.EventType)
+	}
+	req.EventType = quote.EventType
+
+	if quote.AmountINR != nil {
+		if !req.Quantity.IsZero() || (!req.AmountINR.IsZero() && !req.AmountINR.Equal(*quote.AmountINR)) ||
+			(req.DeductFees && !quote.DeductFees) {
+			return fmt.Errorf("%w: quote %s is for %s INR", ErrQuoteMismatch, quote.QuoteID, *quote.AmountINR)
+		}
+		req.AmountINR = *quote.AmountINR
+		req.DeductFees = quote.DeductFees
+	} else {
+		if !req.AmountINR.IsZero() || (!req.Quantity.IsZero() && !req.Quantity.Equal(quote.Quantity)) {
+			return fmt.Errorf("%w: quote %s is for a quantity of %s", ErrQuoteMismatch, quote.QuoteID, quote.Quantity)
+		}
+		req.Quantity = quote.Quantity
+	}
+
+	if quote.UsedByEventID != nil {
+		if *quote.UsedByEventID == req.EventID {
+			return nil
+		}
+		return fmt.Errorf("%w: quote %s was used by event %s", ErrQuoteUsed, quote.QuoteID, *quote.UsedByEventID)
+	}
+	if !time.Now().Before(quote.ExpiresAt) {
+		return fmt.Errorf("%w: quote %s expired at %s", ErrQuoteExpired, quote.QuoteID, quote.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// redeemQuote marks the request's quote used by its event and returns the
+// locked price and the fee plan the quote was charged by, nil for
+// CHARGES_PLAN; ctx must carry a transaction so a failed booking frees the quote
+func (rs *RewardService) redeemQuote(ctx context.Context, req *RewardRequest) (*models.StockPrice, *models.FeePlan, error) {
+	redeemed, err := rs.quoteRepo.Redeem(ctx, req.QuoteID, req.EventID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to redeem reward quote: %w", err)
+	}
+
+	quote, err := rs.quoteRepo.GetByQuoteID(ctx, req.QuoteID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load reward quote: %w", err)
+	}
+	if !redeemed {
+		cause := ErrQuoteExpired
+		if quote.UsedByEventID != nil {
+			cause = ErrQuoteUsed
+		}
+		return nil, nil, failWith(FailureCodeQuoteUnavailable, fmt.Errorf("%w: %s", cause, quote.QuoteID))
+	}
+
+	var plan *models.FeePlan
+	if quote.FeePlanID != nil {
+		if plan, err = rs.feePlanRepo.GetByID(ctx, *quote.FeePlanID); err != nil {
+			return nil, nil, fmt.Errorf("failed to load the quote's fee plan: %w", err)
+		}
+	}
+
+	return &models.StockPrice{
+		ID:          quote.StockPriceID,
+		StockSymbol: quote.StockSymbol,
+		Price:       quote.StockPrice,
+	}, plan, nil
+}
+
+// validateQuoteRequest checks that a quote describes a reward that grants stock
+func validateQuoteRequest(req *RewardRequest) error {
+	if req.StockSymbol == "" {
+		return fmt.Errorf("stock_symbol is required")
+	}
+	if req.Quantity.IsNegative() || req.AmountINR.IsNegative() {
+		return fmt.Errorf("quantity and amount_inr must be positive")
+	}
+	if req.Quantity.IsZero() == req.AmountINR.IsZero() {
+		return fmt.Errorf("exactly one of quantity or amount_inr is required")
+	}
+	if req.DeductFees && req.AmountINR.IsZero() {
+		return fmt.Errorf("deduct_fees requires amount_inr")
+	}
+	if err := normalizePlaces("quantity", &req.Quantity, models.QuantityScale); err != nil {
+		return err
+	}
+	return normalizePlaces("amount_inr", &req.AmountINR, models.AmountScale)
+}
+
+// newQuoteID returns a random, unguessable quote ID
+func newQuoteID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "QT-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"strconv"
+	"strings"
+)
+
+// Risk rules checked before a reward that grants stock is booked
+const (
+	RiskRuleRewardsPerDay    = "MAX_REWARDS_PER_USER_PER_DAY"
+	RiskRuleValuePerMonth    = "MAX_INR_PER_USER_PER_MONTH"
+	RiskRuleQuantityPerEvent = "MAX_QUANTITY_PER_EVENT"
+)
+
+// What happens to a reward that breaches a risk rule
+const (
+	RiskActionReject = "reject"
+	RiskActionReview = "review"
+)
+
+// FailureCodeRiskLimit is recorded on requests rejected by a risk rule
+const FailureCodeRiskLimit = "RISK_LIMIT_EXCEEDED"
+
+// ErrRiskLimitExceeded is returned when a reward is rejected by a risk rule
+var ErrRiskLimitExceeded = newError(KindConflict, "RISK_LIMIT_EXCEEDED", "risk limit exceeded").
+	withStatus(http.StatusUnprocessableEntity)
+
+// RiskBreach describes the risk rule a reward breached
+type RiskBreach struct {
+	Rule   string
+	Detail string
+}
+
+func (b *RiskBreach) Error() string {
+	return fmt.Sprintf("%v: %s: %s", ErrRiskLimitExceeded, b.Rule, b.Detail)
+}
+
+// Unwrap lets callers match a breach with errors.Is(err, ErrRiskLimitExceeded)
+func (b *RiskBreach) Unwrap() error { return ErrRiskLimitExceeded }
+
+// riskRules holds the configured limits; a zero limit is disabled
+type riskRules struct {
+	maxRewardsPerDay    int
+	maxINRPerMonth      decimal.Decimal
+	maxQuantityPerEvent decimal.Decimal
+	// maxQuantityBySymbol overrides maxQuantityPerEvent for single symbols
+	maxQuantityBySymbol map[string]decimal.Decimal
+	action              string
+}
+
+// loadRiskRules reads the risk rules from the environment:
+//
+//	RISK_MAX_REWARDS_PER_USER_PER_DAY=50
+//	RISK_MAX_INR_PER_USER_PER_MONTH=100000
+//	RISK_MAX_QUANTITY_PER_EVENT=100
+//	RISK_MAX_QUANTITY_PER_SYMBOL=RELIANCE:20,TCS:10
+//	RISK_ACTION=reject|review
+func loadRiskRules() riskRules {
+	rules := riskRules{
+		maxQuantityBySymbol: make(map[string]decimal.Decimal),
+		action:              RiskActionReject,
+	}
+
+	if v := os.Getenv("RISK_MAX_REWARDS_PER_USER_PER_DAY"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val >= 0 {
+			rules.maxRewardsPerDay = val
+		}
+	}
+	if v := os.Getenv("RISK_MAX_INR_PER_USER_PER_MONTH"); v != "" {
+		if val, err := decimal.NewFromString(v); err == nil && !val.IsNegative() {
+			rules.maxINRPerMonth = val
+		}
+	}
+	if v := os.Getenv("RISK_MAX_QUANTITY_PER_EVENT"); v != "" {
+		if val, err := decimal.NewFromString(v); err == nil && !val.IsNegative() {
+			rules.maxQuantityPerEvent = val
+		}
+	}
+	for _, pair := range strings.Split(os.Getenv("RISK_MAX_QUANTITY_PER_SYMBOL"), ",") {
+		symbol, limit, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			continue
+		}
+		if val, err := decimal.NewFromString(limit); err == nil && val.IsPositive() {
+			rules.maxQuantityBySymbol[strings.ToUpper(symbol)] = val
+		}
+	}
+	if os.Getenv("RISK_ACTION") == RiskActionReview {
+		rules.action = RiskActionReview
+	}
+
+	return rules
+}
+
+// tracksUsers reports whether any rule depends on a user's past rewards
+func (r riskRules) tracksUsers() bool {
+	return r.maxRewardsPerDay > 0 || r.maxINRPerMonth.IsPositive()
+}
+
+// evaluate checks a reward against the rules, given the user's activity
+// before it. Only rewards that grant stock are checked.
+func (r riskRules) evaluate(reward *models.Reward, activity *models.UserRewardActivity) *RiskBreach {
+	if !reward.Quantity.IsPositive() {
+		return nil
+	}
+
+	limit := r.maxQuantityPerEvent
+	if symbolLimit, ok := r.maxQuantityBySymbol[reward.StockSymbol]; ok {
+		limit = symbolLimit
+	}
+	if limit.IsPositive() && reward.Quantity.GreaterThan(limit) {
+		return &RiskBreach{
+			Rule:   RiskRuleQuantityPerEvent,
+			Detail: fmt.Sprintf("%s %s exceeds the limit of %s", reward.Quantity, reward.StockSymbol, limit),
+		}
+	}
+
+	if activity == nil {
+		return nil
+	}
+	if r.maxRewardsPerDay > 0 && activity.RewardsToday+1 > r.maxRewardsPerDay {
+		return &RiskBreach{
+			Rule:   RiskRuleRewardsPerDay,
+			Detail: fmt.Sprintf("user %s already has %d rewards today, limit %d", reward.UserID, activity.RewardsToday, r.maxRewardsPerDay),
+		}
+	}
+	monthValue := activity.ValueINRThisMonth.Add(reward.TotalValueINR)
+	if r.maxINRPerMonth.IsPositive() && monthValue.GreaterThan(r.maxINRPerMonth) {
+		return &RiskBreach{
+			Rule: RiskRuleValuePerMonth,
+			Detail: fmt.Sprintf("user %s would reach %s INR this month, limit %s",
+				reward.UserID, monthValue, r.maxINRPerMonth),
+		}
+	}
+	return nil
+}
+
+// applyRiskRules checks a reward against the risk rules. A breach either
+// returns an error, or, with RISK_ACTION=review, sends the reward to the
+// approval queue and returns the rule that fired.
+func (rs *RewardService) applyRiskRules(reward *models.Reward, activity *models.UserRewardActivity) (string, error) {
+	breach := rs.risk.evaluate(reward, activity)
+	if breach == nil {
+		return "", nil
+	}
+
+	rs.log.Warnf("Reward for event %s breached %s: %s", reward.EventID, breach.Rule, breach.Detail)
+	if rs.risk.action != RiskActionReview {
+		return "", failWith(FailureCodeRiskLimit, breach)
+	}
+	reward.Status = models.RewardStatusPendingApproval
+	return breach.Rule, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"testing"
+)
+
+func TestRiskRulesEvaluate(t *testing.T) {
+	rules := riskRules{
+		maxRewardsPerDay:    3,
+		maxINRPerMonth:      decimal.RequireFromString("10000"),
+		maxQuantityPerEvent: decimal.RequireFromString("100"),
+		maxQuantityBySymbol: map[string]decimal.Decimal{
+			"MRF": decimal.RequireFromString("2"),
+			"ITC": decimal.RequireFromString("500"),
+		},
+		action: RiskActionReject,
+	}
+	quiet := &models.UserRewardActivity{ValueINRThisMonth: decimal.Zero}
+
+	tests := []struct {
+		name     string
+		symbol   string
+		quantity string
+		value    string
+		activity *models.UserRewardActivity
+		want     string // rule breached, empty for none
+	}{
+		{"within every limit", "TCS", "10", "500.00", quiet, ""},
+		{"event limit reached", "TCS", "100", "500.00", quiet, ""},
+		{"event limit exceeded", "TCS", "100.000001", "500.00", quiet, RiskRuleQuantityPerEvent},
+		{"symbol limit below the event limit", "MRF", "2.5", "500.00", quiet, RiskRuleQuantityPerEvent},
+		{"symbol limit above the event limit", "ITC", "400", "500.00", quiet, ""},
+		{"symbol limit exceeded", "ITC", "501", "500.00", quiet, RiskRuleQuantityPerEvent},
+		{"last reward of the day", "TCS", "1", "500.00", &models.UserRewardActivity{RewardsToday: 2}, ""},
+		{"day limit exceeded", "TCS", "1", "500.00", &models.UserRewardActivity{RewardsToday: 3}, RiskRuleRewardsPerDay},
+		{"month limit reached", "TCS", "1", "500.00", &models.UserRewardActivity{ValueINRThisMonth: decimal.RequireFromString("9500.00")}, ""},
+		{"month limit exceeded", "TCS", "1", "500.01", &models.UserRewardActivity{ValueINRThisMonth: decimal.RequireFromString("9500.00")}, RiskRuleValuePerMonth},
+		// The size of a single event is checked before the user's history
+		{"event before day", "TCS", "101", "500.00", &models.UserRewardActivity{RewardsToday: 3}, RiskRuleQuantityPerEvent},
+		{"day before month", "TCS", "1", "5000.00", &models.UserRewardActivity{RewardsToday: 3, ValueINRThisMonth: decimal.RequireFromString("9500.00")}, RiskRuleRewardsPerDay},
+		{"no history loaded", "TCS", "1", "50000.00", nil, ""},
+		{"negative adjustments are not checked", "MRF", "-50", "-5000.00", &models.UserRewardActivity{RewardsToday: 3}, ""},
+	}
+	for _, tt := range tests {
+		reward := &models.Reward{
+			UserID:        "USR001",
+			StockSymbol:   tt.symbol,
+			Quantity:      decimal.RequireFromString(tt.quantity),
+			TotalValueINR: decimal.RequireFromString(tt.value),
+		}
+		breach := rules.evaluate(reward, tt.activity)
+		got := ""
+		if breach != nil {
+			got = breach.Rule
+			if !errors.Is(breach, ErrRiskLimitExceeded) {
+				t.Errorf("%s: breach does not match ErrRiskLimitExceeded", tt.name)
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: breached %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Disabled rules never fire
+	if breach := (riskRules{}).evaluate(&models.Reward{Quantity: decimal.NewFromInt(1000000)}, &models.UserRewardActivity{RewardsToday: 1000}); breach != nil {
+		t.Errorf("zero limits breached %s", breach.Rule)
+	}
+}
+
+func TestRiskRulesSymbolCase(t *testing.T) {
+	t.Setenv("RISK_MAX_QUANTITY_PER_SYMBOL", "mrf:2, TCS:10")
+	rules := loadRiskRules()
+
+	// A request's symbol is upper-cased before it reaches the rules, so
+	// the per-symbol limit applies however the symbol was sent
+	rs := &RewardService{}
+	req := &RewardRequest{UserID: "USR001", StockSymbol: "mrf", Quantity: decimal.NewFromInt(3), EventID: "EVT-RISK-1"}
+	if err := rs.validateRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	breach := rules.evaluate(&models.Reward{StockSymbol: req.StockSymbol, Quantity: req.Quantity}, nil)
+	if breach == nil || breach.Rule != RiskRuleQuantityPerEvent {
+		t.Errorf("3 %s breached %v, want the per-symbol limit", req.StockSymbol, breach)
+	}
+	if limit := rules.maxQuantityBySymbol["TCS"]; limit.String() != "10" {
+		t.Errorf("TCS limit = %s, want 10", limit)
+	}
+}
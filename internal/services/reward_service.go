@@ -3,55 +3,187 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
+	"net/http"
 	"os"
+	"stockBackend/internal/db"
 	"stockBackend/internal/models"
 	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/sirupsen/logrus"
 )
 
+var (
+	// ErrRewardNotFound is returned when no reward exists for an event ID
+	ErrRewardNotFound = newError(KindNotFound, "REWARD_NOT_FOUND", "reward not found")
+	// ErrRewardAlreadyReversed is returned when reversing a reward that is no longer COMPLETED
+	ErrRewardAlreadyReversed = newError(KindConflict, "REWARD_ALREADY_REVERSED", "reward already reversed")
+	// ErrReversalIDInUse is returned when a reversal ID belongs to an unrelated event
+	ErrReversalIDInUse = newError(KindConflict, "REVERSAL_ID_IN_USE", "reversal_id already used by another event")
+	// ErrIdempotencyConflict is returned when an event_id is reused with a different payload
+	ErrIdempotencyConflict = newError(KindConflict, "IDEMPOTENCY_CONFLICT", "idempotency key conflict")
+	// ErrRequestInProgress is returned while another request for the same event_id is in flight
+	ErrRequestInProgress = &Error{
+		Kind:       KindUnavailable,
+		Code:       "REQUEST_IN_PROGRESS",
+		Message:    "request already processing",
+		RetryAfter: RequestInProgressRetryAfter,
+		Status:     http.StatusTooEarly,
+	}
+	// ErrRequestFailed is returned to a duplicate whose concurrent original failed
+	ErrRequestFailed = newError(KindConflict, "REQUEST_FAILED", "request for this event_id failed")
+	// ErrRequestNotFound is returned when no reward request exists for an event ID
+	ErrRequestNotFound = newError(KindNotFound, "REQUEST_NOT_FOUND", "reward request not found")
+	// ErrRequestNotReplayable is returned when replaying a request that has not failed
+	ErrRequestNotReplayable = newError(KindConflict, "REQUEST_NOT_REPLAYABLE", "only FAILED requests can be replayed")
+	// errClaimLost means a concurrent request with the same event_id claimed it first
+	errClaimLost = errors.New("idempotency claim lost")
+	// ErrInsufficientHoldings is returned when a negative reward would leave a holding below zero
+	ErrInsufficientHoldings = newError(KindConflict, "INSUFFICIENT_HOLDINGS", "insufficient holdings").
+				withStatus(http.StatusUnprocessableEntity)
+)
+
+// RequestInProgressRetryAfter is the retry hint given to callers of an in-flight event
+const RequestInProgressRetryAfter = 2 * time.Second
+
+// EventTypeReversal is the event type of the negative reward that reverses another
+const EventTypeReversal = "REVERSAL"
+
+// Failure codes recorded on FAILED reward requests
+const (
+	FailureCodePriceUnavailable = "PRICE_UNAVAILABLE"
+	FailureCodeRewardWrite      = "REWARD_WRITE_FAILED"
+	FailureCodeLedgerWrite      = "LEDGER_WRITE_FAILED"
+	FailureCodeStuck            = "STUCK_PROCESSING"
+	FailureCodeInvalidPayload   = "INVALID_PAYLOAD"
+	FailureCodeInternal         = "INTERNAL_ERROR"
+)
+
+// processingError tags a write-path failure with the code recorded on the request
+type processingError struct {
+	code string
+	err  error
+}
+
+func (e *processingError) Error() string { return e.err.Error() }
+func (e *processingError) Unwrap() error { return e.err }
+
+// failWith wraps err so it is recorded on the request under code
+func failWith(code string, err error) error {
+	return &processingError{code: code, err: err}
+}
+
+// failureCode returns the recorded code for a write-path error
+func failureCode(err error) string {
+	var perr *processingError
+	if errors.As(err, &perr) {
+		return perr.code
+	}
+	return FailureCodeInternal
+}
+
 // RewardService handles reward operations
 type RewardService struct {
 	rewardRepo        repository.RewardRepository
 	ledgerRepo        repository.LedgerRepository
 	rewardRequestRepo repository.RewardRequestRepository
 	userRepo          repository.UserRepository
+	campaignRepo      repository.CampaignRepository
+	vestingRepo       repository.VestingRepository
+	chargeRepo        repository.RewardChargeRepository
+	quoteRepo         repository.RewardQuoteRepository
+	basketRepo        repository.BasketRepository
+	eventTypeRepo     repository.EventTypeRepository
+	feePlanRepo       repository.FeePlanRepository
 	priceService      *PriceService
 	log               *logrus.Logger
-	brokeragePercent  float64
-	feePercent        float64
+	charges           ChargesCalculator
+	quantityPrecision int
+	quantityRounding  string
+	// approvalThresholdINR sends larger rewards through approval; 0 disables it
+	approvalThresholdINR decimal.Decimal
+	risk                 riskRules
+	// quoteTTL is how long a quote's price stays locked
+	quoteTTL time.Duration
 }
 
 // RewardRequest represents an incoming reward request
 type RewardRequest struct {
-	UserID         string    `json:"user_id" binding:"required"`
-	StockSymbol    string    `json:"stock_symbol" binding:"required"`
-	Quantity       float64   `json:"quantity" binding:"required"`
-	EventID        string    `json:"event_id" binding:"required"`
-	EventTimestamp time.Time `json:"event_timestamp"`
-	EventType      string    `json:"event_type"`
-	Notes          string    `json:"notes"`
+	UserID         string          `json:"user_id" binding:"required"`
+	StockSymbol    string          `json:"stock_symbol"`
+	Quantity       decimal.Decimal `json:"quantity"` // at most 6 places
+	EventID        string          `json:"event_id" binding:"required"`
+	EventTimestamp time.Time       `json:"event_timestamp"`
+	EventType      string          `json:"event_type"`
+	Notes          string          `json:"notes"`
+	// AmountINR requests a reward worth this many rupees instead of a
+	// quantity; at most 2 places
+	AmountINR decimal.Decimal `json:"amount_inr"`
+	// DeductFees pays fees out of AmountINR instead of on top of it
+	DeductFees bool `json:"deduct_fees,omitempty"`
+	// AllowNegative lets an ops adjustment take a holding below zero
+	AllowNegative bool `json:"allow_negative,omitempty"`
+	// CreatedBy identifies the maker; required when the reward needs approval
+	CreatedBy string `json:"created_by,omitempty"`
+	// CampaignID resolves symbol, quantity and event type from a campaign's rule
+	CampaignID *int `json:"campaign_id,omitempty"`
+	// Vesting keeps the rewarded stock unvested until its tranches vest
+	Vesting *models.VestingSchedule `json:"vesting,omitempty"`
+	// QuoteID books the reward at the price locked by an unexpired quote
+	QuoteID string `json:"quote_id,omitempty"`
+	// BasketID splits AmountINR across a basket's symbols, one reward per leg
+	BasketID *int `json:"basket_id,omitempty"`
+
+	// fingerprint identifies the request as the caller sent it, before a
+	// campaign, quote or basket fills in its fields
+	fingerprint string
 }
 
 // RewardResponse represents the response after processing a reward
 type RewardResponse struct {
-	RewardID       int       `json:"reward_id"`
-	UserID         string    `json:"user_id"`
-	StockSymbol    string    `json:"stock_symbol"`
-	Quantity       float64   `json:"quantity"`
-	StockPrice     float64   `json:"stock_price"`
-	TotalValueINR  float64   `json:"total_value_inr"`
-	BrokerageFee   float64   `json:"brokerage_fee"`
-	TransactionFee float64   `json:"transaction_fee"`
-	NetValueINR    float64   `json:"net_value_inr"`
-	EventID        string    `json:"event_id"`
-	Status         string    `json:"status"`
-	Message        string    `json:"message"`
-	Timestamp      time.Time `json:"timestamp"`
+	RewardID int    `json:"reward_id,omitempty"`
+	UserID   string `json:"user_id"`
+	// StockSymbol, Quantity and StockPrice are left out of basket responses
+	StockSymbol    string           `json:"stock_symbol,omitempty"`
+	Quantity       *decimal.Decimal `json:"quantity,omitempty"`
+	StockPrice     *decimal.Decimal `json:"stock_price,omitempty"`
+	StockPriceID   *int             `json:"stock_price_id,omitempty"`
+	TotalValueINR  decimal.Decimal  `json:"total_value_inr"`
+	BrokerageFee   decimal.Decimal  `json:"brokerage_fee"`
+	TransactionFee decimal.Decimal  `json:"transaction_fee"`
+	NetValueINR    decimal.Decimal  `json:"net_value_inr"`
+	// Charges itemizes the brokerage and transaction fee, set by the fee plan
+	// FeePlanID, or by CHARGES_PLAN when it is nil
+	Charges   []*models.RewardCharge `json:"charges,omitempty"`
+	FeePlanID *int                   `json:"fee_plan_id,omitempty"`
+	// Set only for rewards requested as an INR amount
+	RequestedAmountINR *decimal.Decimal `json:"requested_amount_inr,omitempty"`
+	ExecutedAmountINR  *decimal.Decimal `json:"executed_amount_inr,omitempty"`
+	DeductFees         bool             `json:"deduct_fees,omitempty"`
+	EventID            string           `json:"event_id"`
+	Status             string           `json:"status"`
+	RiskRule           string           `json:"risk_rule,omitempty"`
+	Message            string           `json:"message"`
+	Timestamp          time.Time        `json:"timestamp"`
+
+	// Set only for vesting rewards
+	Vesting *models.VestingSchedule `json:"vesting,omitempty"`
+
+	// Set only for basket rewards, whose values and fees total the legs
+	BasketID *int              `json:"basket_id,omitempty"`
+	Legs     []*RewardResponse `json:"legs,omitempty"`
+}
+
+// ReversalRequest represents a request to reverse a previously processed reward
+type ReversalRequest struct {
+	ReversalID string `json:"reversal_id" binding:"required"`
+	Reason     string `json:"reason"`
 }
 
 // NewRewardService creates a new reward service
@@ -60,32 +192,61 @@ func NewRewardService(
 	ledgerRepo repository.LedgerRepository,
 	rewardRequestRepo repository.RewardRequestRepository,
 	userRepo repository.UserRepository,
+	campaignRepo repository.CampaignRepository,
+	vestingRepo repository.VestingRepository,
+	chargeRepo repository.RewardChargeRepository,
+	quoteRepo repository.RewardQuoteRepository,
+	basketRepo repository.BasketRepository,
+	eventTypeRepo repository.EventTypeRepository,
+	feePlanRepo repository.FeePlanRepository,
 	priceService *PriceService,
 	log *logrus.Logger,
 ) *RewardService {
-	brokeragePercent := 0.1 // Default 0.1%
-	feePercent := 0.05      // Default 0.05%
+	quantityPrecision := maxQuantityPrecision
+	quantityRounding := QuantityRoundDown
+	approvalThresholdINR := decimal.Zero
+	quoteTTL := 2 * time.Minute
 
-	if bp := os.Getenv("BROKERAGE_PERCENT"); bp != "" {
-		if val, err := strconv.ParseFloat(bp, 64); err == nil {
-			brokeragePercent = val
+	if qp := os.Getenv("QUANTITY_PRECISION"); qp != "" {
+		if val, err := strconv.Atoi(qp); err == nil && val >= 0 && val <= maxQuantityPrecision {
+			quantityPrecision = val
 		}
 	}
-	if fp := os.Getenv("TRANSACTION_FEE_PERCENT"); fp != "" {
-		if val, err := strconv.ParseFloat(fp, 64); err == nil {
-			feePercent = val
+	if at := os.Getenv("REWARD_APPROVAL_THRESHOLD_INR"); at != "" {
+		if val, err := decimal.NewFromString(at); err == nil && !val.IsNegative() {
+			approvalThresholdINR = val
 		}
 	}
+	if qt := os.Getenv("REWARD_QUOTE_TTL_SECONDS"); qt != "" {
+		if val, err := strconv.Atoi(qt); err == nil && val > 0 {
+			quoteTTL = time.Duration(val) * time.Second
+		}
+	}
+	switch qr := os.Getenv("QUANTITY_ROUNDING"); qr {
+	case QuantityRoundNearest, QuantityRoundUp:
+		quantityRounding = qr
+	}
 
 	return &RewardService{
-		rewardRepo:        rewardRepo,
-		ledgerRepo:        ledgerRepo,
-		rewardRequestRepo: rewardRequestRepo,
-		userRepo:          userRepo,
-		priceService:      priceService,
-		log:               log,
-		brokeragePercent:  brokeragePercent,
-		feePercent:        feePercent,
+		rewardRepo:           rewardRepo,
+		ledgerRepo:           ledgerRepo,
+		rewardRequestRepo:    rewardRequestRepo,
+		userRepo:             userRepo,
+		campaignRepo:         campaignRepo,
+		vestingRepo:          vestingRepo,
+		chargeRepo:           chargeRepo,
+		quoteRepo:            quoteRepo,
+		basketRepo:           basketRepo,
+		eventTypeRepo:        eventTypeRepo,
+		feePlanRepo:          feePlanRepo,
+		priceService:         priceService,
+		log:                  log,
+		charges:              chargesFromEnv(),
+		quantityPrecision:    quantityPrecision,
+		quantityRounding:     quantityRounding,
+		approvalThresholdINR: approvalThresholdINR,
+		risk:                 loadRiskRules(),
+		quoteTTL:             quoteTTL,
 	}
 }
 
@@ -93,26 +254,45 @@ func NewRewardService(
 func (rs *RewardService) ProcessReward(ctx context.Context, req *RewardRequest) (*RewardResponse, error) {
 	rs.log.Infof("Processing reward request for user %s, event %s", req.UserID, req.EventID)
 
-	// Step 1: Validate request
+	// Fingerprint the request as received: resolving it below fills in
+	// fields that may differ between two sends of the same request
+	if req.fingerprint == "" {
+		normalizeVesting(req.Vesting)
+		req.fingerprint = fingerprintRequest(req)
+	}
+
+	// Step 1: Resolve campaign, quoted and basket rewards, then validate request
+	if err := rs.applyCampaign(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := rs.applyQuote(ctx, req); err != nil {
+		return nil, err
+	}
+	if err := rs.applyBasket(ctx, req); err != nil {
+		return nil, err
+	}
 	if err := rs.validateRequest(req); err != nil {
-		return nil, fmt.Errorf("validation failed: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrValidation, err)
+	}
+	if err := rs.applyEventType(ctx, req); err != nil {
+		return nil, err
 	}
 
 	// Step 2: Check idempotency - has this event been processed before?
+	fingerprint := req.fingerprint
+	retry := false
 	existingRequest, err := rs.rewardRequestRepo.GetByEventID(ctx, req.EventID)
 	if err == nil && existingRequest != nil {
-		rs.log.Warnf("Duplicate request detected for event %s", req.EventID)
-		
-		// If already completed, return the previous response
-		if existingRequest.Status == "COMPLETED" && existingRequest.ResponsePayload != nil {
-			var response RewardResponse
-			if err := json.Unmarshal([]byte(*existingRequest.ResponsePayload), &response); err == nil {
-				response.Message = "Duplicate request - returning previous result"
-				return &response, nil
-			}
+		if existingRequest.Status != models.RequestStatusFailed {
+			rs.log.Warnf("Duplicate request detected for event %s", req.EventID)
+			return rs.replayResponse(ctx, existingRequest, fingerprint)
+		}
+		// Failures are not cached: the same payload may be attempted again
+		if err := checkFingerprint(existingRequest, fingerprint); err != nil {
+			return nil, err
 		}
-		
-		return nil, fmt.Errorf("request already processing or failed")
+		rs.log.Infof("Retrying failed request for event %s (attempt %d)", req.EventID, existingRequest.AttemptCount+1)
+		retry = true
 	}
 
 	// Step 3: Ensure user exists
@@ -121,110 +301,636 @@ func (rs *RewardService) ProcessReward(ctx context.Context, req *RewardRequest)
 		return nil, fmt.Errorf("failed to check user existence: %w", err)
 	}
 	if !userExists {
-		return nil, fmt.Errorf("user %s does not exist", req.UserID)
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, req.UserID)
 	}
 
-	// Step 4: Create idempotency record
-	requestPayload, _ := json.Marshal(req)
-	rewardRequest := &models.RewardRequest{
-		EventID:        req.EventID,
-		UserID:         req.UserID,
-		StockSymbol:    req.StockSymbol,
-		Quantity:       req.Quantity,
-		RequestPayload: string(requestPayload),
-		Status:         "PROCESSING",
-	}
-	
-	if err := rs.rewardRequestRepo.Create(ctx, rewardRequest); err != nil {
+	// Steps 4-9 run in one transaction so the idempotency record, reward,
+	// ledger entries and final response commit or roll back together
+	var response *RewardResponse
+	err = db.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		response, err = rs.processRewardTx(ctx, req, retry)
+		return err
+	})
+	if errors.Is(err, errClaimLost) {
+		// The claim waited for the winning request to finish, so its result is stored
+		rs.log.Warnf("Concurrent duplicate request for event %s", req.EventID)
+		existingRequest, err := rs.rewardRequestRepo.GetByEventID(ctx, req.EventID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load concurrent request: %w", err)
+		}
+		return rs.replayResponse(ctx, existingRequest, fingerprint)
+	}
+	if err != nil {
+		rs.recordFailure(ctx, req, err)
+		return nil, err
+	}
+
+	rs.log.Infof("Successfully processed reward %d for user %s", response.RewardID, req.UserID)
+	return response, nil
+}
+
+// processRewardTx runs the write path of ProcessReward; ctx must carry a transaction
+func (rs *RewardService) processRewardTx(ctx context.Context, req *RewardRequest, retry bool) (*RewardResponse, error) {
+	// Step 4: Atomically claim the event: insert the idempotency record, or
+	// reclaim the FAILED one. Both wait for a concurrent claim to finish.
+	// Holdings are locked before the event is claimed, the same order the
+	// batch path uses, so the two cannot deadlock
+	if err := rs.lockRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	record := newRewardRequestRecord(req)
+	var claimed bool
+	var err error
+	if retry {
+		claimed, err = rs.rewardRequestRepo.Retry(ctx, record)
+	} else {
+		claimed, err = rs.rewardRequestRepo.Claim(ctx, record)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to create idempotency record: %w", err)
 	}
+	if !claimed {
+		return nil, errClaimLost
+	}
+
+	return rs.executeRewardTx(ctx, req)
+}
+
+// recordFailure stores a failed write-path attempt so it can be listed and replayed.
+// Only failures tagged by executeRewardTx are recorded; others never claimed the event.
+func (rs *RewardService) recordFailure(ctx context.Context, req *RewardRequest, cause error) {
+	var perr *processingError
+	if !errors.As(cause, &perr) {
+		return
+	}
+
+	record := newFailedRequestRecord(req, perr.code, cause.Error())
+	var breach *RiskBreach
+	if errors.As(cause, &breach) {
+		record.RiskRule = &breach.Rule
+	}
+	if err := rs.rewardRequestRepo.RecordFailures(ctx, []*models.RewardRequest{record}); err != nil {
+		rs.log.Errorf("Failed to record failure for event %s: %v", req.EventID, err)
+	}
+}
+
+// lockRequest takes the locks booking a request needs: the holding for a
+// negative reward and the user for risk checks. They must be taken before
+// the request's idempotency record is claimed or locked.
+func (rs *RewardService) lockRequest(ctx context.Context, req *RewardRequest) error {
+	if req.Quantity.IsNegative() && !req.AllowNegative {
+		if err := rs.rewardRepo.LockHolding(ctx, req.UserID, req.StockSymbol); err != nil {
+			return err
+		}
+	}
+	if rs.risk.tracksUsers() && (req.Quantity.IsPositive() || req.AmountINR.IsPositive()) {
+		if err := rs.rewardRepo.LockUser(ctx, req.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Step 5: Get latest stock price
-	stockPrice, err := rs.priceService.GetLatestPrice(ctx, req.StockSymbol)
+// executeRewardTx prices, books and completes a request whose idempotency
+// record already exists; ctx must carry a transaction
+func (rs *RewardService) executeRewardTx(ctx context.Context, req *RewardRequest) (*RewardResponse, error) {
+	// The event type is read in the transaction that books the reward, so
+	// the rules and accounts applied are the ones in force now
+	eventType, err := activeEventType(ctx, rs.eventTypeRepo, req.EventType)
 	if err != nil {
-		rs.log.Errorf("Failed to get price for %s: %v", req.StockSymbol, err)
-		return nil, fmt.Errorf("failed to get stock price: %w", err)
+		return nil, err
+	}
+	if err := checkEventType(eventType, req); err != nil {
+		return nil, err
 	}
 
-	// Step 6: Calculate values
-	totalValueINR := req.Quantity * stockPrice.Price
-	brokerageFee := rs.calculateBrokerage(totalValueINR)
-	transactionFee := rs.calculateTransactionFee(totalValueINR)
-	netValueINR := totalValueINR - brokerageFee - transactionFee
+	if req.BasketID != nil {
+		return rs.executeBasketTx(ctx, req, eventType)
+	}
 
-	// Handle negative rewards (adjustments)
-	if req.Quantity < 0 {
-		netValueINR = totalValueINR + brokerageFee + transactionFee
+	// Negative rewards must not drive the holding below zero
+	if req.Quantity.IsNegative() && !req.AllowNegative {
+		if err := rs.checkHolding(ctx, req.UserID, req.StockSymbol, req.Quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	// Step 5: Get the stock price and fee plan at the event time, or the
+	// quote's locked price and plan
+	var stockPrice *models.StockPrice
+	var plan *models.FeePlan
+	if req.QuoteID != "" {
+		stockPrice, plan, err = rs.redeemQuote(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		stockPrice, err = rs.priceService.GetPriceAt(ctx, req.StockSymbol, req.EventTimestamp)
+		if err != nil {
+			rs.log.Errorf("Failed to get price for %s: %v", req.StockSymbol, err)
+			return nil, failWith(FailureCodePriceUnavailable, fmt.Errorf("failed to get stock price: %w", err))
+		}
+		plan, err = feePlanAt(ctx, rs.feePlanRepo, req.StockSymbol, eventTypeCode(req.EventType), rewardEventTime(req))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Step 7: Create reward record
-	eventTimestamp := req.EventTimestamp
-	if eventTimestamp.IsZero() {
-		eventTimestamp = time.Now()
+	// Steps 6-7: Calculate values and create reward record
+	reward, err := rs.buildReward(req, stockPrice, plan)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEventTypeValue(eventType, reward); err != nil {
+		return nil, err
+	}
+
+	// Velocity checks read the user's activity under the lock taken before the claim
+	var activity *models.UserRewardActivity
+	if rs.risk.tracksUsers() && reward.Quantity.IsPositive() {
+		activity, err = rs.rewardRepo.GetUserActivity(ctx, req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user reward activity: %w", err)
+		}
+	}
+	riskRule, err := rs.applyRiskRules(reward, activity)
+	if err != nil {
+		return nil, err
 	}
 
-	eventType := req.EventType
-	if eventType == "" {
-		eventType = "REWARD"
+	if err := rs.reserveCampaignSpend(ctx, reward); err != nil {
+		return nil, err
+	}
+	createdReward, err := rs.rewardRepo.Create(ctx, reward)
+	if err != nil {
+		return nil, failWith(FailureCodeRewardWrite, fmt.Errorf("failed to create reward: %w", err))
+	}
+	if err := rs.createVestingTranches(ctx, createdReward); err != nil {
+		return nil, failWith(FailureCodeRewardWrite, fmt.Errorf("failed to create vesting tranches: %w", err))
+	}
+	if err := rs.chargeRepo.BulkCreate(ctx, chargesForRewards(createdReward)); err != nil {
+		return nil, failWith(FailureCodeRewardWrite, fmt.Errorf("failed to create reward charges: %w", err))
+	}
+	if riskRule != "" {
+		if err := rs.rewardRequestRepo.FlagRisk(ctx, req.EventID, riskRule); err != nil {
+			return nil, fmt.Errorf("failed to record risk rule: %w", err)
+		}
 	}
 
+	// Step 8: Create ledger entries (double-entry bookkeeping); a reward
+	// awaiting approval is posted when it is approved
+	if models.IsSettledReward(createdReward.Status) {
+		if err := rs.ledgerRepo.BulkCreate(ctx, ledgerEntriesFor(createdReward, eventType)); err != nil {
+			return nil, failWith(FailureCodeLedgerWrite, fmt.Errorf("failed to create ledger entries: %w", err))
+		}
+	}
+
+	// Step 9: Mark request as completed
+	response := newRewardResponse(createdReward, processedMessage(createdReward))
+	response.RiskRule = riskRule
+
+	responsePayload, _ := json.Marshal(response)
+	if err := rs.rewardRequestRepo.MarkProcessed(ctx, req.EventID, string(responsePayload)); err != nil {
+		return nil, failWith(FailureCodeInternal, fmt.Errorf("failed to mark request as processed: %w", err))
+	}
+
+	return response, nil
+}
+
+// checkHolding locks the user's position in a symbol for the rest of the
+// transaction and rejects a change that would take it below zero
+func (rs *RewardService) checkHolding(ctx context.Context, userID, stockSymbol string, change decimal.Decimal) error {
+	if err := rs.rewardRepo.LockHolding(ctx, userID, stockSymbol); err != nil {
+		return err
+	}
+	held, err := rs.rewardRepo.GetNetQuantity(ctx, userID, stockSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to get current holding: %w", err)
+	}
+	if held.Add(change).IsNegative() {
+		return fmt.Errorf("%w: user %s holds %s %s, adjustment of %s would leave %s",
+			ErrInsufficientHoldings, userID, held, stockSymbol, change, held.Add(change))
+	}
+	return nil
+}
+
+// checkReversible rejects reversing a grant the user no longer holds. The
+// grant's own unvested tranches are cancelled with it, so only other grants'
+// unvested stock is set aside. ctx must carry a transaction.
+func (rs *RewardService) checkReversible(ctx context.Context, original *models.Reward) error {
+	if err := rs.rewardRepo.LockHolding(ctx, original.UserID, original.StockSymbol); err != nil {
+		return err
+	}
+	if err := rs.rewardRepo.LockUser(ctx, original.UserID); err != nil {
+		return err
+	}
+
+	held, err := rs.rewardRepo.GetNetQuantity(ctx, original.UserID, original.StockSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to get current holding: %w", err)
+	}
+	unvested, err := rs.vestingRepo.GetUnvestedQuantities(ctx, original.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load unvested quantities: %w", err)
+	}
+	tranches, err := rs.vestingRepo.GetByRewardID(ctx, original.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load vesting tranches: %w", err)
+	}
+	otherUnvested := unvested[original.StockSymbol]
+	for _, tranche := range tranches {
+		if tranche.Status == models.VestingStatusUnvested {
+			otherUnvested = otherUnvested.Sub(tranche.Quantity)
+		}
+	}
+
+	available := held.Sub(otherUnvested)
+	if original.Quantity.GreaterThan(available) {
+		return fmt.Errorf("%w: user %s has %s %s available to reverse (%s unvested from other rewards), reward granted %s",
+			ErrInsufficientHoldings, original.UserID, decimal.Max(available, decimal.Zero), original.StockSymbol,
+			otherUnvested, original.Quantity)
+	}
+	return nil
+}
+
+// buildReward values a request at the given price, charged by plan or by
+// CHARGES_PLAN when plan is nil, and returns the reward to persist
+func (rs *RewardService) buildReward(req *RewardRequest, stockPrice *models.StockPrice, plan *models.FeePlan) (*models.Reward, error) {
+	calculator := chargesForPlan(plan, rs.charges)
+	var feePlanID *int
+	if plan != nil {
+		feePlanID = &plan.ID
+	}
+
+	quantity := req.Quantity
+	var requestedAmountINR *decimal.Decimal
+	if req.AmountINR.IsPositive() {
+		var err error
+		quantity, err = rs.quantityForAmount(req.AmountINR, stockPrice.Price, req.DeductFees, calculator)
+		if err != nil {
+			return nil, err
+		}
+		requestedAmountINR = &req.AmountINR
+	}
+
+	totalValueINR := roundINR(quantity.Mul(stockPrice.Price))
+	// A deduction is charged like a sale of the stock
+	side := TradeSideBuy
+	if quantity.IsNegative() {
+		side = TradeSideSell
+	}
+	charges := calculator.Charges(side, totalValueINR)
+	brokerageFee, transactionFee := chargeTotals(charges)
+	netValueINR := totalValueINR.Sub(brokerageFee).Sub(transactionFee)
+
+	// Handle negative rewards (adjustments)
+	if quantity.IsNegative() {
+		netValueINR = totalValueINR.Add(brokerageFee).Add(transactionFee)
+	}
+
+	eventTimestamp := rewardEventTime(req)
+
 	var notes *string
 	if req.Notes != "" {
 		notes = &req.Notes
 	}
 
-	reward := &models.Reward{
-		UserID:         req.UserID,
-		StockSymbol:    req.StockSymbol,
-		Quantity:       req.Quantity,
-		EventType:      eventType,
-		EventID:        req.EventID,
-		EventTimestamp: eventTimestamp,
-		StockPrice:     stockPrice.Price,
-		TotalValueINR:  totalValueINR,
-		BrokerageFee:   brokerageFee,
-		TransactionFee: transactionFee,
-		NetValueINR:    netValueINR,
-		Status:         "COMPLETED",
-		Notes:          notes,
+	status := models.RewardStatusCompleted
+	if quantity.IsPositive() && rs.requiresApproval(totalValueINR) {
+		if req.CreatedBy == "" {
+			return nil, ErrCreatorRequired
+		}
+		status = models.RewardStatusPendingApproval
 	}
 
-	createdReward, err := rs.rewardRepo.Create(ctx, reward)
+	var createdBy *string
+	if req.CreatedBy != "" {
+		createdBy = &req.CreatedBy
+	}
+
+	return &models.Reward{
+		UserID:             req.UserID,
+		StockSymbol:        req.StockSymbol,
+		Quantity:           quantity,
+		EventType:          eventTypeCode(req.EventType),
+		EventID:            req.EventID,
+		EventTimestamp:     eventTimestamp,
+		StockPrice:         stockPrice.Price,
+		StockPriceID:       &stockPrice.ID,
+		TotalValueINR:      totalValueINR,
+		BrokerageFee:       brokerageFee,
+		TransactionFee:     transactionFee,
+		NetValueINR:        netValueINR,
+		Charges:            charges,
+		FeePlanID:          feePlanID,
+		RequestedAmountINR: requestedAmountINR,
+		DeductFees:         req.DeductFees && requestedAmountINR != nil,
+		Status:             status,
+		Notes:              notes,
+		CreatedBy:          createdBy,
+		CampaignID:         req.CampaignID,
+		Vesting:            req.Vesting,
+	}, nil
+}
+
+// rewardEventTime is the event time of a request, now when it names none
+func rewardEventTime(req *RewardRequest) time.Time {
+	if req.EventTimestamp.IsZero() {
+		return time.Now()
+	}
+	return req.EventTimestamp
+}
+
+// newRewardRequestRecord builds the idempotency record for a request. The
+// payload is stored resolved, for recovery to book it as it was priced; the
+// fingerprint is the one taken as the request was received.
+func newRewardRequestRecord(req *RewardRequest) *models.RewardRequest {
+	requestPayload, _ := json.Marshal(req)
+	fingerprint := req.fingerprint
+	if fingerprint == "" {
+		fingerprint = fingerprintRequest(req)
+	}
+	return &models.RewardRequest{
+		EventID:            req.EventID,
+		UserID:             req.UserID,
+		StockSymbol:        req.StockSymbol,
+		Quantity:           req.Quantity,
+		RequestPayload:     string(requestPayload),
+		RequestFingerprint: fingerprint,
+		Status:             models.RequestStatusProcessing,
+	}
+}
+
+// checkFingerprint rejects reuse of an event_id with a different payload
+func checkFingerprint(existing *models.RewardRequest, fingerprint string) error {
+	// Records written before fingerprinting have none and cannot be compared
+	if existing.RequestFingerprint != "" && existing.RequestFingerprint != fingerprint {
+		return fmt.Errorf("%w: event %s was already used with a different payload", ErrIdempotencyConflict, existing.EventID)
+	}
+	return nil
+}
+
+// replayResponse returns the stored response of an already seen event.
+// A different payload under the same event_id is a conflict, never a replay.
+// The reward may have been approved, rejected or reversed since its response
+// was stored, so the replay reports the reward's current status.
+func (rs *RewardService) replayResponse(ctx context.Context, existing *models.RewardRequest, fingerprint string) (*RewardResponse, error) {
+	if err := checkFingerprint(existing, fingerprint); err != nil {
+		return nil, err
+	}
+
+	switch existing.Status {
+	case models.RequestStatusCompleted:
+		if existing.ResponsePayload == nil {
+			return nil, fmt.Errorf("%w: event %s has no stored response", ErrInternal, existing.EventID)
+		}
+		var response RewardResponse
+		if err := json.Unmarshal([]byte(*existing.ResponsePayload), &response); err != nil {
+			return nil, fmt.Errorf("%w: stored response of event %s is unreadable: %v", ErrInternal, existing.EventID, err)
+		}
+		response.Message = "Duplicate request - returning previous result"
+		if err := rs.refreshReplayStatus(ctx, &response); err != nil {
+			return nil, err
+		}
+		for _, leg := range response.Legs {
+			if err := rs.refreshReplayStatus(ctx, leg); err != nil {
+				return nil, err
+			}
+		}
+		return &response, nil
+	case models.RequestStatusProcessing:
+		return nil, fmt.Errorf("%w: event %s", ErrRequestInProgress, existing.EventID)
+	case models.RequestStatusFailed:
+		// A concurrent request for the event failed; sending it again retries it
+		if existing.ErrorCode != nil {
+			return nil, fmt.Errorf("%w: event %s failed with %s", ErrRequestFailed, existing.EventID, *existing.ErrorCode)
+		}
+		return nil, fmt.Errorf("%w: event %s", ErrRequestFailed, existing.EventID)
+	default:
+		return nil, fmt.Errorf("%w: request for event %s has unknown status %s", ErrInternal, existing.EventID, existing.Status)
+	}
+}
+
+// refreshReplayStatus sets a replayed response's status from its reward:
+// PENDING_APPROVAL, REJECTED and REVERSED are reported as they are, any
+// other status as SUCCESS
+func (rs *RewardService) refreshReplayStatus(ctx context.Context, response *RewardResponse) error {
+	if response.RewardID == 0 {
+		return nil
+	}
+	reward, err := rs.rewardRepo.GetByID(ctx, response.RewardID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create reward: %w", err)
+		return fmt.Errorf("failed to load reward %d: %w", response.RewardID, err)
+	}
+	switch reward.Status {
+	case models.RewardStatusPendingApproval, models.RewardStatusRejected, models.RewardStatusReversed:
+		response.Status = reward.Status
+	default:
+		response.Status = "SUCCESS"
 	}
+	return nil
+}
 
-	// Step 8: Create ledger entries (double-entry bookkeeping)
-	if err := rs.createLedgerEntries(ctx, createdReward); err != nil {
-		rs.log.Errorf("Failed to create ledger entries: %v", err)
-		// Don't fail the reward, but log the error
+// ReplayRequest re-runs a FAILED reward request from its stored payload
+func (rs *RewardService) ReplayRequest(ctx context.Context, eventID string) (*RewardResponse, error) {
+	existing, err := rs.rewardRequestRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to load reward request: %w", err)
+	}
+	if existing.Status != models.RequestStatusFailed {
+		return nil, fmt.Errorf("%w: request %s is %s", ErrRequestNotReplayable, eventID, existing.Status)
 	}
 
-	// Step 9: Mark request as completed
+	var req RewardRequest
+	if err := json.Unmarshal([]byte(existing.RequestPayload), &req); err != nil {
+		return nil, fmt.Errorf("stored request payload is unreadable: %w", err)
+	}
+	// The stored payload is already resolved; it is the same request as
+	// the one originally received
+	req.fingerprint = existing.RequestFingerprint
+
+	rs.log.Infof("Replaying failed request for event %s", eventID)
+	return rs.ProcessReward(ctx, &req)
+}
+
+// ListRewardRequests lists reward requests in the given status, newest first
+func (rs *RewardService) ListRewardRequests(ctx context.Context, status string, limit, offset int) ([]*models.RewardRequest, error) {
+	return rs.rewardRequestRepo.ListByStatus(ctx, status, limit, offset)
+}
+
+// ReverseReward undoes a processed reward without deleting its history.
+// The original is marked REVERSED and a linked negative reward is booked with
+// mirror-image ledger entries. Repeating a call with the same reversal ID
+// returns the existing reversal.
+func (rs *RewardService) ReverseReward(ctx context.Context, eventID string, req *ReversalRequest) (*RewardResponse, error) {
+	rs.log.Infof("Reversing reward for event %s with reversal %s", eventID, req.ReversalID)
+
+	if req.ReversalID == "" {
+		return nil, fmt.Errorf("%w: reversal_id is required", ErrValidation)
+	}
+	if req.ReversalID == eventID {
+		return nil, fmt.Errorf("%w: reversal_id must differ from the reward event_id", ErrValidation)
+	}
+
+	var response *RewardResponse
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		// Lock the original so concurrent reversals are serialized
+		original, err := rs.rewardRepo.LockByEventID(ctx, eventID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRewardNotFound
+			}
+			return fmt.Errorf("failed to load reward: %w", err)
+		}
+
+		// Same reversal ID seen before - replay it if it reverses this reward
+		existing, err := rs.rewardRepo.GetByEventID(ctx, req.ReversalID)
+		if err == nil {
+			if existing.ReversalOf != nil && *existing.ReversalOf == original.ID {
+				response = newRewardResponse(existing, "Duplicate request - returning previous result")
+				return nil
+			}
+			return ErrReversalIDInUse
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to check reversal: %w", err)
+		}
+
+		if original.ReversalOf != nil {
+			return fmt.Errorf("%w: a reversal cannot itself be reversed", ErrValidation)
+		}
+		if original.Status == models.RewardStatusReversed {
+			return ErrRewardAlreadyReversed
+		}
+		if original.EventType == EventTypeTransferOut || original.EventType == EventTypeTransferIn {
+			return fmt.Errorf("%w: transfers cannot be reversed; transfer the stock back instead", ErrInvalidStatusTransition)
+		}
+		if original.EventType == EventTypeRedemption {
+			return fmt.Errorf("%w: executed redemptions cannot be reversed", ErrInvalidStatusTransition)
+		}
+		if original.ClawedBackAt != nil {
+			return fmt.Errorf("%w: reward was covered by a clawback", ErrInvalidStatusTransition)
+		}
+		if !models.CanTransitionReward(original.Status, models.RewardStatusReversed) {
+			return fmt.Errorf("%w: %s rewards cannot be reversed", ErrInvalidStatusTransition, original.Status)
+		}
+
+		// Taking back a grant must not leave the position negative or eat
+		// into stock still vesting from other grants
+		if original.Quantity.IsPositive() {
+			if err := rs.checkReversible(ctx, original); err != nil {
+				return err
+			}
+		}
+
+		notes := fmt.Sprintf("Reversal of %s", original.EventID)
+		if req.Reason != "" {
+			notes = fmt.Sprintf("%s: %s", notes, req.Reason)
+		}
+
+		originalCharges, err := rs.chargeRepo.GetByRewardID(ctx, original.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load reward charges: %w", err)
+		}
+		charges := make([]*models.RewardCharge, 0, len(originalCharges))
+		for _, charge := range originalCharges {
+			charges = append(charges, &models.RewardCharge{Code: charge.Code, Account: charge.Account, Amount: charge.Amount.Neg()})
+		}
+
+		reversal, err := rs.rewardRepo.Create(ctx, &models.Reward{
+			UserID:         original.UserID,
+			StockSymbol:    original.StockSymbol,
+			Quantity:       original.Quantity.Neg(),
+			EventType:      EventTypeReversal,
+			EventID:        req.ReversalID,
+			EventTimestamp: time.Now(),
+			StockPrice:     original.StockPrice,
+			StockPriceID:   original.StockPriceID,
+			TotalValueINR:  original.TotalValueINR.Neg(),
+			BrokerageFee:   original.BrokerageFee.Neg(),
+			TransactionFee: original.TransactionFee.Neg(),
+			NetValueINR:    original.NetValueINR.Neg(),
+			Charges:        charges,
+			Status:         models.RewardStatusCompleted,
+			Notes:          &notes,
+			ReversalOf:     &original.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create reversal: %w", err)
+		}
+		if err := rs.chargeRepo.BulkCreate(ctx, chargesForRewards(reversal)); err != nil {
+			return fmt.Errorf("failed to create reversal charges: %w", err)
+		}
+
+		if err := rs.createReversalLedgerEntries(ctx, original, reversal); err != nil {
+			return fmt.Errorf("failed to create reversal ledger entries: %w", err)
+		}
+
+		if err := rs.rewardRepo.MarkReversed(ctx, original.ID); err != nil {
+			return fmt.Errorf("failed to mark reward reversed: %w", err)
+		}
+		// Tranches still unvested are cancelled; the mirrored ledger entries
+		// already undo both the unvested booking and any vesting so far
+		if _, err := rs.vestingRepo.CancelByRewardID(ctx, original.ID); err != nil {
+			return fmt.Errorf("failed to cancel vesting tranches: %w", err)
+		}
+
+		response = newRewardResponse(reversal, "Reward reversed successfully")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rs.log.Infof("Reversed reward for event %s with reversal %s", eventID, req.ReversalID)
+	return response, nil
+}
+
+// newRewardResponse builds the API response for a persisted reward
+func newRewardResponse(reward *models.Reward, message string) *RewardResponse {
 	response := &RewardResponse{
-		RewardID:       createdReward.ID,
-		UserID:         createdReward.UserID,
-		StockSymbol:    createdReward.StockSymbol,
-		Quantity:       createdReward.Quantity,
-		StockPrice:     createdReward.StockPrice,
-		TotalValueINR:  createdReward.TotalValueINR,
-		BrokerageFee:   createdReward.BrokerageFee,
-		TransactionFee: createdReward.TransactionFee,
-		NetValueINR:    createdReward.NetValueINR,
-		EventID:        createdReward.EventID,
+		RewardID:       reward.ID,
+		UserID:         reward.UserID,
+		StockSymbol:    reward.StockSymbol,
+		Quantity:       &reward.Quantity,
+		StockPrice:     &reward.StockPrice,
+		StockPriceID:   reward.StockPriceID,
+		TotalValueINR:  reward.TotalValueINR,
+		BrokerageFee:   reward.BrokerageFee,
+		TransactionFee: reward.TransactionFee,
+		NetValueINR:    reward.NetValueINR,
+		Charges:        reward.Charges,
+		FeePlanID:      reward.FeePlanID,
+		Vesting:        reward.Vesting,
+		EventID:        reward.EventID,
 		Status:         "SUCCESS",
-		Message:        "Reward processed successfully",
+		Message:        message,
 		Timestamp:      time.Now(),
 	}
-
-	responsePayload, _ := json.Marshal(response)
-	responseStr := string(responsePayload)
-	if err := rs.rewardRequestRepo.MarkProcessed(ctx, req.EventID, responseStr); err != nil {
-		rs.log.Errorf("Failed to mark request as processed: %v", err)
+	// Rewards not yet (or never) booked report their reward status instead
+	if reward.Status == models.RewardStatusPendingApproval || reward.Status == models.RewardStatusRejected {
+		response.Status = reward.Status
 	}
+	if reward.RequestedAmountINR != nil {
+		executed := executedAmountINR(reward.TotalValueINR, reward.BrokerageFee, reward.TransactionFee, reward.DeductFees)
+		response.RequestedAmountINR = reward.RequestedAmountINR
+		response.ExecutedAmountINR = &executed
+		response.DeductFees = reward.DeductFees
+	}
+	return response
+}
 
-	rs.log.Infof("Successfully processed reward %d for user %s", createdReward.ID, req.UserID)
-	return response, nil
+// processedMessage is the response message for a newly processed reward
+func processedMessage(reward *models.Reward) string {
+	if reward.Status == models.RewardStatusPendingApproval {
+		return "Reward created and awaiting approval"
+	}
+	return "Reward processed successfully"
 }
 
 // validateRequest validates the reward request
@@ -232,158 +938,203 @@ func (rs *RewardService) validateRequest(req *RewardRequest) error {
 	if req.UserID == "" {
 		return fmt.Errorf("user_id is required")
 	}
-	if req.StockSymbol == "" {
+	if req.BasketID != nil {
+		if err := validateBasketRequest(req); err != nil {
+			return err
+		}
+	} else if req.StockSymbol == "" {
 		return fmt.Errorf("stock_symbol is required")
 	}
-	if req.Quantity == 0 {
-		return fmt.Errorf("quantity cannot be zero")
+	// Symbols are stored, priced and risk-checked in upper case
+	req.StockSymbol = strings.ToUpper(req.StockSymbol)
+	if req.AmountINR.IsNegative() {
+		return fmt.Errorf("amount_inr must be positive")
+	}
+	if req.AmountINR.IsPositive() && !req.Quantity.IsZero() {
+		return fmt.Errorf("quantity and amount_inr are mutually exclusive")
+	}
+	if req.AmountINR.IsZero() && req.Quantity.IsZero() {
+		return fmt.Errorf("quantity or amount_inr is required")
+	}
+	if err := normalizePlaces("quantity", &req.Quantity, models.QuantityScale); err != nil {
+		return err
+	}
+	if err := normalizePlaces("amount_inr", &req.AmountINR, models.AmountScale); err != nil {
+		return err
+	}
+	if req.DeductFees && req.AmountINR.IsZero() {
+		return fmt.Errorf("deduct_fees requires amount_inr")
 	}
 	if req.EventID == "" {
 		return fmt.Errorf("event_id is required")
 	}
-	return nil
-}
-
-// calculateBrokerage calculates brokerage fee
-func (rs *RewardService) calculateBrokerage(totalValue float64) float64 {
-	fee := math.Abs(totalValue) * (rs.brokeragePercent / 100.0)
-	return rs.roundToTwoDecimals(fee)
-}
-
-// calculateTransactionFee calculates transaction fee
-func (rs *RewardService) calculateTransactionFee(totalValue float64) float64 {
-	fee := math.Abs(totalValue) * (rs.feePercent / 100.0)
-	return rs.roundToTwoDecimals(fee)
+	if strings.HasPrefix(req.EventID, basketLegPrefix) {
+		return fmt.Errorf("event_id must not start with %s, which is reserved for basket legs", basketLegPrefix)
+	}
+	if err := rs.priceService.checkEventTime(req.EventTimestamp); err != nil {
+		return err
+	}
+	normalizeVesting(req.Vesting)
+	return validateVesting(req.Vesting, req.Quantity, req.AmountINR)
 }
 
-// roundToTwoDecimals rounds a float to 2 decimal places
-func (rs *RewardService) roundToTwoDecimals(value float64) float64 {
-	return math.Round(value*100) / 100
+// createLedgerEntries creates double-entry ledger entries for a reward,
+// posted to the accounts of its event type. Charges of a reward read back
+// from the database are loaded first.
+func (rs *RewardService) createLedgerEntries(ctx context.Context, reward *models.Reward) error {
+	eventType, err := ledgerEventType(ctx, rs.eventTypeRepo, reward.EventType)
+	if err != nil {
+		return err
+	}
+	if reward.Charges == nil {
+		if reward.Charges, err = rs.chargeRepo.GetByRewardID(ctx, reward.ID); err != nil {
+			return fmt.Errorf("failed to load reward charges: %w", err)
+		}
+	}
+	return rs.ledgerRepo.BulkCreate(ctx, ledgerEntriesFor(reward, eventType))
 }
 
-// createLedgerEntries creates double-entry ledger entries for a reward
-func (rs *RewardService) createLedgerEntries(ctx context.Context, reward *models.Reward) error {
+// ledgerEntriesFor builds the balanced ledger entries for a reward; the
+// value is balanced against the event type's credit or debit account
+func ledgerEntriesFor(reward *models.Reward, eventType *models.EventType) []*models.LedgerEntry {
 	entries := make([]*models.LedgerEntry, 0)
 
 	// For positive rewards (receiving stocks)
-	if reward.Quantity > 0 {
-		// DEBIT: Stock Asset Account (increase in assets)
-		stockAssetDesc := fmt.Sprintf("Stock reward: %s x %.6f @ %.2f INR", 
+	if reward.Quantity.IsPositive() {
+		// DEBIT: Stock Asset Account (increase in assets); vesting stock
+		// is held as unvested until its tranches vest
+		stockAssetDesc := fmt.Sprintf("Stock reward: %s x %s @ %s INR",
 			reward.StockSymbol, reward.Quantity, reward.StockPrice)
+		assetAccount := AccountStockAsset
+		if reward.Vesting != nil {
+			assetAccount = AccountUnvestedStockAsset
+		}
 		entries = append(entries, &models.LedgerEntry{
 			RewardID:    reward.ID,
 			UserID:      reward.UserID,
 			EntryType:   "DEBIT",
-			AccountType: "STOCK_ASSET",
+			AccountType: assetAccount,
 			Amount:      reward.TotalValueINR,
 			Currency:    "INR",
 			Description: &stockAssetDesc,
 			ReferenceID: &reward.EventID,
 		})
 
-		// CREDIT: Reward Income Account (source of the asset)
+		// CREDIT: the event type's income account (source of the asset)
 		rewardIncomeDesc := fmt.Sprintf("Reward income for event %s", reward.EventID)
 		entries = append(entries, &models.LedgerEntry{
 			RewardID:    reward.ID,
 			UserID:      reward.UserID,
 			EntryType:   "CREDIT",
-			AccountType: "REWARD_INCOME",
+			AccountType: eventType.CreditAccount,
 			Amount:      reward.TotalValueINR,
 			Currency:    "INR",
 			Description: &rewardIncomeDesc,
 			ReferenceID: &reward.EventID,
 		})
 
-		// DEBIT: Brokerage Expense
-		if reward.BrokerageFee > 0 {
-			brokerageDesc := fmt.Sprintf("Brokerage fee for %s", reward.EventID)
-			entries = append(entries, &models.LedgerEntry{
-				RewardID:    reward.ID,
-				UserID:      reward.UserID,
-				EntryType:   "DEBIT",
-				AccountType: "BROKERAGE_EXPENSE",
-				Amount:      reward.BrokerageFee,
-				Currency:    "INR",
-				Description: &brokerageDesc,
-				ReferenceID: &reward.EventID,
-			})
-
-			// CREDIT: Cash (payment of brokerage)
-			entries = append(entries, &models.LedgerEntry{
-				RewardID:    reward.ID,
-				UserID:      reward.UserID,
-				EntryType:   "CREDIT",
-				AccountType: "CASH",
-				Amount:      reward.BrokerageFee,
-				Currency:    "INR",
-				Description: &brokerageDesc,
-				ReferenceID: &reward.EventID,
-			})
-		}
-
-		// DEBIT: Transaction Fee Expense
-		if reward.TransactionFee > 0 {
-			feeDesc := fmt.Sprintf("Transaction fee for %s", reward.EventID)
+		// DEBIT: each charge's expense account, CREDIT: Cash (payment of the charge)
+		for _, charge := range reward.Charges {
+			if !charge.Amount.IsPositive() {
+				continue
+			}
+			chargeDesc := chargeDescription(charge.Code, reward.EventID)
 			entries = append(entries, &models.LedgerEntry{
 				RewardID:    reward.ID,
 				UserID:      reward.UserID,
 				EntryType:   "DEBIT",
-				AccountType: "FEE_EXPENSE",
-				Amount:      reward.TransactionFee,
+				AccountType: charge.Account,
+				Amount:      charge.Amount,
 				Currency:    "INR",
-				Description: &feeDesc,
+				Description: &chargeDesc,
 				ReferenceID: &reward.EventID,
-			})
-
-			// CREDIT: Cash (payment of fee)
-			entries = append(entries, &models.LedgerEntry{
+			}, &models.LedgerEntry{
 				RewardID:    reward.ID,
 				UserID:      reward.UserID,
 				EntryType:   "CREDIT",
 				AccountType: "CASH",
-				Amount:      reward.TransactionFee,
+				Amount:      charge.Amount,
 				Currency:    "INR",
-				Description: &feeDesc,
+				Description: &chargeDesc,
 				ReferenceID: &reward.EventID,
 			})
 		}
 	} else {
 		// For negative rewards (adjustments/deductions)
 		// CREDIT: Stock Asset Account (decrease in assets)
-		stockAssetDesc := fmt.Sprintf("Stock adjustment: %s x %.6f @ %.2f INR",
+		stockAssetDesc := fmt.Sprintf("Stock adjustment: %s x %s @ %s INR",
 			reward.StockSymbol, reward.Quantity, reward.StockPrice)
 		entries = append(entries, &models.LedgerEntry{
 			RewardID:    reward.ID,
 			UserID:      reward.UserID,
 			EntryType:   "CREDIT",
-			AccountType: "STOCK_ASSET",
-			Amount:      math.Abs(reward.TotalValueINR),
+			AccountType: AccountStockAsset,
+			Amount:      reward.TotalValueINR.Abs(),
 			Currency:    "INR",
 			Description: &stockAssetDesc,
 			ReferenceID: &reward.EventID,
 		})
 
-		// DEBIT: Adjustment Expense Account
+		// DEBIT: the event type's adjustment account
 		adjustmentDesc := fmt.Sprintf("Stock adjustment for event %s", reward.EventID)
 		entries = append(entries, &models.LedgerEntry{
 			RewardID:    reward.ID,
 			UserID:      reward.UserID,
 			EntryType:   "DEBIT",
-			AccountType: "ADJUSTMENT_EXPENSE",
-			Amount:      math.Abs(reward.TotalValueINR),
+			AccountType: eventType.DebitAccount,
+			Amount:      reward.TotalValueINR.Abs(),
 			Currency:    "INR",
 			Description: &adjustmentDesc,
 			ReferenceID: &reward.EventID,
 		})
 	}
 
-	// Bulk create all ledger entries
+	return entries
+}
+
+// createReversalLedgerEntries posts the mirror image of the original reward's
+// ledger entries (fees included) against the reversal reward
+func (rs *RewardService) createReversalLedgerEntries(ctx context.Context, original, reversal *models.Reward) error {
+	originalEntries, err := rs.ledgerRepo.GetByRewardID(ctx, original.ID)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*models.LedgerEntry, 0, len(originalEntries))
+	for _, entry := range originalEntries {
+		entryType := "DEBIT"
+		if entry.EntryType == "DEBIT" {
+			entryType = "CREDIT"
+		}
+		desc := fmt.Sprintf("Reversal of %s entry for event %s", entry.AccountType, original.EventID)
+		entries = append(entries, &models.LedgerEntry{
+			RewardID:    reversal.ID,
+			UserID:      entry.UserID,
+			EntryType:   entryType,
+			AccountType: entry.AccountType,
+			Amount:      entry.Amount,
+			Currency:    entry.Currency,
+			Description: &desc,
+			ReferenceID: &reversal.EventID,
+		})
+	}
+
 	return rs.ledgerRepo.BulkCreate(ctx, entries)
 }
 
-// GetRewardByEventID retrieves a reward by event ID
+// GetRewardByEventID retrieves a reward by event ID with its charges
 func (rs *RewardService) GetRewardByEventID(ctx context.Context, eventID string) (*models.Reward, error) {
-	return rs.rewardRepo.GetByEventID(ctx, eventID)
+	reward, err := rs.rewardRepo.GetByEventID(ctx, eventID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrRewardNotFound, eventID)
+		}
+		return nil, err
+	}
+	if reward.Charges, err = rs.chargeRepo.GetByRewardID(ctx, reward.ID); err != nil {
+		return nil, fmt.Errorf("failed to load reward charges: %w", err)
+	}
+	return reward, nil
 }
 
 // GetUserRewards retrieves rewards for a user
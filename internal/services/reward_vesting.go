@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"time"
+)
+
+// Ledger accounts holding rewarded stock before and after it vests
+const (
+	AccountStockAsset         = "STOCK_ASSET"
+	AccountUnvestedStockAsset = "UNVESTED_STOCK_ASSET"
+)
+
+// maxVestingTranches bounds the rows a single reward can create
+const maxVestingTranches = 120
+
+// validateVesting checks that a vesting schedule describes at least one tranche
+func validateVesting(vesting *models.VestingSchedule, quantity, amountINR decimal.Decimal) error {
+	if vesting == nil {
+		return nil
+	}
+	if quantity.IsNegative() || (quantity.IsZero() && amountINR.IsZero()) {
+		return fmt.Errorf("vesting only applies to rewards that grant stock")
+	}
+	if vesting.CliffDays < 0 || vesting.Tranches < 0 || vesting.IntervalDays < 0 {
+		return fmt.Errorf("vesting days and tranches must not be negative")
+	}
+
+	if len(vesting.VestDates) > 0 {
+		if vesting.CliffDays != 0 || vesting.Tranches != 0 || vesting.IntervalDays != 0 {
+			return fmt.Errorf("vesting.vest_dates cannot be combined with cliff_days, tranches or interval_days")
+		}
+		if len(vesting.VestDates) > maxVestingTranches {
+			return fmt.Errorf("vesting allows at most %d tranches", maxVestingTranches)
+		}
+		// Dates are sorted by normalizeVesting, so only duplicates remain to catch
+		for i := 1; i < len(vesting.VestDates); i++ {
+			if vesting.VestDates[i].Equal(vesting.VestDates[i-1]) {
+				return fmt.Errorf("vesting.vest_dates must be distinct")
+			}
+		}
+		return nil
+	}
+
+	if vesting.Tranches > maxVestingTranches {
+		return fmt.Errorf("vesting allows at most %d tranches", maxVestingTranches)
+	}
+	if vesting.Tranches > 1 && vesting.IntervalDays == 0 {
+		return fmt.Errorf("vesting.interval_days is required with more than one tranche")
+	}
+	if vesting.Tranches <= 1 && vesting.IntervalDays != 0 {
+		return fmt.Errorf("vesting.interval_days requires more than one tranche")
+	}
+	if vesting.CliffDays == 0 && vesting.Tranches <= 1 {
+		return fmt.Errorf("vesting needs cliff_days, tranches or vest_dates")
+	}
+	return nil
+}
+
+// vestDates returns the tranche dates of a schedule for a reward granted at grantedAt
+func vestDates(vesting *models.VestingSchedule, grantedAt time.Time) []time.Time {
+	if len(vesting.VestDates) > 0 {
+		return vesting.VestDates
+	}
+
+	count := vesting.Tranches
+	if count < 1 {
+		count = 1
+	}
+	first := grantedAt.AddDate(0, 0, vesting.CliffDays)
+	dates := make([]time.Time, count)
+	for i := range dates {
+		dates[i] = first.AddDate(0, 0, i*vesting.IntervalDays)
+	}
+	return dates
+}
+
+// vestingTranches splits a vesting reward into equal tranches. Quantities are
+// rounded to the configured precision and values to paise; the last tranche
+// takes the remainder so the tranches add up to the reward exactly.
+func (rs *RewardService) vestingTranches(reward *models.Reward) []*models.VestingTranche {
+	if reward.Vesting == nil || !reward.Quantity.IsPositive() {
+		return nil
+	}
+
+	dates := vestDates(reward.Vesting, reward.EventTimestamp)
+	count := decimal.NewFromInt(int64(len(dates)))
+	share := reward.Quantity.Div(count, int32(rs.quantityPrecision), decimal.RoundDown).Round(models.QuantityScale, decimal.RoundDown)
+	shareValue := reward.TotalValueINR.Div(count, models.AmountScale, decimal.RoundHalfUp)
+
+	tranches := make([]*models.VestingTranche, 0, len(dates))
+	remainingQuantity, remainingValue := reward.Quantity, reward.TotalValueINR
+	for i, date := range dates {
+		quantity, value := share, shareValue
+		if i == len(dates)-1 {
+			quantity, value = remainingQuantity, remainingValue
+		}
+		// A tranche too small to hold any stock is dropped and its value
+		// stays with the remainder
+		if !quantity.IsPositive() {
+			continue
+		}
+		remainingQuantity = remainingQuantity.Sub(quantity)
+		remainingValue = remainingValue.Sub(value)
+		tranches = append(tranches, &models.VestingTranche{
+			RewardID:    reward.ID,
+			UserID:      reward.UserID,
+			StockSymbol: reward.StockSymbol,
+			Quantity:    quantity,
+			ValueINR:    value,
+			VestDate:    date,
+			Status:      models.VestingStatusUnvested,
+		})
+	}
+	return tranches
+}
+
+// createVestingTranches stores the tranches of every vesting reward
+func (rs *RewardService) createVestingTranches(ctx context.Context, rewards ...*models.Reward) error {
+	var tranches []*models.VestingTranche
+	for _, reward := range rewards {
+		tranches = append(tranches, rs.vestingTranches(reward)...)
+	}
+	return rs.vestingRepo.BulkCreate(ctx, tranches)
+}
+
+// normalizeVesting puts vest dates in UTC and ascending order so equivalent
+// schedules store and fingerprint the same way
+func normalizeVesting(vesting *models.VestingSchedule) {
+	if vesting == nil {
+		return
+	}
+	for i, date := range vesting.VestDates {
+		vesting.VestDates[i] = date.UTC()
+	}
+	sort.Slice(vesting.VestDates, func(a, b int) bool { return vesting.VestDates[a].Before(vesting.VestDates[b]) })
+}
+
+// vestingLedgerEntries moves a vested tranche from unvested to vested stock
+func vestingLedgerEntries(tranche *models.VestingTranche) []*models.LedgerEntry {
+	desc := fmt.Sprintf("Vesting: %s x %s of reward %d", tranche.StockSymbol, tranche.Quantity, tranche.RewardID)
+	reference := fmt.Sprintf("VEST-%d", tranche.ID)
+	return []*models.LedgerEntry{
+		{
+			RewardID:    tranche.RewardID,
+			UserID:      tranche.UserID,
+			EntryType:   "DEBIT",
+			AccountType: AccountStockAsset,
+			Amount:      tranche.ValueINR,
+			Currency:    "INR",
+			Description: &desc,
+			ReferenceID: &reference,
+		},
+		{
+			RewardID:    tranche.RewardID,
+			UserID:      tranche.UserID,
+			EntryType:   "CREDIT",
+			AccountType: AccountUnvestedStockAsset,
+			Amount:      tranche.ValueINR,
+			Currency:    "INR",
+			Description: &desc,
+			ReferenceID: &reference,
+		},
+	}
+}
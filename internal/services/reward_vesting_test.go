@@ -0,0 +1,113 @@
+package services
+
+import (
+	"stockBackend/internal/models"
+	"stockBackend/pkg/decimal"
+	"testing"
+	"time"
+)
+
+func TestVestingTranches(t *testing.T) {
+	grantedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		precision int
+		quantity  string
+		value     string
+		vesting   *models.VestingSchedule
+		want      []string // quantity@value per tranche
+		wantDates []time.Time
+	}{
+		{
+			name:      "last tranche takes the remainder",
+			precision: 6,
+			quantity:  "10.000000",
+			value:     "1000.00",
+			vesting:   &models.VestingSchedule{CliffDays: 30, Tranches: 3, IntervalDays: 30},
+			want:      []string{"3.333333@333.33", "3.333333@333.33", "3.333334@333.34"},
+			wantDates: []time.Time{grantedAt.AddDate(0, 0, 30), grantedAt.AddDate(0, 0, 60), grantedAt.AddDate(0, 0, 90)},
+		},
+		{
+			name:      "configured precision",
+			precision: 2,
+			quantity:  "1.000000",
+			value:     "100.00",
+			vesting:   &models.VestingSchedule{Tranches: 3, IntervalDays: 7},
+			want:      []string{"0.330000@33.33", "0.330000@33.33", "0.340000@33.34"},
+		},
+		{
+			name:      "cliff only",
+			precision: 6,
+			quantity:  "5.500000",
+			value:     "550.00",
+			vesting:   &models.VestingSchedule{CliffDays: 365},
+			want:      []string{"5.500000@550.00"},
+			wantDates: []time.Time{grantedAt.AddDate(0, 0, 365)},
+		},
+		{
+			name:      "explicit dates",
+			precision: 6,
+			quantity:  "2.000000",
+			value:     "300.00",
+			vesting: &models.VestingSchedule{VestDates: []time.Time{
+				time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			}},
+			want: []string{"1.000000@150.00", "1.000000@150.00"},
+			wantDates: []time.Time{
+				time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// Shares round down to nothing: the dropped tranches' value
+			// stays with the one tranche that holds stock
+			name:      "dropped tranches keep their value",
+			precision: 6,
+			quantity:  "0.000002",
+			value:     "0.10",
+			vesting:   &models.VestingSchedule{Tranches: 3, IntervalDays: 30},
+			want:      []string{"0.000002@0.10"},
+		},
+		{
+			name:      "no schedule",
+			precision: 6,
+			quantity:  "10.000000",
+			value:     "1000.00",
+		},
+	}
+	for _, tt := range tests {
+		rs := &RewardService{quantityPrecision: tt.precision}
+		reward := &models.Reward{
+			ID:             7,
+			UserID:         "USR001",
+			StockSymbol:    "TCS",
+			Quantity:       decimal.RequireFromString(tt.quantity),
+			TotalValueINR:  decimal.RequireFromString(tt.value),
+			EventTimestamp: grantedAt,
+			Vesting:        tt.vesting,
+		}
+		tranches := rs.vestingTranches(reward)
+		if len(tranches) != len(tt.want) {
+			t.Errorf("%s: got %d tranches, want %d", tt.name, len(tranches), len(tt.want))
+			continue
+		}
+
+		quantity, value := decimal.Zero, decimal.Zero
+		for i, tranche := range tranches {
+			if got := tranche.Quantity.String() + "@" + tranche.ValueINR.String(); got != tt.want[i] {
+				t.Errorf("%s: tranche %d = %s, want %s", tt.name, i, got, tt.want[i])
+			}
+			if tt.wantDates != nil && !tranche.VestDate.Equal(tt.wantDates[i]) {
+				t.Errorf("%s: tranche %d vests %s, want %s", tt.name, i, tranche.VestDate, tt.wantDates[i])
+			}
+			if tranche.RewardID != reward.ID || tranche.Status != models.VestingStatusUnvested {
+				t.Errorf("%s: tranche %d has reward %d, status %s", tt.name, i, tranche.RewardID, tranche.Status)
+			}
+			quantity, value = quantity.Add(tranche.Quantity), value.Add(tranche.ValueINR)
+		}
+		if len(tranches) > 0 && (!quantity.Equal(reward.Quantity) || !value.Equal(reward.TotalValueINR)) {
+			t.Errorf("%s: tranches sum to %s@%s, want %s@%s", tt.name, quantity, value, reward.Quantity, reward.TotalValueINR)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"os"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrScheduledRewardNotFound is returned when no schedule exists for an ID
+	ErrScheduledRewardNotFound = newError(KindNotFound, "SCHEDULED_REWARD_NOT_FOUND", "scheduled reward not found")
+	// ErrScheduledRewardNotActive is returned when cancelling a finished or cancelled schedule
+	ErrScheduledRewardNotActive = newError(KindConflict, "SCHEDULED_REWARD_NOT_ACTIVE", "scheduled reward is not active")
+	// ErrInvalidScheduledReward is returned when a schedule's reward or timing is invalid
+	ErrInvalidScheduledReward = newError(KindValidation, "INVALID_SCHEDULED_REWARD", "invalid scheduled reward")
+)
+
+// scheduleMetrics counts scheduled runs; published at /debug/vars
+var scheduleMetrics = expvar.NewMap("scheduled_rewards")
+
+// ScheduledRewardService stores future and recurring rewards and grants them
+// when due through ProcessReward
+type ScheduledRewardService struct {
+	scheduleRepo  repository.ScheduledRewardRepository
+	userRepo      repository.UserRepository
+	rewardService *RewardService
+	log           *logrus.Logger
+	cron          *cron.Cron
+	cronEntry     cron.EntryID
+	interval      time.Duration
+	batchSize     int
+}
+
+// ScheduledRewardRequest represents a reward to grant once at start_at, or
+// occurrences times at a daily, weekly or monthly frequency from start_at
+type ScheduledRewardRequest struct {
+	UserID      string          `json:"user_id" binding:"required"`
+	StockSymbol string          `json:"stock_symbol" binding:"required"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	AmountINR   decimal.Decimal `json:"amount_inr"`
+	EventType   string          `json:"event_type"`
+	Notes       string          `json:"notes"`
+	CreatedBy   string          `json:"created_by"`
+	Frequency   string          `json:"frequency" binding:"required"`
+	StartAt     time.Time       `json:"start_at"`
+	Occurrences int             `json:"occurrences"`
+}
+
+// NewScheduledRewardService creates a new scheduled reward service whose runs
+// are added to scheduler
+func NewScheduledRewardService(
+	scheduleRepo repository.ScheduledRewardRepository,
+	userRepo repository.UserRepository,
+	rewardService *RewardService,
+	scheduler *cron.Cron,
+	log *logrus.Logger,
+) *ScheduledRewardService {
+	interval := time.Minute
+	batchSize := 100
+
+	if v := os.Getenv("SCHEDULED_REWARD_INTERVAL_MINUTES"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val > 0 {
+			interval = time.Duration(val) * time.Minute
+		}
+	}
+	if v := os.Getenv("SCHEDULED_REWARD_BATCH_SIZE"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val > 0 {
+			batchSize = val
+		}
+	}
+
+	return &ScheduledRewardService{
+		scheduleRepo:  scheduleRepo,
+		userRepo:      userRepo,
+		rewardService: rewardService,
+		log:           log,
+		cron:          scheduler,
+		interval:      interval,
+		batchSize:     batchSize,
+	}
+}
+
+// Start adds the scheduled runs to the scheduler
+func (s *ScheduledRewardService) Start() error {
+	entry, err := s.cron.AddFunc(fmt.Sprintf("@every %s", s.interval), func() {
+		if _, err := s.RunDue(context.Background()); err != nil {
+			s.log.Errorf("Failed to run scheduled rewards: %v", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule reward scheduler: %w", err)
+	}
+	s.cronEntry = entry
+
+	s.log.Infof("Reward scheduler started with interval %s", s.interval)
+	return nil
+}
+
+// Stop removes the scheduled runs from the scheduler
+func (s *ScheduledRewardService) Stop() {
+	if s.cron != nil {
+		s.cron.Remove(s.cronEntry)
+		s.log.Info("Reward scheduler stopped")
+	}
+}
+
+// CreateScheduledReward validates and stores a schedule; its first run is due at start_at
+func (s *ScheduledRewardService) CreateScheduledReward(ctx context.Context, req *ScheduledRewardRequest) (*models.ScheduledReward, error) {
+	schedule := &models.ScheduledReward{
+		UserID:      req.UserID,
+		StockSymbol: strings.ToUpper(req.StockSymbol),
+		EventType:   strings.ToUpper(req.EventType),
+		Frequency:   strings.ToUpper(req.Frequency),
+		StartAt:     req.StartAt,
+		Occurrences: req.Occurrences,
+		Status:      models.ScheduleStatusActive,
+	}
+	if schedule.EventType == "" {
+		schedule.EventType = "SCHEDULED"
+	}
+	if schedule.StartAt.IsZero() {
+		schedule.StartAt = time.Now()
+	}
+	if schedule.Occurrences == 0 && schedule.Frequency == models.ScheduleFrequencyOnce {
+		schedule.Occurrences = 1
+	}
+	if !req.Quantity.IsZero() {
+		schedule.Quantity = &req.Quantity
+	}
+	if !req.AmountINR.IsZero() {
+		schedule.AmountINR = &req.AmountINR
+	}
+	if req.Notes != "" {
+		schedule.Notes = &req.Notes
+	}
+	if req.CreatedBy != "" {
+		schedule.CreatedBy = &req.CreatedBy
+	}
+	schedule.NextRunAt = &schedule.StartAt
+
+	if err := validateScheduledReward(schedule); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScheduledReward, err)
+	}
+	// Every due run is granted on the next tick, so a schedule may only
+	// start far enough back to owe one period of runs
+	if earliest := earliestStartAt(schedule.Frequency, time.Now()); schedule.StartAt.Before(earliest) {
+		return nil, fmt.Errorf("%w: start_at must not be before %s", ErrInvalidScheduledReward, earliest.Format(time.RFC3339))
+	}
+	// The type's other rules are checked on every run, as they may change
+	if _, err := activeEventType(ctx, s.rewardService.eventTypeRepo, schedule.EventType); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.userRepo.Exists(ctx, schedule.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.scheduleRepo.Create(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to create scheduled reward: %w", err)
+	}
+
+	s.log.Infof("Scheduled %s reward %d for user %s, %d runs from %s",
+		schedule.Frequency, schedule.ID, schedule.UserID, schedule.Occurrences, schedule.StartAt.Format(time.RFC3339))
+	return schedule, nil
+}
+
+// GetScheduledReward retrieves a schedule by ID
+func (s *ScheduledRewardService) GetScheduledReward(ctx context.Context, id int) (*models.ScheduledReward, error) {
+	schedule, err := s.scheduleRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrScheduledRewardNotFound
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// ListScheduledRewards lists schedules, newest first, optionally by user and status
+func (s *ScheduledRewardService) ListScheduledRewards(ctx context.Context, userID, status string, limit, offset int) ([]*models.ScheduledReward, error) {
+	return s.scheduleRepo.List(ctx, userID, status, limit, offset)
+}
+
+// CancelScheduledReward stops a schedule's remaining runs; rewards already granted are kept
+func (s *ScheduledRewardService) CancelScheduledReward(ctx context.Context, id int) (*models.ScheduledReward, error) {
+	cancelled, err := s.scheduleRepo.Cancel(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel scheduled reward: %w", err)
+	}
+
+	schedule, err := s.GetScheduledReward(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, fmt.Errorf("%w: status is %s", ErrScheduledRewardNotActive, schedule.Status)
+	}
+
+	s.log.Infof("Cancelled scheduled reward %d after %d of %d runs", id, schedule.RunsCompleted, schedule.Occurrences)
+	return schedule, nil
+}
+
+// RunDue processes every run that is due across up to one batch of
+// schedules, catching up runs missed while the service was down. It returns
+// the number of runs processed.
+func (s *ScheduledRewardService) RunDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	due, err := s.scheduleRepo.ListDue(ctx, now, s.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due scheduled rewards: %w", err)
+	}
+
+	total := 0
+	for _, schedule := range due {
+		total += s.runSchedule(ctx, schedule, now)
+	}
+
+	if total > 0 {
+		s.log.Infof("Processed %d scheduled reward runs", total)
+	}
+	return total, nil
+}
+
+// runSchedule processes a schedule's due runs in order and returns how many
+// it processed. Run n is always sent as event SCHED-<id>-<n>, so a run that
+// was already granted, or is granted concurrently by another worker, is
+// deduplicated by ProcessReward's idempotency check.
+func (s *ScheduledRewardService) runSchedule(ctx context.Context, schedule *models.ScheduledReward, now time.Time) int {
+	runs := 0
+	for schedule.Status == models.ScheduleStatusActive && schedule.NextRunAt != nil && !schedule.NextRunAt.After(now) {
+		run := schedule.RunsCompleted + 1
+		entry := s.log.WithFields(logrus.Fields{
+			"schedule_id": schedule.ID,
+			"user_id":     schedule.UserID,
+			"run":         run,
+		})
+
+		_, err := s.rewardService.ProcessReward(ctx, scheduledRewardRequest(schedule, run))
+		var perr *processingError
+		switch {
+		case err == nil:
+			schedule.LastError = nil
+		case errors.As(err, &perr):
+			// The run is stored as a FAILED request that can be replayed, so
+			// later runs are not held up behind it
+			scheduleMetrics.Add("failed", 1)
+			entry.Warnf("Scheduled reward run failed and was left for replay: %v", err)
+			message := err.Error()
+			schedule.LastError = &message
+		case errors.Is(err, ErrRequestInProgress):
+			// Another worker is processing this run
+			return runs
+		case permanentRunError(err):
+			// The run was rejected before it was claimed and would be
+			// rejected again, so it is skipped rather than retried forever
+			scheduleMetrics.Add("skipped", 1)
+			entry.Warnf("Scheduled reward run was rejected and skipped: %v", err)
+			message := fmt.Sprintf("run %d skipped: %v", run, err)
+			schedule.LastError = &message
+		default:
+			scheduleMetrics.Add("errors", 1)
+			entry.Errorf("Scheduled reward run could not be processed: %v", err)
+			if setErr := s.scheduleRepo.SetLastError(ctx, schedule.ID, err.Error()); setErr != nil {
+				entry.Errorf("Failed to record scheduled reward error: %v", setErr)
+			}
+			return runs
+		}
+
+		schedule.RunsCompleted = run
+		if run >= schedule.Occurrences {
+			schedule.NextRunAt = nil
+			schedule.Status = models.ScheduleStatusCompleted
+		} else {
+			next := scheduledRunAt(schedule, run+1)
+			schedule.NextRunAt = &next
+		}
+
+		advanced, err := s.scheduleRepo.Advance(ctx, schedule)
+		if err != nil {
+			scheduleMetrics.Add("errors", 1)
+			entry.Errorf("Failed to advance scheduled reward: %v", err)
+			return runs
+		}
+		if !advanced {
+			// Another worker advanced or cancelled the schedule meanwhile
+			return runs
+		}
+		scheduleMetrics.Add("runs", 1)
+		runs++
+	}
+	return runs
+}
+
+// permanentRunError reports whether a run failed for a reason that retrying
+// it cannot fix, such as an inactive event type, a missing creator or an
+// amount too small to buy any stock. Unavailable and internal errors, such as
+// a missing price or a database outage, may clear and are retried.
+func permanentRunError(err error) bool {
+	switch AsError(err).Kind {
+	case KindValidation, KindNotFound, KindConflict:
+		return true
+	default:
+		return false
+	}
+}
+
+// scheduledRewardRequest builds the reward request of run n. The event time
+// is left empty so a run caught up after downtime is priced when it is granted.
+func scheduledRewardRequest(schedule *models.ScheduledReward, run int) *RewardRequest {
+	notes := fmt.Sprintf("Scheduled reward %d, run %d of %d due %s",
+		schedule.ID, run, schedule.Occurrences, scheduledRunAt(schedule, run).Format(time.RFC3339))
+	if schedule.Notes != nil {
+		notes = fmt.Sprintf("%s: %s", notes, *schedule.Notes)
+	}
+
+	req := &RewardRequest{
+		UserID:      schedule.UserID,
+		StockSymbol: schedule.StockSymbol,
+		EventID:     scheduledEventID(schedule.ID, run),
+		EventType:   schedule.EventType,
+		Notes:       notes,
+	}
+	if schedule.CreatedBy != nil {
+		req.CreatedBy = *schedule.CreatedBy
+	}
+	if schedule.Quantity != nil {
+		req.Quantity = *schedule.Quantity
+	}
+	if schedule.AmountINR != nil {
+		req.AmountINR = *schedule.AmountINR
+	}
+	return req
+}
+
+// scheduledEventID is the event ID of run n of a schedule
+func scheduledEventID(scheduleID, run int) string {
+	return fmt.Sprintf("SCHED-%d-%d", scheduleID, run)
+}
+
+// scheduledRunAt returns the due time of run n, counting from 1. Each run is
+// offset from start_at rather than from the previous run, so monthly runs
+// keep their day of month, or fall on the last day of shorter months.
+func scheduledRunAt(schedule *models.ScheduledReward, run int) time.Time {
+	switch schedule.Frequency {
+	case models.ScheduleFrequencyDaily:
+		return schedule.StartAt.AddDate(0, 0, run-1)
+	case models.ScheduleFrequencyWeekly:
+		return schedule.StartAt.AddDate(0, 0, 7*(run-1))
+	case models.ScheduleFrequencyMonthly:
+		return addMonths(schedule.StartAt, run-1)
+	default:
+		return schedule.StartAt
+	}
+}
+
+// addMonths moves t by months, clamped to the last day of the target month
+// where AddDate would run into the next one
+func addMonths(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// earliestStartAt is the earliest start_at a new schedule of the frequency
+// accepts: one period before now, a day for one-off schedules
+func earliestStartAt(frequency string, now time.Time) time.Time {
+	switch frequency {
+	case models.ScheduleFrequencyWeekly:
+		return now.AddDate(0, 0, -7)
+	case models.ScheduleFrequencyMonthly:
+		return addMonths(now, -1)
+	default:
+		return now.AddDate(0, 0, -1)
+	}
+}
+
+// validateScheduledReward checks a schedule's reward and timing
+func validateScheduledReward(schedule *models.ScheduledReward) error {
+	if schedule.UserID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	if schedule.StockSymbol == "" {
+		return fmt.Errorf("stock_symbol is required")
+	}
+	if (schedule.Quantity == nil) == (schedule.AmountINR == nil) {
+		return fmt.Errorf("exactly one of quantity or amount_inr is required")
+	}
+	if schedule.Quantity != nil {
+		if !schedule.Quantity.IsPositive() {
+			return fmt.Errorf("quantity must be positive")
+		}
+		if err := normalizePlaces("quantity", schedule.Quantity, models.QuantityScale); err != nil {
+			return err
+		}
+	}
+	if schedule.AmountINR != nil {
+		if !schedule.AmountINR.IsPositive() {
+			return fmt.Errorf("amount_inr must be positive")
+		}
+		if err := normalizePlaces("amount_inr", schedule.AmountINR, models.AmountScale); err != nil {
+			return err
+		}
+	}
+
+	switch schedule.Frequency {
+	case models.ScheduleFrequencyOnce:
+		if schedule.Occurrences != 1 {
+			return fmt.Errorf("%s schedules run exactly once", schedule.Frequency)
+		}
+	case models.ScheduleFrequencyDaily, models.ScheduleFrequencyWeekly, models.ScheduleFrequencyMonthly:
+		if schedule.Occurrences < 1 {
+			return fmt.Errorf("occurrences must be at least 1")
+		}
+	default:
+		return fmt.Errorf("frequency must be one of %s, %s, %s, %s", models.ScheduleFrequencyOnce,
+			models.ScheduleFrequencyDaily, models.ScheduleFrequencyWeekly, models.ScheduleFrequencyMonthly)
+	}
+	return nil
+}
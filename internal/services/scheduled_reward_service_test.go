@@ -0,0 +1,62 @@
+package services
+
+import (
+	"stockBackend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestScheduledRunAt(t *testing.T) {
+	at := func(s string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name      string
+		frequency string
+		startAt   string
+		want      []string // due times of runs 1, 2, ...
+	}{
+		{"once", models.ScheduleFrequencyOnce, "2026-01-31T09:00:00Z", []string{"2026-01-31T09:00:00Z"}},
+		{"daily", models.ScheduleFrequencyDaily, "2026-02-27T09:00:00Z", []string{"2026-02-27T09:00:00Z", "2026-02-28T09:00:00Z", "2026-03-01T09:00:00Z"}},
+		{"weekly", models.ScheduleFrequencyWeekly, "2026-12-24T09:00:00Z", []string{"2026-12-24T09:00:00Z", "2026-12-31T09:00:00Z", "2027-01-07T09:00:00Z"}},
+		{"monthly", models.ScheduleFrequencyMonthly, "2026-01-15T09:00:00Z", []string{"2026-01-15T09:00:00Z", "2026-02-15T09:00:00Z", "2026-03-15T09:00:00Z"}},
+		// Runs fall on the last day of shorter months and return to the
+		// 31st afterwards, rather than drifting into the next month
+		{"monthly from the 31st", models.ScheduleFrequencyMonthly, "2026-01-31T09:00:00Z",
+			[]string{"2026-01-31T09:00:00Z", "2026-02-28T09:00:00Z", "2026-03-31T09:00:00Z", "2026-04-30T09:00:00Z", "2026-05-31T09:00:00Z"}},
+		{"monthly into a leap February", models.ScheduleFrequencyMonthly, "2027-12-30T09:00:00Z",
+			[]string{"2027-12-30T09:00:00Z", "2028-01-30T09:00:00Z", "2028-02-29T09:00:00Z", "2028-03-30T09:00:00Z"}},
+		{"monthly across years", models.ScheduleFrequencyMonthly, "2026-11-30T09:00:00Z", []string{"2026-11-30T09:00:00Z", "2026-12-30T09:00:00Z", "2027-01-30T09:00:00Z"}},
+	}
+	for _, tt := range tests {
+		schedule := &models.ScheduledReward{Frequency: tt.frequency, StartAt: at(tt.startAt)}
+		for i, want := range tt.want {
+			if got := scheduledRunAt(schedule, i+1); !got.Equal(at(want)) {
+				t.Errorf("%s: run %d due %s, want %s", tt.name, i+1, got.Format(time.RFC3339), want)
+			}
+		}
+	}
+}
+
+func TestEarliestStartAt(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		frequency string
+		want      time.Time
+	}{
+		{models.ScheduleFrequencyOnce, time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)},
+		{models.ScheduleFrequencyDaily, time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)},
+		{models.ScheduleFrequencyWeekly, time.Date(2026, 3, 24, 12, 0, 0, 0, time.UTC)},
+		{models.ScheduleFrequencyMonthly, time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := earliestStartAt(tt.frequency, now); !got.Equal(tt.want) {
+			t.Errorf("%s: earliest start %s, want %s", tt.frequency, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"stockBackend/pkg/decimal"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// Event types of the paired rewards a transfer books
+const (
+	EventTypeTransferOut = "TRANSFER_OUT"
+	EventTypeTransferIn  = "TRANSFER_IN"
+)
+
+// AccountTransferClearing carries transferred stock between the sender's and
+// the receiver's entries; across both users it nets to zero
+const AccountTransferClearing = "TRANSFER_CLEARING"
+
+var (
+	// ErrTransferNotFound is returned when no transfer exists for a transfer ID
+	ErrTransferNotFound = newError(KindNotFound, "TRANSFER_NOT_FOUND", "transfer not found")
+	// ErrTransferIDInUse is returned when a transfer ID was used for a different transfer
+	ErrTransferIDInUse = newError(KindConflict, "TRANSFER_ID_IN_USE", "transfer_id already used by another transfer")
+	// ErrInvalidTransfer is returned when a transfer request is invalid
+	ErrInvalidTransfer = newError(KindValidation, "INVALID_TRANSFER", "invalid transfer")
+)
+
+// TransferService moves rewarded stock from one user to another
+type TransferService struct {
+	rewardRepo     repository.RewardRepository
+	ledgerRepo     repository.LedgerRepository
+	vestingRepo    repository.VestingRepository
+	transferRepo   repository.TransferRepository
+	redemptionRepo repository.RedemptionRepository
+	userRepo       repository.UserRepository
+	priceService   *PriceService
+	log            *logrus.Logger
+}
+
+// TransferRequest represents a gift of quantity of a symbol between users
+type TransferRequest struct {
+	TransferID  string          `json:"transfer_id" binding:"required"`
+	FromUserID  string          `json:"from_user_id" binding:"required"`
+	ToUserID    string          `json:"to_user_id" binding:"required"`
+	StockSymbol string          `json:"stock_symbol" binding:"required"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	Note        string          `json:"note"`
+}
+
+// NewTransferService creates a new transfer service
+func NewTransferService(
+	rewardRepo repository.RewardRepository,
+	ledgerRepo repository.LedgerRepository,
+	vestingRepo repository.VestingRepository,
+	transferRepo repository.TransferRepository,
+	redemptionRepo repository.RedemptionRepository,
+	userRepo repository.UserRepository,
+	priceService *PriceService,
+	log *logrus.Logger,
+) *TransferService {
+	return &TransferService{
+		rewardRepo:     rewardRepo,
+		ledgerRepo:     ledgerRepo,
+		vestingRepo:    vestingRepo,
+		transferRepo:   transferRepo,
+		redemptionRepo: redemptionRepo,
+		userRepo:       userRepo,
+		priceService:   priceService,
+		log:            log,
+	}
+}
+
+// Transfer moves stock from the sender to the receiver at the current price.
+// Only the sender's available holding, net of unvested stock and requested
+// redemptions, can be sent.
+// The TRANSFER_OUT and TRANSFER_IN rewards and their ledger entries commit
+// together; repeating a call with the same transfer ID returns the stored
+// transfer.
+func (ts *TransferService) Transfer(ctx context.Context, req *TransferRequest) (*models.Transfer, error) {
+	req.StockSymbol = strings.ToUpper(req.StockSymbol)
+	if err := validateTransfer(req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransfer, err)
+	}
+
+	for _, userID := range []string{req.FromUserID, req.ToUserID} {
+		exists, err := ts.userRepo.Exists(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check user existence: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
+		}
+	}
+
+	transfer := &models.Transfer{
+		TransferID:  req.TransferID,
+		FromUserID:  req.FromUserID,
+		ToUserID:    req.ToUserID,
+		StockSymbol: req.StockSymbol,
+		Quantity:    req.Quantity,
+	}
+	if req.Note != "" {
+		transfer.Note = &req.Note
+	}
+
+	replayed := false
+	err := db.WithTransaction(ctx, func(ctx context.Context) error {
+		// The sender's holding is locked before the transfer is claimed, the
+		// same order rewards use, so a concurrent adjustment cannot interleave
+		if err := ts.rewardRepo.LockHolding(ctx, req.FromUserID, req.StockSymbol); err != nil {
+			return err
+		}
+
+		claimed, err := ts.transferRepo.Claim(ctx, transfer)
+		if err != nil {
+			return fmt.Errorf("failed to record transfer: %w", err)
+		}
+		if !claimed {
+			existing, err := ts.transferRepo.GetByTransferID(ctx, req.TransferID)
+			if err != nil {
+				return fmt.Errorf("failed to load transfer: %w", err)
+			}
+			if existing.FromUserID != req.FromUserID || existing.ToUserID != req.ToUserID ||
+				existing.StockSymbol != req.StockSymbol || !existing.Quantity.Equal(req.Quantity) {
+				return ErrTransferIDInUse
+			}
+			transfer = existing
+			replayed = true
+			return nil
+		}
+
+		if err := ts.checkAvailable(ctx, transfer); err != nil {
+			return err
+		}
+		return ts.bookTransfer(ctx, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !replayed {
+		ts.log.Infof("Transfer %s moved %s %s from %s to %s",
+			transfer.TransferID, transfer.Quantity, transfer.StockSymbol, transfer.FromUserID, transfer.ToUserID)
+	}
+	return transfer, nil
+}
+
+// checkAvailable rejects a transfer larger than the sender's holding net of
+// unvested stock and of requested redemptions, the same availability a
+// redemption is checked against; ctx must carry the transaction holding the
+// sender's lock
+func (ts *TransferService) checkAvailable(ctx context.Context, transfer *models.Transfer) error {
+	held, err := ts.rewardRepo.GetNetQuantity(ctx, transfer.FromUserID, transfer.StockSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to get current holding: %w", err)
+	}
+	unvested, err := ts.vestingRepo.GetUnvestedQuantities(ctx, transfer.FromUserID)
+	if err != nil {
+		return fmt.Errorf("failed to load unvested quantities: %w", err)
+	}
+
+	requested, err := ts.redemptionRepo.GetRequestedQuantity(ctx, transfer.FromUserID, transfer.StockSymbol, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load requested redemptions: %w", err)
+	}
+
+	available := held.Sub(unvested[transfer.StockSymbol]).Sub(requested)
+	if transfer.Quantity.GreaterThan(available) {
+		return fmt.Errorf("%w: user %s has %s %s available to transfer (%s unvested, %s requested for redemption), requested %s",
+			ErrInsufficientHoldings, transfer.FromUserID, decimal.Max(available, decimal.Zero), transfer.StockSymbol,
+			unvested[transfer.StockSymbol], requested, transfer.Quantity)
+	}
+	return nil
+}
+
+// bookTransfer prices a claimed transfer and books its paired rewards and
+// ledger entries; ctx must carry a transaction
+func (ts *TransferService) bookTransfer(ctx context.Context, transfer *models.Transfer) error {
+	stockPrice, err := ts.priceService.GetPriceAt(ctx, transfer.StockSymbol, time.Time{})
+	if err != nil {
+		return fmt.Errorf("failed to get stock price: %w", err)
+	}
+	value := roundINR(transfer.Quantity.Mul(stockPrice.Price))
+	if !value.IsPositive() {
+		return fmt.Errorf("%w: %s %s is worth less than 0.01 INR", ErrInvalidTransfer, transfer.Quantity, transfer.StockSymbol)
+	}
+
+	now := time.Now()
+	outNotes := fmt.Sprintf("Transfer %s to %s", transfer.TransferID, transfer.ToUserID)
+	inNotes := fmt.Sprintf("Transfer %s from %s", transfer.TransferID, transfer.FromUserID)
+	if transfer.Note != nil {
+		outNotes = fmt.Sprintf("%s: %s", outNotes, *transfer.Note)
+		inNotes = fmt.Sprintf("%s: %s", inNotes, *transfer.Note)
+	}
+
+	out := &models.Reward{
+		UserID:         transfer.FromUserID,
+		StockSymbol:    transfer.StockSymbol,
+		Quantity:       transfer.Quantity.Neg(),
+		EventType:      EventTypeTransferOut,
+		EventID:        transfer.TransferID + "-OUT",
+		EventTimestamp: now,
+		StockPrice:     stockPrice.Price,
+		StockPriceID:   &stockPrice.ID,
+		TotalValueINR:  value.Neg(),
+		NetValueINR:    value.Neg(),
+		Status:         models.RewardStatusCompleted,
+		Notes:          &outNotes,
+	}
+	in := &models.Reward{
+		UserID:         transfer.ToUserID,
+		StockSymbol:    transfer.StockSymbol,
+		Quantity:       transfer.Quantity,
+		EventType:      EventTypeTransferIn,
+		EventID:        transfer.TransferID + "-IN",
+		EventTimestamp: now,
+		StockPrice:     stockPrice.Price,
+		StockPriceID:   &stockPrice.ID,
+		TotalValueINR:  value,
+		NetValueINR:    value,
+		Status:         models.RewardStatusCompleted,
+		Notes:          &inNotes,
+	}
+	if err := ts.rewardRepo.BulkCreate(ctx, []*models.Reward{out, in}); err != nil {
+		return fmt.Errorf("failed to create transfer rewards: %w", err)
+	}
+	if err := ts.ledgerRepo.BulkCreate(ctx, transferLedgerEntries(transfer, out, in)); err != nil {
+		return fmt.Errorf("failed to create transfer ledger entries: %w", err)
+	}
+
+	transfer.StockPrice = &stockPrice.Price
+	transfer.StockPriceID = &stockPrice.ID
+	transfer.ValueINR = &value
+	transfer.OutRewardID = &out.ID
+	transfer.InRewardID = &in.ID
+	if err := ts.transferRepo.Complete(ctx, transfer); err != nil {
+		return fmt.Errorf("failed to complete transfer: %w", err)
+	}
+	return nil
+}
+
+// GetTransfer retrieves a transfer by its transfer ID
+func (ts *TransferService) GetTransfer(ctx context.Context, transferID string) (*models.Transfer, error) {
+	transfer, err := ts.transferRepo.GetByTransferID(ctx, transferID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// GetUserTransfers lists the transfers a user sent or received, newest first
+func (ts *TransferService) GetUserTransfers(ctx context.Context, userID string, limit, offset int) ([]*models.Transfer, error) {
+	return ts.transferRepo.GetByUserID(ctx, userID, limit, offset)
+}
+
+// validateTransfer checks a transfer request
+func validateTransfer(req *TransferRequest) error {
+	if req.TransferID == "" {
+		return fmt.Errorf("transfer_id is required")
+	}
+	// Leaves room for the -OUT suffix within rewards.event_id
+	if len(req.TransferID) > maxEventIDLength-4 {
+		return fmt.Errorf("transfer_id must be at most %d characters", maxEventIDLength-4)
+	}
+	if req.FromUserID == "" || req.ToUserID == "" {
+		return fmt.Errorf("from_user_id and to_user_id are required")
+	}
+	if req.FromUserID == req.ToUserID {
+		return fmt.Errorf("a user cannot transfer to themselves")
+	}
+	if req.StockSymbol == "" {
+		return fmt.Errorf("stock_symbol is required")
+	}
+	if !req.Quantity.IsPositive() {
+		return fmt.Errorf("quantity must be positive")
+	}
+	return normalizePlaces("quantity", &req.Quantity, models.QuantityScale)
+}
+
+// transferLedgerEntries moves the transferred value out of the sender's
+// stock asset and into the receiver's, through the transfer clearing account
+func transferLedgerEntries(transfer *models.Transfer, out, in *models.Reward) []*models.LedgerEntry {
+	value := in.TotalValueINR
+	desc := fmt.Sprintf("Transfer %s: %s x %s from %s to %s",
+		transfer.TransferID, transfer.StockSymbol, transfer.Quantity, transfer.FromUserID, transfer.ToUserID)
+
+	entry := func(reward *models.Reward, entryType, account string) *models.LedgerEntry {
+		return &models.LedgerEntry{
+			RewardID:    reward.ID,
+			UserID:      reward.UserID,
+			EntryType:   entryType,
+			AccountType: account,
+			Amount:      value,
+			Currency:    "INR",
+			Description: &desc,
+			ReferenceID: &transfer.TransferID,
+		}
+	}
+	return []*models.LedgerEntry{
+		entry(out, "CREDIT", AccountStockAsset),
+		entry(out, "DEBIT", AccountTransferClearing),
+		entry(in, "DEBIT", AccountStockAsset),
+		entry(in, "CREDIT", AccountTransferClearing),
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"os"
+	"stockBackend/internal/db"
+	"stockBackend/internal/models"
+	"stockBackend/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// vestingMetrics counts vested tranches and failed runs; published at /debug/vars
+var vestingMetrics = expvar.NewMap("reward_vesting")
+
+// VestingService vests due reward tranches on a daily schedule
+type VestingService struct {
+	vestingRepo repository.VestingRepository
+	ledgerRepo  repository.LedgerRepository
+	log         *logrus.Logger
+	cron        *cron.Cron
+	cronEntry   cron.EntryID
+	schedule    string
+	batchSize   int
+}
+
+// NewVestingService creates a new vesting service whose runs are added to scheduler
+func NewVestingService(
+	vestingRepo repository.VestingRepository,
+	ledgerRepo repository.LedgerRepository,
+	scheduler *cron.Cron,
+	log *logrus.Logger,
+) *VestingService {
+	schedule := "@daily"
+	batchSize := 500
+
+	if v := os.Getenv("VESTING_SCHEDULE"); v != "" {
+		schedule = v
+	}
+	if v := os.Getenv("VESTING_BATCH_SIZE"); v != "" {
+		if val, err := strconv.Atoi(v); err == nil && val > 0 {
+			batchSize = val
+		}
+	}
+
+	return &VestingService{
+		vestingRepo: vestingRepo,
+		ledgerRepo:  ledgerRepo,
+		log:         log,
+		cron:        scheduler,
+		schedule:    schedule,
+		batchSize:   batchSize,
+	}
+}
+
+// Start adds the vesting runs to the scheduler
+func (s *VestingService) Start() error {
+	entry, err := s.cron.AddFunc(s.schedule, func() {
+		if _, err := s.VestDueTranches(context.Background()); err != nil {
+			s.log.Errorf("Failed to vest due tranches: %v", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule vesting: %w", err)
+	}
+	s.cronEntry = entry
+
+	s.log.Infof("Vesting job started with schedule %s", s.schedule)
+	return nil
+}
+
+// Stop removes the vesting runs from the scheduler
+func (s *VestingService) Stop() {
+	if s.cron != nil {
+		s.cron.Remove(s.cronEntry)
+		s.log.Info("Vesting job stopped")
+	}
+}
+
+// VestDueTranches vests every tranche due by now whose reward is settled,
+// posting the move from unvested to vested stock with each batch. It returns
+// the number of tranches vested.
+func (s *VestingService) VestDueTranches(ctx context.Context) (int, error) {
+	now := time.Now()
+	total := 0
+
+	for {
+		vested := 0
+		err := db.WithTransaction(ctx, func(ctx context.Context) error {
+			tranches, err := s.vestingRepo.LockDue(ctx, now, s.batchSize)
+			if err != nil {
+				return fmt.Errorf("failed to load due tranches: %w", err)
+			}
+			if len(tranches) == 0 {
+				return nil
+			}
+
+			ids := make([]int, 0, len(tranches))
+			entries := make([]*models.LedgerEntry, 0, len(tranches)*2)
+			for _, tranche := range tranches {
+				ids = append(ids, tranche.ID)
+				entries = append(entries, vestingLedgerEntries(tranche)...)
+			}
+			if err := s.ledgerRepo.BulkCreate(ctx, entries); err != nil {
+				return fmt.Errorf("failed to post vesting entries: %w", err)
+			}
+			if err := s.vestingRepo.MarkVested(ctx, ids, now); err != nil {
+				return fmt.Errorf("failed to mark tranches vested: %w", err)
+			}
+
+			vested = len(tranches)
+			return nil
+		})
+		if err != nil {
+			vestingMetrics.Add("errors", 1)
+			return total, err
+		}
+
+		total += vested
+		vestingMetrics.Add("vested", int64(vested))
+		if vested < s.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		s.log.Infof("Vested %d reward tranches", total)
+	}
+	return total, nil
+}
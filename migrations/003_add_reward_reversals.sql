@@ -0,0 +1,42 @@
+-- Reward reversals
+-- A reversal marks the original reward REVERSED and books a linked negative reward
+-- with mirror-image ledger entries, so the history is kept instead of deleted
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS reversal_of INTEGER REFERENCES rewards(id);
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS reversed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rewards_reversal_of ON rewards(reversal_of) WHERE reversal_of IS NOT NULL;
+
+COMMENT ON COLUMN rewards.reversal_of IS 'Reward reversed by this row (set only on reversal rows)';
+COMMENT ON COLUMN rewards.reversed_at IS 'When the reward was reversed';
+
+
+-- A reversed reward and its reversal cancel out, so both are left out of aggregates
+CREATE OR REPLACE VIEW v_user_portfolio AS
+SELECT 
+    r.user_id,
+    r.stock_symbol,
+    SUM(r.quantity) as total_quantity,
+    AVG(r.stock_price) as avg_purchase_price,
+    SUM(r.total_value_inr) as total_invested_inr,
+    SUM(r.brokerage_fee + r.transaction_fee) as total_fees,
+    COUNT(*) as transaction_count,
+    MIN(r.event_timestamp) as first_reward_date,
+    MAX(r.event_timestamp) as last_reward_date
+FROM rewards r
+WHERE r.status = 'COMPLETED' AND r.reversal_of IS NULL
+GROUP BY r.user_id, r.stock_symbol
+HAVING SUM(r.quantity) > 0;
+
+
+CREATE OR REPLACE VIEW v_daily_holdings AS
+SELECT 
+    r.user_id,
+    r.stock_symbol,
+    DATE(r.event_timestamp) as holding_date,
+    SUM(r.quantity) as daily_quantity,
+    SUM(r.total_value_inr) as daily_value_inr
+FROM rewards r
+WHERE r.status = 'COMPLETED' AND r.reversal_of IS NULL
+GROUP BY r.user_id, r.stock_symbol, DATE(r.event_timestamp)
+ORDER BY holding_date DESC;
@@ -0,0 +1,6 @@
+-- Request fingerprints for idempotency
+-- Reusing an event_id with a different payload is rejected instead of replaying the old result
+
+ALTER TABLE reward_requests ADD COLUMN IF NOT EXISTS request_fingerprint VARCHAR(64);
+
+COMMENT ON COLUMN reward_requests.request_fingerprint IS 'SHA-256 of the canonical request, compared when an event_id is reused';
@@ -0,0 +1,12 @@
+-- Failure tracking for reward requests
+-- Failed requests keep an error code, message and attempt count so ops can list and replay them
+
+ALTER TABLE reward_requests ADD COLUMN IF NOT EXISTS error_code VARCHAR(50);
+ALTER TABLE reward_requests ADD COLUMN IF NOT EXISTS error_message TEXT;
+ALTER TABLE reward_requests ADD COLUMN IF NOT EXISTS attempt_count INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE reward_requests ADD COLUMN IF NOT EXISTS last_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_reward_requests_status_created ON reward_requests(status, created_at DESC);
+
+COMMENT ON COLUMN reward_requests.error_code IS 'Machine-readable reason of the last failure, e.g. PRICE_UNAVAILABLE';
+COMMENT ON COLUMN reward_requests.attempt_count IS 'Number of times the request has been attempted';
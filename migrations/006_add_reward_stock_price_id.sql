@@ -0,0 +1,8 @@
+-- Event-time pricing
+-- Each reward records the stock_prices row it was valued at
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS stock_price_id INTEGER REFERENCES stock_prices(id);
+
+CREATE INDEX IF NOT EXISTS idx_rewards_stock_price_id ON rewards(stock_price_id);
+
+COMMENT ON COLUMN rewards.stock_price_id IS 'Price row used to value the reward: the latest at or before event_timestamp';
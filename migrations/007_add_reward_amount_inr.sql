@@ -0,0 +1,8 @@
+-- INR-denominated rewards
+-- A reward requested as an INR amount keeps that amount and its fee policy
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS requested_amount_inr DECIMAL(15, 2);
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS deduct_fees BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN rewards.requested_amount_inr IS 'INR amount the reward was requested for; NULL when requested by quantity';
+COMMENT ON COLUMN rewards.deduct_fees IS 'Fees were paid out of requested_amount_inr instead of on top of it';
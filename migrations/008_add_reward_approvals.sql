@@ -0,0 +1,52 @@
+-- Reward approval workflow
+-- Rewards above the approval threshold are created PENDING_APPROVAL and only
+-- reach the ledger and portfolio once a different reviewer APPROVES them
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS created_by VARCHAR(100);
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS reviewed_by VARCHAR(100);
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS review_note TEXT;
+
+ALTER TABLE rewards DROP CONSTRAINT IF EXISTS chk_rewards_status;
+ALTER TABLE rewards ADD CONSTRAINT chk_rewards_status
+    CHECK (status IN ('COMPLETED', 'REVERSED', 'PENDING_APPROVAL', 'APPROVED', 'REJECTED'));
+
+ALTER TABLE rewards DROP CONSTRAINT IF EXISTS chk_rewards_reviewer;
+ALTER TABLE rewards ADD CONSTRAINT chk_rewards_reviewer
+    CHECK (reviewed_by IS NULL OR created_by IS NULL OR reviewed_by <> created_by);
+
+CREATE INDEX IF NOT EXISTS idx_rewards_status ON rewards(status);
+
+COMMENT ON COLUMN rewards.created_by IS 'Identity that requested the reward (the maker)';
+COMMENT ON COLUMN rewards.reviewed_by IS 'Identity that approved or rejected the reward (the checker)';
+
+
+-- Approved rewards count like completed ones
+CREATE OR REPLACE VIEW v_user_portfolio AS
+SELECT 
+    r.user_id,
+    r.stock_symbol,
+    SUM(r.quantity) as total_quantity,
+    AVG(r.stock_price) as avg_purchase_price,
+    SUM(r.total_value_inr) as total_invested_inr,
+    SUM(r.brokerage_fee + r.transaction_fee) as total_fees,
+    COUNT(*) as transaction_count,
+    MIN(r.event_timestamp) as first_reward_date,
+    MAX(r.event_timestamp) as last_reward_date
+FROM rewards r
+WHERE r.status IN ('COMPLETED', 'APPROVED') AND r.reversal_of IS NULL
+GROUP BY r.user_id, r.stock_symbol
+HAVING SUM(r.quantity) > 0;
+
+
+CREATE OR REPLACE VIEW v_daily_holdings AS
+SELECT 
+    r.user_id,
+    r.stock_symbol,
+    DATE(r.event_timestamp) as holding_date,
+    SUM(r.quantity) as daily_quantity,
+    SUM(r.total_value_inr) as daily_value_inr
+FROM rewards r
+WHERE r.status IN ('COMPLETED', 'APPROVED') AND r.reversal_of IS NULL
+GROUP BY r.user_id, r.stock_symbol, DATE(r.event_timestamp)
+ORDER BY holding_date DESC;
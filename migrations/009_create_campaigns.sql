@@ -0,0 +1,48 @@
+-- Reward campaigns
+-- A campaign decides the symbol and quantity of its rewards from a rule and
+-- caps total and per-user spend; spend is reserved in the reward's transaction
+
+CREATE TABLE IF NOT EXISTS campaigns (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    event_type VARCHAR(50) NOT NULL DEFAULT 'REWARD',
+    rule_type VARCHAR(20) NOT NULL CHECK (rule_type IN ('FIXED_QUANTITY', 'FIXED_INR', 'RANDOM_BASKET')),
+    stock_symbol VARCHAR(20),
+    basket TEXT[],
+    quantity DECIMAL(15, 6) CHECK (quantity > 0),
+    amount_inr DECIMAL(15, 2) CHECK (amount_inr > 0),
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    budget_inr DECIMAL(15, 2) NOT NULL CHECK (budget_inr > 0),
+    spent_inr DECIMAL(15, 2) NOT NULL DEFAULT 0 CHECK (spent_inr >= 0),
+    per_user_cap_inr DECIMAL(15, 2) CHECK (per_user_cap_inr > 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (spent_inr <= budget_inr),
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_active ON campaigns(active, starts_at);
+
+COMMENT ON TABLE campaigns IS 'Reward campaigns with a quantity rule and INR budget';
+COMMENT ON COLUMN campaigns.basket IS 'Symbols a RANDOM_BASKET rule picks from';
+COMMENT ON COLUMN campaigns.spent_inr IS 'Reward value reserved so far; never exceeds budget_inr';
+
+CREATE TABLE IF NOT EXISTS campaign_user_spend (
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    spent_inr DECIMAL(15, 2) NOT NULL DEFAULT 0 CHECK (spent_inr >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (campaign_id, user_id)
+);
+
+COMMENT ON TABLE campaign_user_spend IS 'Per-user campaign spend, checked against per_user_cap_inr';
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS campaign_id INTEGER REFERENCES campaigns(id);
+
+CREATE INDEX IF NOT EXISTS idx_rewards_campaign_id ON rewards(campaign_id);
+
+DROP TRIGGER IF EXISTS update_campaigns_updated_at ON campaigns;
+CREATE TRIGGER update_campaigns_updated_at BEFORE UPDATE ON campaigns
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,13 @@
+-- Reward risk rules
+-- Velocity and size limits are checked before a reward is booked; the rule
+-- that fired is kept on the request whether it was rejected or sent to review
+
+ALTER TABLE reward_requests ADD COLUMN IF NOT EXISTS risk_rule VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_reward_requests_risk_rule ON reward_requests(risk_rule)
+    WHERE risk_rule IS NOT NULL;
+
+-- Per-user velocity checks count recent rewards
+CREATE INDEX IF NOT EXISTS idx_rewards_user_created ON rewards(user_id, created_at DESC);
+
+COMMENT ON COLUMN reward_requests.risk_rule IS 'Risk rule that rejected the request or sent its reward to review';
@@ -0,0 +1,27 @@
+-- Reward vesting
+-- A vesting reward is booked to UNVESTED_STOCK_ASSET and split into dated
+-- tranches; a daily job moves each due tranche to STOCK_ASSET
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS vesting JSONB;
+
+COMMENT ON COLUMN rewards.vesting IS 'Requested vesting schedule (cliff, tranches, vest dates); NULL vests immediately';
+
+CREATE TABLE IF NOT EXISTS reward_vesting_tranches (
+    id SERIAL PRIMARY KEY,
+    reward_id INTEGER NOT NULL REFERENCES rewards(id) ON DELETE CASCADE,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    stock_symbol VARCHAR(20) NOT NULL,
+    quantity DECIMAL(15, 6) NOT NULL CHECK (quantity > 0),
+    value_inr DECIMAL(15, 2) NOT NULL,
+    vest_date TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'UNVESTED' CHECK (status IN ('UNVESTED', 'VESTED', 'CANCELLED')),
+    vested_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_vesting_tranches_reward_id ON reward_vesting_tranches(reward_id);
+CREATE INDEX IF NOT EXISTS idx_vesting_tranches_user_symbol ON reward_vesting_tranches(user_id, stock_symbol);
+CREATE INDEX IF NOT EXISTS idx_vesting_tranches_due ON reward_vesting_tranches(vest_date)
+    WHERE status = 'UNVESTED';
+
+COMMENT ON TABLE reward_vesting_tranches IS 'Dated portions of vesting rewards; value_inr is valued at the grant price';
@@ -0,0 +1,23 @@
+-- Reward clawbacks
+-- A clawback reclaims a user's recent grants with negative CLAWBACK rewards;
+-- each run is stored once under its clawback_id with what it recovered
+
+CREATE TABLE IF NOT EXISTS clawbacks (
+    id SERIAL PRIMARY KEY,
+    clawback_id VARCHAR(255) UNIQUE NOT NULL,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    window_days INTEGER NOT NULL CHECK (window_days > 0),
+    event_types TEXT[],
+    reason TEXT,
+    items JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_clawbacks_user_id ON clawbacks(user_id);
+
+COMMENT ON TABLE clawbacks IS 'Clawback runs; items holds granted, recovered and unrecoverable quantity per symbol';
+
+-- Grants already covered by a clawback are not clawed back again
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS clawed_back_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN rewards.clawed_back_at IS 'When a clawback covered this grant';
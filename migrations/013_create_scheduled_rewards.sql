@@ -0,0 +1,30 @@
+-- Scheduled and recurring rewards
+-- A schedule grants the same reward once or every day, week or month for a
+-- fixed number of runs; run n is processed as event SCHED-<id>-<n>
+
+CREATE TABLE IF NOT EXISTS scheduled_rewards (
+    id SERIAL PRIMARY KEY,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    stock_symbol VARCHAR(20) NOT NULL,
+    quantity DECIMAL(15, 6) CHECK (quantity > 0),
+    amount_inr DECIMAL(15, 2) CHECK (amount_inr > 0),
+    event_type VARCHAR(50) NOT NULL DEFAULT 'SCHEDULED',
+    notes TEXT,
+    created_by VARCHAR(100),
+    frequency VARCHAR(20) NOT NULL CHECK (frequency IN ('ONCE', 'DAILY', 'WEEKLY', 'MONTHLY')),
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    occurrences INTEGER NOT NULL CHECK (occurrences > 0),
+    runs_completed INTEGER NOT NULL DEFAULT 0,
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'COMPLETED', 'CANCELLED')),
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((quantity IS NULL) <> (amount_inr IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_rewards_due ON scheduled_rewards(next_run_at) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_scheduled_rewards_user_id ON scheduled_rewards(user_id);
+
+COMMENT ON TABLE scheduled_rewards IS 'Future and recurring rewards, granted by the scheduler through the normal reward path';
+COMMENT ON COLUMN scheduled_rewards.next_run_at IS 'Due time of run runs_completed + 1; NULL once every run is done';
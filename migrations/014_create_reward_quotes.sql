@@ -0,0 +1,29 @@
+-- Reward quotes
+-- A quote prices a reward without booking it and locks that price until
+-- expires_at; a reward that sends the quote_id is booked at the locked price,
+-- and each quote can be used by one reward only
+
+CREATE TABLE IF NOT EXISTS reward_quotes (
+    id SERIAL PRIMARY KEY,
+    quote_id VARCHAR(64) UNIQUE NOT NULL,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    stock_symbol VARCHAR(20) NOT NULL,
+    quantity DECIMAL(15, 6) NOT NULL,
+    amount_inr DECIMAL(15, 2),
+    deduct_fees BOOLEAN NOT NULL DEFAULT FALSE,
+    stock_price DECIMAL(15, 4) NOT NULL,
+    stock_price_id INTEGER NOT NULL REFERENCES stock_prices(id),
+    total_value_inr DECIMAL(15, 2) NOT NULL,
+    brokerage_fee DECIMAL(15, 2) NOT NULL,
+    transaction_fee DECIMAL(15, 2) NOT NULL,
+    net_value_inr DECIMAL(15, 2) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_by_event_id VARCHAR(255),
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reward_quotes_expires_at ON reward_quotes(expires_at) WHERE used_by_event_id IS NULL;
+
+COMMENT ON TABLE reward_quotes IS 'Priced reward previews whose price is locked until expires_at';
+COMMENT ON COLUMN reward_quotes.used_by_event_id IS 'Event of the reward that used the quote';
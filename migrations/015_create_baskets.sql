@@ -0,0 +1,29 @@
+-- Stock baskets
+-- A basket reward splits one INR amount across the basket's symbols by weight
+-- and books one reward per leg under the request's event_id
+
+CREATE TABLE IF NOT EXISTS baskets (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    description TEXT,
+    legs JSONB NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_baskets_active ON baskets(active);
+
+COMMENT ON TABLE baskets IS 'Weighted stock baskets rewarded as a single event';
+COMMENT ON COLUMN baskets.legs IS 'Array of {stock_symbol, weight}; fixed once created so replays split the same way';
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS basket_id INTEGER REFERENCES baskets(id);
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS parent_event_id VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_rewards_parent_event_id ON rewards(parent_event_id) WHERE parent_event_id IS NOT NULL;
+
+COMMENT ON COLUMN rewards.parent_event_id IS 'Event ID of the basket reward this leg belongs to';
+
+DROP TRIGGER IF EXISTS update_baskets_updated_at ON baskets;
+CREATE TRIGGER update_baskets_updated_at BEFORE UPDATE ON baskets
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,27 @@
+-- User-to-user transfers
+-- A transfer moves vested stock between users as a TRANSFER_OUT reward on the
+-- sender and a TRANSFER_IN reward on the receiver, booked in one transaction
+-- and stored once under its transfer_id
+
+CREATE TABLE IF NOT EXISTS transfers (
+    id SERIAL PRIMARY KEY,
+    transfer_id VARCHAR(96) UNIQUE NOT NULL,
+    from_user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    to_user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    stock_symbol VARCHAR(20) NOT NULL,
+    quantity DECIMAL(15, 6) NOT NULL CHECK (quantity > 0),
+    stock_price DECIMAL(15, 4),
+    stock_price_id INTEGER REFERENCES stock_prices(id),
+    value_inr DECIMAL(15, 2),
+    note TEXT,
+    out_reward_id INTEGER REFERENCES rewards(id),
+    in_reward_id INTEGER REFERENCES rewards(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfers_from_user_id ON transfers(from_user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_transfers_to_user_id ON transfers(to_user_id, created_at DESC);
+
+COMMENT ON TABLE transfers IS 'Gifts of rewarded stock between users';
+COMMENT ON COLUMN transfers.transfer_id IS 'Caller-supplied idempotency key; rewards are booked as <transfer_id>-OUT and <transfer_id>-IN';
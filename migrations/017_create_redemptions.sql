@@ -0,0 +1,42 @@
+-- Redemptions
+-- A redemption sells a quantity of a user's vested stock back for INR. It is
+-- REQUESTED by the user, EXECUTED at the current price (booking a negative
+-- REDEMPTION reward and its ledger entries) and PAID once the INR is sent.
+-- A REQUESTED redemption can be CANCELLED instead.
+
+CREATE TABLE IF NOT EXISTS redemptions (
+    id SERIAL PRIMARY KEY,
+    redemption_id VARCHAR(93) UNIQUE NOT NULL,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    stock_symbol VARCHAR(20) NOT NULL,
+    quantity DECIMAL(15, 6) NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'REQUESTED'
+        CHECK (status IN ('REQUESTED', 'EXECUTED', 'PAID', 'CANCELLED')),
+    stock_price DECIMAL(15, 4),
+    stock_price_id INTEGER REFERENCES stock_prices(id),
+    gross_value_inr DECIMAL(15, 2),
+    brokerage_fee DECIMAL(15, 2),
+    transaction_fee DECIMAL(15, 2),
+    net_payout_inr DECIMAL(15, 2),
+    cost_basis_inr DECIMAL(15, 2),
+    realized_pnl_inr DECIMAL(15, 2),
+    reward_id INTEGER REFERENCES rewards(id),
+    payment_reference VARCHAR(100),
+    requested_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    executed_at TIMESTAMP WITH TIME ZONE,
+    paid_at TIMESTAMP WITH TIME ZONE,
+    cancelled_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_redemptions_user_id ON redemptions(user_id, requested_at DESC);
+CREATE INDEX IF NOT EXISTS idx_redemptions_requested ON redemptions(user_id, stock_symbol) WHERE status = 'REQUESTED';
+
+COMMENT ON TABLE redemptions IS 'Sales of rewarded stock back to INR';
+COMMENT ON COLUMN redemptions.redemption_id IS 'Caller-supplied idempotency key; the sale is booked as reward <redemption_id>-REDEEM';
+COMMENT ON COLUMN redemptions.cost_basis_inr IS 'Average acquisition cost of the quantity sold';
+COMMENT ON COLUMN redemptions.realized_pnl_inr IS 'Net payout less cost basis';
+
+DROP TRIGGER IF EXISTS update_redemptions_updated_at ON redemptions;
+CREATE TRIGGER update_redemptions_updated_at BEFORE UPDATE ON redemptions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
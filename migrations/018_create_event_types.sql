@@ -0,0 +1,48 @@
+-- Event type registry
+-- A reward's event_type must name an active registered type, whose rules are
+-- checked before the reward is booked and whose accounts it is posted to
+
+CREATE TABLE IF NOT EXISTS event_types (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    description TEXT,
+    allow_negative BOOLEAN NOT NULL DEFAULT FALSE,
+    max_quantity DECIMAL(15, 6) CHECK (max_quantity > 0),
+    max_value_inr DECIMAL(15, 2) CHECK (max_value_inr > 0),
+    notes_required BOOLEAN NOT NULL DEFAULT FALSE,
+    credit_account VARCHAR(50) NOT NULL DEFAULT 'REWARD_INCOME',
+    debit_account VARCHAR(50) NOT NULL DEFAULT 'ADJUSTMENT_EXPENSE',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE event_types IS 'Registered reward event types and the rules their rewards follow';
+COMMENT ON COLUMN event_types.max_quantity IS 'Largest absolute quantity of one reward; NULL for no limit';
+COMMENT ON COLUMN event_types.max_value_inr IS 'Largest absolute INR value of one reward; NULL for no limit';
+COMMENT ON COLUMN event_types.credit_account IS 'Account credited with the value of stock granted';
+COMMENT ON COLUMN event_types.debit_account IS 'Account debited with the value of stock taken back';
+
+INSERT INTO event_types (code, description, allow_negative, notes_required) VALUES
+    ('REWARD', 'Default event type', TRUE, FALSE),
+    ('SIGNUP', 'Onboarding reward', FALSE, FALSE),
+    ('REFERRAL', 'Referral reward', FALSE, FALSE),
+    ('TRADE_CASHBACK', 'Cashback on trading activity', FALSE, FALSE),
+    ('SCHEDULED', 'Default event type of scheduled rewards', FALSE, FALSE),
+    ('ADJUSTMENT', 'Manual correction by ops', TRUE, TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+-- Event types already in use stay valid, with the rules they had until now
+INSERT INTO event_types (code, description, allow_negative)
+SELECT DISTINCT event_type, 'Registered from existing data', TRUE
+FROM (
+    SELECT event_type FROM rewards
+    UNION SELECT event_type FROM campaigns
+    UNION SELECT event_type FROM scheduled_rewards
+) used
+WHERE event_type NOT IN ('REVERSAL', 'CLAWBACK', 'TRANSFER_OUT', 'TRANSFER_IN', 'REDEMPTION')
+ON CONFLICT (code) DO NOTHING;
+
+DROP TRIGGER IF EXISTS update_event_types_updated_at ON event_types;
+CREATE TRIGGER update_event_types_updated_at BEFORE UPDATE ON event_types
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
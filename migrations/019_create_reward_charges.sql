@@ -0,0 +1,31 @@
+-- Itemized reward charges
+-- Every charge on a reward (brokerage, transaction fee, or the statutory
+-- STT, exchange, SEBI, stamp duty and GST components) is stored with the
+-- ledger account it is posted to. rewards.brokerage_fee keeps the brokerage
+-- total and rewards.transaction_fee the total of every other charge.
+
+CREATE TABLE IF NOT EXISTS reward_charges (
+    id SERIAL PRIMARY KEY,
+    reward_id INTEGER NOT NULL REFERENCES rewards(id) ON DELETE CASCADE,
+    code VARCHAR(30) NOT NULL,
+    account VARCHAR(50) NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL,
+    UNIQUE (reward_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reward_charges_reward_id ON reward_charges(reward_id);
+
+COMMENT ON TABLE reward_charges IS 'Charges itemized on each reward; negative on reversals';
+COMMENT ON COLUMN reward_charges.code IS 'BROKERAGE, TRANSACTION_FEE, STT, EXCHANGE_CHARGE, SEBI_FEE, STAMP_DUTY or GST';
+COMMENT ON COLUMN reward_charges.account IS 'Ledger account debited with the charge';
+
+-- Rewards booked before itemization carry their two fees as charges
+INSERT INTO reward_charges (reward_id, code, account, amount)
+SELECT id, 'BROKERAGE', 'BROKERAGE_EXPENSE', brokerage_fee
+FROM rewards WHERE brokerage_fee <> 0
+ON CONFLICT (reward_id, code) DO NOTHING;
+
+INSERT INTO reward_charges (reward_id, code, account, amount)
+SELECT id, 'TRANSACTION_FEE', 'FEE_EXPENSE', transaction_fee
+FROM rewards WHERE transaction_fee <> 0
+ON CONFLICT (reward_id, code) DO NOTHING;
@@ -0,0 +1,33 @@
+-- Effective-dated fee plans
+-- A reward is charged by the most specific plan in force at its event time:
+-- symbol and event type, then symbol, then event type, then the plan for
+-- every reward. Without one the CHARGES_PLAN configuration applies.
+
+CREATE TABLE IF NOT EXISTS fee_plans (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    stock_symbol VARCHAR(20),
+    event_type VARCHAR(50),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    effective_to TIMESTAMP WITH TIME ZONE,
+    charges JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fee_plans_effective ON fee_plans(effective_from, effective_to);
+
+COMMENT ON TABLE fee_plans IS 'Dated charge plans; plans of the same symbol and event type never overlap';
+COMMENT ON COLUMN fee_plans.stock_symbol IS 'Symbol the plan is limited to; NULL for every symbol';
+COMMENT ON COLUMN fee_plans.event_type IS 'Event type the plan is limited to; NULL for every event type';
+COMMENT ON COLUMN fee_plans.effective_to IS 'End of the plan, exclusive; NULL while open-ended';
+COMMENT ON COLUMN fee_plans.charges IS 'Array of {code, account, side, percent, flat_inr, min_inr, max_inr, levied_on}; fixed once created';
+
+ALTER TABLE rewards ADD COLUMN IF NOT EXISTS fee_plan_id INTEGER REFERENCES fee_plans(id);
+
+COMMENT ON COLUMN rewards.fee_plan_id IS 'Fee plan that set the charges; NULL when CHARGES_PLAN did';
+
+DROP TRIGGER IF EXISTS update_fee_plans_updated_at ON fee_plans;
+CREATE TRIGGER update_fee_plans_updated_at BEFORE UPDATE ON fee_plans
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
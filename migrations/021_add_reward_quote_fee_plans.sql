@@ -0,0 +1,10 @@
+-- Event type and fee plan of reward quotes
+-- A quote is priced for one event type under the fee plan in force when it is
+-- created; the reward that uses it is booked as that event type and charged
+-- by that plan, even if a new plan has started since.
+
+ALTER TABLE reward_quotes ADD COLUMN IF NOT EXISTS event_type VARCHAR(50) NOT NULL DEFAULT 'REWARD';
+ALTER TABLE reward_quotes ADD COLUMN IF NOT EXISTS fee_plan_id INTEGER REFERENCES fee_plans(id);
+
+COMMENT ON COLUMN reward_quotes.event_type IS 'Event type the quote was priced for; the reward must use it';
+COMMENT ON COLUMN reward_quotes.fee_plan_id IS 'Fee plan that set the quoted charges; NULL when CHARGES_PLAN did';
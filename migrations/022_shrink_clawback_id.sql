@@ -0,0 +1,7 @@
+-- Clawback IDs become the event IDs of CLAWBACK rewards with a -SYMBOL
+-- suffix, so they must leave room for the longest symbol within
+-- rewards.event_id VARCHAR(100)
+
+ALTER TABLE clawbacks ALTER COLUMN clawback_id TYPE VARCHAR(79);
+
+COMMENT ON COLUMN clawbacks.clawback_id IS 'At most 79 characters, so <clawback_id>-<SYMBOL> fits rewards.event_id';
@@ -0,0 +1,371 @@
+// Package decimal is an exact base-10 number for money and stock quantities.
+//
+// A Decimal is an arbitrary-precision integer coefficient and a number of
+// digits after the point, so 10.50 is 1050 with scale 2. Addition,
+// subtraction and multiplication are exact; only Round and Div drop digits,
+// and both take the RoundingMode to drop them with. Decimals are immutable
+// and the zero value is 0.
+//
+// Decimals are written to JSON as strings ("10.50") so clients never parse
+// them into binary floats, and read from JSON strings or numbers. They scan
+// from and encode to PostgreSQL NUMERIC through pgx.
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RoundingMode says how Round and Div drop digits
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds to the nearest value and ties away from zero: 2.345 -> 2.35, -2.345 -> -2.35
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds to the nearest value and ties to the even digit: 2.345 -> 2.34, 2.355 -> 2.36
+	RoundHalfEven
+	// RoundDown truncates toward zero: 2.349 -> 2.34, -2.349 -> -2.34
+	RoundDown
+	// RoundUp rounds away from zero: 2.341 -> 2.35, -2.341 -> -2.35
+	RoundUp
+)
+
+// maxScale bounds the digits after the point a parsed value may have, so a
+// value like 1e-1000000000 cannot exhaust memory
+const maxScale = 1000
+
+var (
+	bigOne = big.NewInt(1)
+	bigTen = big.NewInt(10)
+)
+
+// Zero is the decimal 0
+var Zero = Decimal{}
+
+// Decimal is an exact decimal number: coef × 10^-scale
+type Decimal struct {
+	coef  *big.Int // nil is 0
+	scale int32    // digits after the point, never negative
+}
+
+// New returns coef × 10^-scale, so New(1050, 2) is 10.50
+func New(coef int64, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{coef: new(big.Int).Mul(big.NewInt(coef), pow10(-scale))}
+	}
+	return Decimal{coef: big.NewInt(coef), scale: scale}
+}
+
+// NewFromInt returns the integer value as a decimal
+func NewFromInt(value int64) Decimal {
+	return Decimal{coef: big.NewInt(value)}
+}
+
+// NewFromFloat returns the shortest decimal that reads back as value. It is
+// for values that start out as floats, such as simulated prices; round the
+// result to the scale it is stored at. It panics on NaN and infinities.
+func NewFromFloat(value float64) Decimal {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		panic(fmt.Sprintf("decimal: cannot represent %v", value))
+	}
+	d, err := NewFromString(strconv.FormatFloat(value, 'f', -1, 64))
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// NewFromString parses a decimal such as "10.50", "-0.000125" or "1.5e3".
+// The digits given are kept, so "10.50" has scale 2.
+func NewFromString(value string) (Decimal, error) {
+	s := value
+	exp := int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("decimal: invalid exponent in %q", value)
+		}
+		s, exp = s[:i], e
+	}
+
+	negative := false
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	digits := intPart + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("decimal: invalid number %q", value)
+	}
+
+	scale := int64(len(fracPart)) - exp
+	if scale > maxScale || scale < -maxScale {
+		return Decimal{}, fmt.Errorf("decimal: %q is out of range", value)
+	}
+	coef, _ := new(big.Int).SetString(digits, 10)
+	if negative {
+		coef.Neg(coef)
+	}
+	if scale < 0 {
+		return Decimal{coef: coef.Mul(coef, pow10(int32(-scale)))}, nil
+	}
+	return Decimal{coef: coef, scale: int32(scale)}, nil
+}
+
+// RequireFromString is NewFromString for constants known to be valid; it
+// panics on an invalid value
+func RequireFromString(value string) Decimal {
+	d, err := NewFromString(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Min returns the smaller of a and b
+func Min(a, b Decimal) Decimal {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// Max returns the larger of a and b
+func Max(a, b Decimal) Decimal {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// Add returns d + d2
+func (d Decimal) Add(d2 Decimal) Decimal {
+	scale := maxInt32(d.scale, d2.scale)
+	return Decimal{coef: new(big.Int).Add(d.rescaled(scale), d2.rescaled(scale)), scale: scale}
+}
+
+// Sub returns d - d2
+func (d Decimal) Sub(d2 Decimal) Decimal {
+	scale := maxInt32(d.scale, d2.scale)
+	return Decimal{coef: new(big.Int).Sub(d.rescaled(scale), d2.rescaled(scale)), scale: scale}
+}
+
+// Mul returns d × d2 exactly; its scale is the sum of both scales
+func (d Decimal) Mul(d2 Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.int(), d2.int()), scale: d.scale + d2.scale}
+}
+
+// Div returns d ÷ d2 rounded to places digits after the point. It panics
+// when d2 is zero.
+func (d Decimal) Div(d2 Decimal, places int32, mode RoundingMode) Decimal {
+	if d2.IsZero() {
+		panic("decimal: division by zero")
+	}
+	// d/d2 = (a/10^sa) / (b/10^sb), so at scale p the coefficient is
+	// a × 10^(sb+p-sa) / b, or a × 10^(sb+p) / (b × 10^sa) when sa is larger
+	num := new(big.Int).Set(d.int())
+	den := new(big.Int).Set(d2.int())
+	if shift := d2.scale + places - d.scale; shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	return Decimal{coef: quoRound(num, den, mode), scale: places}
+}
+
+// Round returns d with places digits after the point, rounded by mode. A
+// value with fewer digits is padded with zeros, so the result always has
+// exactly places digits.
+func (d Decimal) Round(places int32, mode RoundingMode) Decimal {
+	if places >= d.scale {
+		return Decimal{coef: d.rescaled(places), scale: places}
+	}
+	return Decimal{coef: quoRound(d.int(), pow10(d.scale-places), mode), scale: places}
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Abs returns |d|
+func (d Decimal) Abs() Decimal {
+	return Decimal{coef: new(big.Int).Abs(d.int()), scale: d.scale}
+}
+
+// Cmp returns -1, 0 or +1 as d is less than, equal to or greater than d2
+func (d Decimal) Cmp(d2 Decimal) int {
+	scale := maxInt32(d.scale, d2.scale)
+	return d.rescaled(scale).Cmp(d2.rescaled(scale))
+}
+
+// Equal reports whether d and d2 are the same number, whatever their scales
+func (d Decimal) Equal(d2 Decimal) bool { return d.Cmp(d2) == 0 }
+
+// GreaterThan reports whether d > d2
+func (d Decimal) GreaterThan(d2 Decimal) bool { return d.Cmp(d2) > 0 }
+
+// GreaterThanOrEqual reports whether d >= d2
+func (d Decimal) GreaterThanOrEqual(d2 Decimal) bool { return d.Cmp(d2) >= 0 }
+
+// LessThan reports whether d < d2
+func (d Decimal) LessThan(d2 Decimal) bool { return d.Cmp(d2) < 0 }
+
+// LessThanOrEqual reports whether d <= d2
+func (d Decimal) LessThanOrEqual(d2 Decimal) bool { return d.Cmp(d2) <= 0 }
+
+// Sign returns -1, 0 or +1 as d is negative, zero or positive
+func (d Decimal) Sign() int { return d.int().Sign() }
+
+// IsZero reports whether d is 0
+func (d Decimal) IsZero() bool { return d.Sign() == 0 }
+
+// IsPositive reports whether d > 0
+func (d Decimal) IsPositive() bool { return d.Sign() > 0 }
+
+// IsNegative reports whether d < 0
+func (d Decimal) IsNegative() bool { return d.Sign() < 0 }
+
+// Scale returns the number of digits after the point
+func (d Decimal) Scale() int32 { return d.scale }
+
+// Float64 returns the nearest float64, for ratios such as percentages that
+// are not money
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// String returns d in plain notation with all of its digits after the
+// point, such as "10.50" or "-0.000125"
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		point := len(digits) - int(d.scale)
+		digits = digits[:point] + "." + digits[point:]
+	}
+	if d.IsNegative() {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON writes d as a JSON string
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON reads d from a JSON string or number; numbers are read from
+// their text, so no precision is lost. null leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	text := string(data)
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		text = text[1 : len(text)-1]
+	}
+	parsed, err := NewFromString(text)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// ScanNumeric lets pgx scan a NUMERIC column into d
+func (d *Decimal) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		return errors.New("decimal: cannot scan NULL into Decimal; use *Decimal")
+	}
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return errors.New("decimal: cannot scan NaN or infinity")
+	}
+	coef := new(big.Int)
+	if v.Int != nil {
+		coef.Set(v.Int)
+	}
+	if v.Exp >= 0 {
+		*d = Decimal{coef: coef.Mul(coef, pow10(v.Exp))}
+		return nil
+	}
+	*d = Decimal{coef: coef, scale: -v.Exp}
+	return nil
+}
+
+// NumericValue lets pgx write d to a NUMERIC parameter
+func (d Decimal) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: new(big.Int).Set(d.int()), Exp: -d.scale, Valid: true}, nil
+}
+
+// int returns the coefficient, treating the zero value's nil as 0
+func (d Decimal) int() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// rescaled returns the coefficient at a scale no smaller than d's own
+func (d Decimal) rescaled(scale int32) *big.Int {
+	if scale == d.scale {
+		return d.int()
+	}
+	return new(big.Int).Mul(d.int(), pow10(scale-d.scale))
+}
+
+// quoRound returns num ÷ den rounded to an integer by mode
+func quoRound(num, den *big.Int, mode RoundingMode) *big.Int {
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return quo
+	}
+
+	// The quotient is truncated toward zero; a step away from zero rounds
+	// its magnitude up
+	away := num.Sign() * den.Sign()
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1).Sub(half, new(big.Int).Abs(den)) // 2|rem| - |den|: <0 below half, 0 at half
+
+	roundAway := false
+	switch mode {
+	case RoundUp:
+		roundAway = true
+	case RoundHalfUp:
+		roundAway = half.Sign() >= 0
+	case RoundHalfEven:
+		roundAway = half.Sign() > 0 || (half.Sign() == 0 && quo.Bit(0) == 1)
+	}
+	if roundAway {
+		quo.Add(quo, big.NewInt(int64(away)))
+	}
+	return quo
+}
+
+// pow10 returns 10^n
+func pow10(n int32) *big.Int {
+	if n == 0 {
+		return new(big.Int).Set(bigOne)
+	}
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,260 @@
+package decimal
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestNewFromString(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string
+		scale int32
+	}{
+		{"0", "0", 0},
+		{"10.50", "10.50", 2},
+		{"-0.000125", "-0.000125", 6},
+		{"+3.1", "3.1", 1},
+		{"007.250", "7.250", 3},
+		{"-00.5", "-0.5", 1},
+		{".5", "0.5", 1},
+		{"5.", "5", 0},
+		{"1.5e3", "1500", 0},
+		{"1.5E3", "1500", 0},
+		{"1.5e+3", "1500", 0},
+		{"125e-5", "0.00125", 5},
+		{"-2.5e-2", "-0.025", 3},
+		{"1.25e1", "12.5", 1},
+		{"123456789012345678901234567890.123456789", "123456789012345678901234567890.123456789", 9},
+	}
+	for _, tt := range tests {
+		got, err := NewFromString(tt.in)
+		if err != nil {
+			t.Errorf("NewFromString(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want || got.Scale() != tt.scale {
+			t.Errorf("NewFromString(%q) = %s (scale %d), want %s (scale %d)", tt.in, got, got.Scale(), tt.want, tt.scale)
+		}
+	}
+}
+
+func TestNewFromStringInvalid(t *testing.T) {
+	for _, in := range []string{"", "-", "+", ".", "abc", "1.2.3", "1e", "1e1.5", "e5", "--1", "+-1", " 1", "1 ", "1,5", "0x10", "NaN", "Inf", "1e-1001", "1e1001"} {
+		if d, err := NewFromString(in); err == nil {
+			t.Errorf("NewFromString(%q) = %s, want an error", in, d)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int32
+		mode   RoundingMode
+		want   string
+	}{
+		// Exactly half way
+		{"2.345", 2, RoundHalfUp, "2.35"},
+		{"-2.345", 2, RoundHalfUp, "-2.35"},
+		{"2.345", 2, RoundHalfEven, "2.34"},
+		{"-2.345", 2, RoundHalfEven, "-2.34"},
+		{"2.355", 2, RoundHalfEven, "2.36"},
+		{"-2.355", 2, RoundHalfEven, "-2.36"},
+		{"2.345", 2, RoundDown, "2.34"},
+		{"-2.345", 2, RoundDown, "-2.34"},
+		{"2.345", 2, RoundUp, "2.35"},
+		{"-2.345", 2, RoundUp, "-2.35"},
+		// Just below half way
+		{"2.3449", 2, RoundHalfUp, "2.34"},
+		{"-2.3449", 2, RoundHalfUp, "-2.34"},
+		{"2.3449", 2, RoundHalfEven, "2.34"},
+		{"2.3449", 2, RoundUp, "2.35"},
+		{"-2.3449", 2, RoundUp, "-2.35"},
+		// Just above half way
+		{"2.3451", 2, RoundHalfUp, "2.35"},
+		{"2.3451", 2, RoundHalfEven, "2.35"},
+		{"-2.3451", 2, RoundHalfEven, "-2.35"},
+		{"2.3451", 2, RoundDown, "2.34"},
+		{"-2.3451", 2, RoundDown, "-2.34"},
+		// Exact values are unchanged by every mode
+		{"2.34", 2, RoundUp, "2.34"},
+		{"-2.34", 2, RoundDown, "-2.34"},
+		// Carries and rounding to whole numbers
+		{"9.995", 2, RoundHalfUp, "10.00"},
+		{"-9.995", 2, RoundHalfUp, "-10.00"},
+		{"0.5", 0, RoundHalfEven, "0"},
+		{"1.5", 0, RoundHalfEven, "2"},
+		{"-0.5", 0, RoundHalfUp, "-1"},
+		{"0.001", 2, RoundDown, "0.00"},
+		// Fewer digits are padded to exactly places
+		{"10.5", 2, RoundHalfUp, "10.50"},
+		{"3", 4, RoundDown, "3.0000"},
+		{"0", 2, RoundUp, "0.00"},
+	}
+	for _, tt := range tests {
+		got := RequireFromString(tt.in).Round(tt.places, tt.mode)
+		if got.String() != tt.want {
+			t.Errorf("%s.Round(%d, %d) = %s, want %s", tt.in, tt.places, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := RequireFromString("10.50"), RequireFromString("-0.125")
+	if got := a.Add(b).String(); got != "10.375" {
+		t.Errorf("Add = %s, want 10.375", got)
+	}
+	if got := a.Sub(b).String(); got != "10.625" {
+		t.Errorf("Sub = %s, want 10.625", got)
+	}
+	if got := a.Mul(b).String(); got != "-1.31250" {
+		t.Errorf("Mul = %s, want -1.31250", got)
+	}
+	if got := Zero.Add(a).String(); got != "10.50" {
+		t.Errorf("Zero.Add = %s, want 10.50", got)
+	}
+	if !RequireFromString("1.50").Equal(RequireFromString("1.5")) {
+		t.Error("1.50 should equal 1.5")
+	}
+	if RequireFromString("-0.01").Cmp(Zero) != -1 || !RequireFromString("0.00").IsZero() {
+		t.Error("Cmp or IsZero is wrong around zero")
+	}
+	if got := Min(a, b); !got.Equal(b) {
+		t.Errorf("Min = %s, want %s", got, b)
+	}
+	if got := Max(a, b); !got.Equal(a) {
+		t.Errorf("Max = %s, want %s", got, a)
+	}
+}
+
+func TestDiv(t *testing.T) {
+	hundred := NewFromInt(100)
+	tests := []struct {
+		name   string
+		num    Decimal
+		den    Decimal
+		places int32
+		mode   RoundingMode
+		want   string
+	}{
+		// feeFor: value × percent / 100 to paise, half-up
+		{"brokerage", RequireFromString("1842.75").Mul(RequireFromString("0.1")), hundred, 2, RoundHalfUp, "1.84"},
+		{"fee at half", RequireFromString("4875.00").Mul(RequireFromString("0.1")), hundred, 2, RoundHalfUp, "4.88"},
+		{"fee below half", RequireFromString("4875.00").Mul(RequireFromString("0.05")), hundred, 2, RoundHalfUp, "2.44"},
+		{"exchange charge", RequireFromString("10000.00").Mul(RequireFromString("0.00297")), hundred, 2, RoundHalfUp, "0.30"},
+		{"negative value", RequireFromString("-1842.75").Mul(RequireFromString("0.1")), hundred, 2, RoundHalfUp, "-1.84"},
+		// percentOf: exact at the product's scale plus 2
+		{"percent of", RequireFromString("10.30").Mul(RequireFromString("18")), hundred, 4, RoundHalfUp, "1.8540"},
+		{"percent of percent", RequireFromString("0.10297").Mul(RequireFromString("18")), hundred, 7, RoundHalfUp, "0.0185346"},
+		// Quantities for an INR amount
+		{"quantity down", RequireFromString("500"), RequireFromString("123.4500"), 6, RoundDown, "4.050222"},
+		{"quantity up", RequireFromString("500"), RequireFromString("123.4500"), 6, RoundUp, "4.050223"},
+		{"one third even", NewFromInt(1), NewFromInt(3), 4, RoundHalfEven, "0.3333"},
+		{"two thirds", NewFromInt(-2), NewFromInt(3), 2, RoundHalfUp, "-0.67"},
+		{"negative divisor", NewFromInt(1), NewFromInt(-8), 2, RoundHalfEven, "-0.12"},
+		{"smaller scale than dividend", RequireFromString("1.23456"), NewFromInt(1), 2, RoundDown, "1.23"},
+	}
+	for _, tt := range tests {
+		got := tt.num.Div(tt.den, tt.places, tt.mode)
+		if got.String() != tt.want {
+			t.Errorf("%s: %s / %s = %s, want %s", tt.name, tt.num, tt.den, got, tt.want)
+		}
+	}
+}
+
+func TestDivByZeroPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Div by zero should panic")
+		}
+	}()
+	NewFromInt(1).Div(Zero, 2, RoundHalfUp)
+}
+
+func TestNewFromFloat(t *testing.T) {
+	if got := NewFromFloat(175.5).String(); got != "175.5" {
+		t.Errorf("NewFromFloat(175.5) = %s", got)
+	}
+	if got := NewFromFloat(0.1).Add(NewFromFloat(0.2)).String(); got != "0.3" {
+		t.Errorf("0.1 + 0.2 = %s, want 0.3", got)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	type payload struct {
+		Amount Decimal  `json:"amount"`
+		Cap    *Decimal `json:"cap"`
+	}
+	for _, in := range []string{"10.50", "-0.000125", "0", "123456789.123456"} {
+		data, err := json.Marshal(payload{Amount: RequireFromString(in)})
+		if err != nil {
+			t.Fatalf("Marshal(%s): %v", in, err)
+		}
+		if want := `{"amount":"` + in + `","cap":null}`; string(data) != want {
+			t.Errorf("Marshal(%s) = %s, want %s", in, data, want)
+		}
+		var out payload
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if out.Amount.String() != in || out.Cap != nil {
+			t.Errorf("round trip of %s gave %s, cap %v", in, out.Amount, out.Cap)
+		}
+	}
+
+	// Numbers are read from their text without passing through a float
+	var out payload
+	if err := json.Unmarshal([]byte(`{"amount": 0.1000000000000000055511151231257827, "cap": "5"}`), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Amount.String() != "0.1000000000000000055511151231257827" || out.Cap == nil || out.Cap.String() != "5" {
+		t.Errorf("Unmarshal of a number = %s, cap %v", out.Amount, out.Cap)
+	}
+
+	for _, bad := range []string{`{"amount": "ten"}`, `{"amount": true}`, `{"amount": ""}`} {
+		if err := json.Unmarshal([]byte(bad), &out); err == nil {
+			t.Errorf("Unmarshal(%s) should fail", bad)
+		}
+	}
+}
+
+func TestNumericRoundTrip(t *testing.T) {
+	for _, in := range []string{"10.50", "-0.000125", "0", "1500", "98765432109876543210.0123"} {
+		d := RequireFromString(in)
+		n, err := d.NumericValue()
+		if err != nil {
+			t.Fatalf("NumericValue(%s): %v", in, err)
+		}
+		var out Decimal
+		if err := out.ScanNumeric(n); err != nil {
+			t.Fatalf("ScanNumeric(%s): %v", in, err)
+		}
+		if out.String() != in {
+			t.Errorf("round trip of %s gave %s", in, out)
+		}
+	}
+
+	// PostgreSQL may send a positive exponent for whole numbers
+	var out Decimal
+	if err := out.ScanNumeric(pgtype.Numeric{Int: big.NewInt(15), Exp: 2, Valid: true}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1500" {
+		t.Errorf("ScanNumeric(15e2) = %s, want 1500", out)
+	}
+
+	for name, n := range map[string]pgtype.Numeric{
+		"NULL":      {},
+		"NaN":       {NaN: true, Valid: true},
+		"infinity":  {InfinityModifier: pgtype.Infinity, Valid: true},
+		"-infinity": {InfinityModifier: pgtype.NegativeInfinity, Valid: true},
+	} {
+		if err := out.ScanNumeric(n); err == nil {
+			t.Errorf("ScanNumeric(%s) should fail", name)
+		}
+	}
+}